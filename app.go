@@ -7,10 +7,13 @@ package transire
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // QueueSender provides an abstraction for sending messages to queues.
@@ -22,6 +25,13 @@ type QueueSender interface {
 type Context struct {
 	context.Context
 	Queues QueueSender
+	Topics TopicPublisher
+	// Timers backs ScheduleAt, ScheduleAfter, and CancelScheduled; nil when the
+	// dispatcher provides none.
+	Timers Timers
+	// Logger is set by dispatchers with fields identifying the handler invocation.
+	// It is nil in contexts built by hand; use Log for a logger that is never nil.
+	Logger *slog.Logger
 }
 
 // Message represents a queue message.
@@ -38,6 +48,12 @@ type QueueHandler func(ctx Context, msg Message) error
 // ScheduleHandler processes a scheduled invocation.
 type ScheduleHandler func(ctx Context, at time.Time) error
 
+// QueueMiddleware wraps a QueueHandler, e.g. for logging, recovery, or metrics.
+type QueueMiddleware func(QueueHandler) QueueHandler
+
+// ScheduleMiddleware wraps a ScheduleHandler, e.g. for logging, recovery, or metrics.
+type ScheduleMiddleware func(ScheduleHandler) ScheduleHandler
+
 // Dispatcher routes events from a concrete runtime (local, AWS, etc) into handlers.
 type Dispatcher interface {
 	Run(ctx context.Context, app *App) error
@@ -46,20 +62,69 @@ type Dispatcher interface {
 
 // App is the root container for an application built with Transire.
 // It aggregates HTTP, queue, and scheduler handlers behind cloud-agnostic interfaces.
-// Schedule represents a scheduled task with a fixed rate.
+// Schedule represents a scheduled task with a fixed rate or a cron expression.
 type Schedule struct {
-	Name     string
-	Every    time.Duration
+	Name  string
+	Every time.Duration
+	// Cron is a five-field cron expression; when set it takes precedence over Every.
+	Cron string
+	// Location is the time zone Cron is evaluated in; nil means UTC.
+	Location *time.Location
 	Handler  ScheduleHandler
+	// Metadata is static data delivered with every run; see WithMetadata.
 	Metadata map[string]string
+	// Concurrency decides whether runs may overlap; empty means ConcurrencyAllow.
+	Concurrency Concurrency
+	// RunOnStart runs the schedule once when the dispatcher starts.
+	RunOnStart bool
+	// Jitter is the longest random delay added to each run.
+	Jitter time.Duration
+
+	cron *cronSpec
+}
+
+// Next returns the first scheduled time strictly after the given time.
+// It returns the zero time when the schedule can never fire.
+func (s Schedule) Next(after time.Time) time.Time {
+	if s.Cron == "" {
+		if s.Every <= 0 {
+			return time.Time{}
+		}
+		return after.Add(s.Every)
+	}
+	spec := s.cron
+	if spec == nil {
+		var err error
+		if spec, err = parseCron(s.Cron); err != nil {
+			return time.Time{}
+		}
+	}
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return spec.next(after.In(loc))
 }
 
 type App struct {
-	router        *chi.Mux
-	queueHandlers map[string]QueueHandler
-	schedules     map[string]Schedule
-	dispatcher    Dispatcher
-	queueSender   QueueSender
+	router              *chi.Mux
+	queueHandlers       map[string]QueueHandler
+	batchHandlers       map[string]BatchQueueHandler
+	queueConfigs        map[string]QueueConfig
+	schedules           map[string]Schedule
+	queueMiddlewares    []QueueMiddleware
+	scheduleMiddlewares []ScheduleMiddleware
+	topics              map[string][]string
+	dispatcher          Dispatcher
+	queueSender         QueueSender
+	topicPublisher      TopicPublisher
+	timers              Timers
+	logger              *slog.Logger
+	tracerProvider      trace.TracerProvider
+	metrics             Metrics
+	errorHooks          []ErrorHook
+	startHooks          []LifecycleHook
+	shutdownHooks       []LifecycleHook
 }
 
 // New creates a new application with a chi router and empty handler registries.
@@ -68,7 +133,10 @@ func New() *App {
 	return &App{
 		router:        r,
 		queueHandlers: map[string]QueueHandler{},
+		batchHandlers: map[string]BatchQueueHandler{},
+		queueConfigs:  map[string]QueueConfig{},
 		schedules:     map[string]Schedule{},
+		topics:        map[string][]string{},
 	}
 }
 
@@ -82,18 +150,66 @@ func (a *App) Use(middlewares ...func(http.Handler) http.Handler) {
 	a.router.Use(middlewares...)
 }
 
+// UseQueue registers middlewares applied to every queue handler.
+// As with Use, the first middleware registered is the outermost.
+func (a *App) UseQueue(middlewares ...QueueMiddleware) {
+	a.queueMiddlewares = append(a.queueMiddlewares, middlewares...)
+}
+
+// UseSchedule registers middlewares applied to every schedule handler.
+// As with Use, the first middleware registered is the outermost.
+func (a *App) UseSchedule(middlewares ...ScheduleMiddleware) {
+	a.scheduleMiddlewares = append(a.scheduleMiddlewares, middlewares...)
+}
+
 // RegisterQueueHandler binds a handler to a named queue.
-func (a *App) RegisterQueueHandler(queue string, handler QueueHandler) {
+// Options must be literal calls (e.g. transire.WithDeadLetterQueue(5)) so builds can discover them.
+func (a *App) RegisterQueueHandler(queue string, handler QueueHandler, opts ...QueueOption) {
 	a.queueHandlers[queue] = handler
+	delete(a.batchHandlers, queue)
+	var cfg QueueConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	a.queueConfigs[queue] = cfg
 }
 
 // RegisterScheduleHandler binds a schedule name to a handler that runs every duration.
-func (a *App) RegisterScheduleHandler(name string, every time.Duration, handler ScheduleHandler) {
-	a.schedules[name] = Schedule{
+// Options must be literal calls (e.g. transire.WithConcurrency(transire.ConcurrencySkip))
+// so builds can discover them.
+func (a *App) RegisterScheduleHandler(name string, every time.Duration, handler ScheduleHandler, opts ...ScheduleOption) {
+	sched := Schedule{
 		Name:    name,
 		Every:   every,
 		Handler: handler,
 	}
+	applyScheduleOptions(&sched, opts)
+	a.schedules[name] = sched
+}
+
+// RegisterCronHandler binds a schedule name to a handler that runs on a five-field cron
+// expression (minute hour day-of-month month day-of-week), evaluated in the IANA timezone
+// (UTC when empty). It panics if the expression, timezone, or an option is invalid.
+func (a *App) RegisterCronHandler(name string, expr string, timezone string, handler ScheduleHandler, opts ...ScheduleOption) {
+	spec, err := parseCron(expr)
+	if err != nil {
+		panic(err)
+	}
+	loc := time.UTC
+	if timezone != "" {
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			panic(fmt.Errorf("transire: schedule %s: %w", name, err))
+		}
+	}
+	sched := Schedule{
+		Name:     name,
+		Cron:     expr,
+		Location: loc,
+		Handler:  handler,
+		cron:     spec,
+	}
+	applyScheduleOptions(&sched, opts)
+	a.schedules[name] = sched
 }
 
 // QueueHandlers exposes registered queue handlers.
@@ -106,6 +222,47 @@ func (a *App) Schedules() map[string]Schedule {
 	return a.schedules
 }
 
+// QueueConfig returns the options a queue was registered with.
+func (a *App) QueueConfig(queue string) QueueConfig {
+	return a.queueConfigs[queue]
+}
+
+// QueueHandler returns the handler for a queue wrapped in the queue middlewares,
+// the queue's retry option, panic recovery and OnError hooks, metrics, and a tracing
+// span per message.
+// Dispatchers should invoke handlers through this rather than QueueHandlers.
+func (a *App) QueueHandler(queue string) (QueueHandler, bool) {
+	handler, ok := a.queueHandlers[queue]
+	if !ok || handler == nil {
+		return nil, false
+	}
+	if retries := a.queueConfigs[queue].MaxRetries; retries > 0 {
+		handler = retrying(recovering(handler), retries)
+	}
+	for i := len(a.queueMiddlewares) - 1; i >= 0; i-- {
+		handler = a.queueMiddlewares[i](handler)
+	}
+	handler = a.recoveredQueue(queue, handler)
+	return a.tracedQueue(queue, a.measuredQueue(queue, handler)), true
+}
+
+// ScheduleHandler returns the handler for a schedule wrapped in the schedule middlewares
+// panic recovery and OnError hooks, metrics, and a tracing span per run, with the run's
+// ScheduleInvocation on its context. Dispatchers should invoke handlers through this rather
+// than Schedule.Handler, passing trigger data with ContextWithScheduleData.
+func (a *App) ScheduleHandler(name string) (ScheduleHandler, bool) {
+	sched, ok := a.schedules[name]
+	if !ok || sched.Handler == nil {
+		return nil, false
+	}
+	handler := sched.Handler
+	for i := len(a.scheduleMiddlewares) - 1; i >= 0; i-- {
+		handler = a.scheduleMiddlewares[i](handler)
+	}
+	handler = a.recoveredSchedule(name, handler)
+	return invokedSchedule(sched, a.tracedSchedule(name, a.measuredSchedule(name, handler))), true
+}
+
 // RouterHandler exposes the chi router for HTTP serving.
 func (a *App) RouterHandler() http.Handler {
 	return a.router
@@ -121,6 +278,19 @@ func (a *App) QueueSender() QueueSender {
 	return a.queueSender
 }
 
+// NewContext builds the handler context for ctx from the app's queue sender, topic
+// publisher, timers, and logger. Dispatchers use it (or QueueContext, BatchContext and
+// ScheduleContext) so every handler kind sees the same primitives.
+func (a *App) NewContext(ctx context.Context) Context {
+	return Context{
+		Context: ctx,
+		Queues:  a.queueSender,
+		Topics:  a.topicPublisher,
+		Timers:  a.timers,
+		Logger:  a.loggerFor(ctx),
+	}
+}
+
 // SetDispatcher defines which dispatcher should run the app.
 func (a *App) SetDispatcher(dispatcher Dispatcher) {
 	a.dispatcher = dispatcher
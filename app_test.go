@@ -52,3 +52,103 @@ func TestRunInvokesDispatcher(t *testing.T) {
 		t.Fatalf("dispatcher Run was not called")
 	}
 }
+
+func TestQueueMiddlewareOrder(t *testing.T) {
+	app := New()
+	var calls []string
+	trace := func(name string) QueueMiddleware {
+		return func(next QueueHandler) QueueHandler {
+			return func(ctx Context, msg Message) error {
+				calls = append(calls, name)
+				return next(ctx, msg)
+			}
+		}
+	}
+	app.UseQueue(trace("outer"), trace("inner"))
+	app.RegisterQueueHandler("q1", func(ctx Context, msg Message) error {
+		calls = append(calls, "handler")
+		return nil
+	})
+
+	handler, ok := app.QueueHandler("q1")
+	if !ok {
+		t.Fatalf("queue handler not found")
+	}
+	if err := handler(Context{Context: context.Background()}, Message{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(calls) != 3 || calls[0] != "outer" || calls[1] != "inner" || calls[2] != "handler" {
+		t.Fatalf("unexpected middleware order: %v", calls)
+	}
+	if _, ok := app.QueueHandler("missing"); ok {
+		t.Fatalf("expected missing queue to report not found")
+	}
+}
+
+func TestScheduleMiddlewareWraps(t *testing.T) {
+	app := New()
+	errWrapped := errors.New("wrapped")
+	app.UseSchedule(func(next ScheduleHandler) ScheduleHandler {
+		return func(ctx Context, at time.Time) error {
+			if err := next(ctx, at); err != nil {
+				return errWrapped
+			}
+			return nil
+		}
+	})
+	app.RegisterScheduleHandler("job1", time.Minute, func(ctx Context, at time.Time) error {
+		return errors.New("raw")
+	})
+
+	handler, ok := app.ScheduleHandler("job1")
+	if !ok {
+		t.Fatalf("schedule handler not found")
+	}
+	if err := handler(Context{Context: context.Background()}, time.Now()); !errors.Is(err, errWrapped) {
+		t.Fatalf("expected middleware to wrap error, got %v", err)
+	}
+}
+
+func TestRegisterQueueHandlerOptions(t *testing.T) {
+	app := New()
+	app.RegisterQueueHandler("q1", func(ctx Context, msg Message) error { return nil }, WithDeadLetterQueue(4))
+	app.RegisterQueueHandler("q2", func(ctx Context, msg Message) error { return nil })
+
+	if got := app.QueueConfig("q1").MaxReceiveCount; got != 4 {
+		t.Fatalf("expected max receive count 4, got %d", got)
+	}
+	if got := app.QueueConfig("q2").MaxReceiveCount; got != 0 {
+		t.Fatalf("expected no dead-letter queue, got %d", got)
+	}
+	if DeadLetterQueueName("q1") != "q1-dlq" {
+		t.Fatalf("unexpected dead-letter queue name: %s", DeadLetterQueueName("q1"))
+	}
+}
+
+func TestQueueHandlerRetriesInProcess(t *testing.T) {
+	app := New()
+	calls := 0
+	app.RegisterQueueHandler("work", func(ctx Context, msg Message) error {
+		calls++
+		if calls < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	}, WithMaxRetries(2), WithBatchSize(20), WithBatchWindow(time.Second), WithMaxConcurrency(5), WithVisibilityTimeout(time.Minute))
+
+	handler, ok := app.QueueHandler("work")
+	if !ok {
+		t.Fatalf("expected handler")
+	}
+	if err := handler(Context{Context: context.Background()}, Message{Queue: "work"}); err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+
+	want := QueueConfig{BatchSize: 20, BatchWindow: time.Second, MaxConcurrency: 5, VisibilityTimeout: time.Minute, MaxRetries: 2}
+	if got := app.QueueConfig("work"); got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,119 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import "errors"
+
+// BatchQueueHandler processes a batch of messages from one queue in a single call.
+type BatchQueueHandler func(ctx Context, msgs []Message) BatchResult
+
+// MessageFailure identifies a message of a batch that was not processed.
+type MessageFailure struct {
+	MessageID string
+	Err       error
+}
+
+// errReportedFailed stands in for the error of a failure reported without one.
+var errReportedFailed = errors.New("message reported as failed")
+
+// cause returns f.Err, or a generic error when the handler reported none, so the
+// message is still treated as failed.
+func (f MessageFailure) cause() error {
+	if f.Err == nil {
+		return errReportedFailed
+	}
+	return f.Err
+}
+
+// BatchResult reports the messages of a batch that failed. Messages not listed are
+// treated as processed; failed messages are retried under the queue's retry policy.
+type BatchResult struct {
+	Failures []MessageFailure
+}
+
+// Fail records msg as failed with err.
+func (r *BatchResult) Fail(msg Message, err error) {
+	r.Failures = append(r.Failures, MessageFailure{MessageID: msg.ID, Err: err})
+}
+
+// FailAll returns a result that fails every message in msgs with err.
+func FailAll(msgs []Message, err error) BatchResult {
+	var r BatchResult
+	for _, msg := range msgs {
+		r.Fail(msg, err)
+	}
+	return r
+}
+
+// RegisterBatchQueueHandler binds a handler that receives whole batches from a named queue.
+// Batch size and window come from WithBatchSize and WithBatchWindow. Queue middlewares
+// registered with UseQueue do not apply to whole batches delivered through
+// BatchQueueHandler; they do wrap the single-message adapter that QueueHandler returns
+// for the queue.
+func (a *App) RegisterBatchQueueHandler(queue string, handler BatchQueueHandler, opts ...QueueOption) {
+	a.RegisterQueueHandler(queue, singleMessage(handler), opts...)
+	a.batchHandlers[queue] = handler
+}
+
+// BatchQueueHandler returns the batch handler for a queue, wrapped in the queue's retry
+// option, panic recovery and OnError hooks, metrics, and a tracing span per batch.
+// Dispatchers should deliver whole batches to queues that have one.
+func (a *App) BatchQueueHandler(queue string) (BatchQueueHandler, bool) {
+	handler, ok := a.batchHandlers[queue]
+	if !ok || handler == nil {
+		return nil, false
+	}
+	if retries := a.queueConfigs[queue].MaxRetries; retries > 0 {
+		handler = retryingBatch(recoveringBatch(handler), retries)
+	}
+	handler = a.recoveredBatch(queue, handler)
+	return a.tracedBatch(queue, a.measuredBatch(queue, handler)), true
+}
+
+// singleMessage adapts a batch handler to a single-message QueueHandler.
+func singleMessage(handler BatchQueueHandler) QueueHandler {
+	return func(ctx Context, msg Message) error {
+		result := handler(ctx, []Message{msg})
+		for _, f := range result.Failures {
+			if f.MessageID == msg.ID {
+				return f.cause()
+			}
+		}
+		return nil
+	}
+}
+
+// retryingBatch re-invokes handler with the messages that failed transiently, up to retries times.
+func retryingBatch(handler BatchQueueHandler, retries int) BatchQueueHandler {
+	return func(ctx Context, msgs []Message) BatchResult {
+		result := handler(ctx, msgs)
+		for i := 0; i < retries && len(result.Failures) > 0; i++ {
+			if ctx.Context != nil && ctx.Err() != nil {
+				break
+			}
+			byID := make(map[string]Message, len(msgs))
+			for _, msg := range msgs {
+				byID[msg.ID] = msg
+			}
+			var final BatchResult
+			var again []Message
+			for _, f := range result.Failures {
+				msg, ok := byID[f.MessageID]
+				if !ok || IsPermanent(f.Err) {
+					final.Failures = append(final.Failures, f)
+					continue
+				}
+				again = append(again, msg)
+			}
+			if len(again) == 0 {
+				return final
+			}
+			retried := handler(ctx, again)
+			final.Failures = append(final.Failures, retried.Failures...)
+			result = final
+		}
+		return result
+	}
+}
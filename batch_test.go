@@ -0,0 +1,93 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRegisterBatchQueueHandler(t *testing.T) {
+	app := New()
+	app.RegisterBatchQueueHandler("bulk", func(ctx Context, msgs []Message) BatchResult {
+		var r BatchResult
+		for _, msg := range msgs {
+			if string(msg.Body) == "bad" {
+				r.Fail(msg, errors.New("rejected"))
+			}
+		}
+		return r
+	})
+
+	if _, ok := app.BatchQueueHandler("bulk"); !ok {
+		t.Fatalf("expected batch handler")
+	}
+	single, ok := app.QueueHandler("bulk")
+	if !ok {
+		t.Fatalf("batch queues should also expose a single-message handler")
+	}
+	if err := single(Context{}, Message{ID: "1", Body: []byte("good")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := single(Context{}, Message{ID: "2", Body: []byte("bad")}); err == nil || err.Error() != "rejected" {
+		t.Fatalf("expected rejected, got %v", err)
+	}
+
+	app.RegisterQueueHandler("bulk", func(ctx Context, msg Message) error { return nil })
+	if _, ok := app.BatchQueueHandler("bulk"); ok {
+		t.Fatalf("re-registering a single handler should replace the batch handler")
+	}
+}
+
+func TestBatchQueueHandlerRetriesFailedMessages(t *testing.T) {
+	app := New()
+	var batches [][]string
+	app.RegisterBatchQueueHandler("bulk", func(ctx Context, msgs []Message) BatchResult {
+		var ids []string
+		var r BatchResult
+		for _, msg := range msgs {
+			ids = append(ids, msg.ID)
+			switch {
+			case msg.ID == "poison":
+				r.Fail(msg, Permanent(errors.New("bad")))
+			case msg.ID == "flaky" && len(batches) == 0:
+				r.Fail(msg, errors.New("transient"))
+			}
+		}
+		batches = append(batches, ids)
+		return r
+	}, WithMaxRetries(3))
+
+	handler, _ := app.BatchQueueHandler("bulk")
+	result := handler(Context{Context: context.Background()}, []Message{{ID: "ok"}, {ID: "flaky"}, {ID: "poison"}})
+
+	if len(batches) != 2 || len(batches[1]) != 1 || batches[1][0] != "flaky" {
+		t.Fatalf("expected only the transient failure to be retried, got %v", batches)
+	}
+	if len(result.Failures) != 1 || result.Failures[0].MessageID != "poison" {
+		t.Fatalf("unexpected failures: %+v", result.Failures)
+	}
+}
+
+func TestFailAll(t *testing.T) {
+	err := errors.New("db down")
+	r := FailAll([]Message{{ID: "a"}, {ID: "b"}}, err)
+	if len(r.Failures) != 2 || r.Failures[1].MessageID != "b" || r.Failures[1].Err != err {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+}
+
+func TestSingleMessageFailureWithoutError(t *testing.T) {
+	app := New()
+	app.RegisterBatchQueueHandler("bulk", func(ctx Context, msgs []Message) BatchResult {
+		return BatchResult{Failures: []MessageFailure{{MessageID: msgs[0].ID}}}
+	})
+
+	single, _ := app.QueueHandler("bulk")
+	if err := single(Context{}, Message{ID: "1"}); err == nil {
+		t.Fatalf("expected a failure reported without an error to fail the message")
+	}
+}
@@ -6,6 +6,7 @@ package transire
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 )
 
@@ -13,19 +14,42 @@ type contextKey string
 
 const httpContextKey contextKey = "transire-http-context"
 
-// InjectContext adds a Transire context into each HTTP request.
+// InjectContext adds a Transire context into each HTTP request. The request's correlation
+// ID is taken from the X-Correlation-Id header (see CorrelationID) and echoed in the response.
 func InjectContext(sender QueueSender) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = withCorrelationID(w, r)
+			logger := LoggerFromContext(r.Context())
+			if logger == nil {
+				logger = slog.Default()
+			}
+			logger = logger.With("correlation_id", CorrelationID(r.Context()))
 			ctx := Context{
 				Context: r.Context(),
 				Queues:  sender,
+				Logger:  logger,
 			}
 			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), httpContextKey, ctx)))
 		})
 	}
 }
 
+// ContextMiddleware adds the app's handler context (see NewContext) into each HTTP request,
+// with the method, path, and request ID on its logger, and traces the request. The request
+// ID is the dispatcher's, else the X-Request-Id header, else a generated one; handlers read
+// it with chi's middleware.GetReqID. Correlation IDs are handled as in InjectContext.
+func (a *App) ContextMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			a.traceHTTP(w, withCorrelationID(w, withRequestID(r)), func(w http.ResponseWriter, r *http.Request) {
+				ctx := a.httpContext(r)
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), httpContextKey, ctx)))
+			})
+		})
+	}
+}
+
 // RequestContext extracts the Transire context from an HTTP request when present.
 func RequestContext(r *http.Request) (Context, bool) {
 	value := r.Context().Value(httpContextKey)
@@ -37,3 +61,10 @@ func RequestContext(r *http.Request) (Context, bool) {
 	}
 	return Context{}, false
 }
+
+// withCorrelationID attaches the request's correlation ID to its context and response headers.
+func withCorrelationID(w http.ResponseWriter, r *http.Request) *http.Request {
+	id := requestCorrelationID(r)
+	w.Header().Set(CorrelationIDHeader, id)
+	return r.WithContext(ContextWithCorrelationID(r.Context(), id))
+}
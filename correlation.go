@@ -0,0 +1,98 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// CorrelationIDHeader is the HTTP header carrying a correlation ID into and out of a request.
+const CorrelationIDHeader = "X-Correlation-Id"
+
+// CorrelationIDAttribute is the message attribute queue senders stamp with the sender's
+// correlation ID, so the receiving handler continues the same chain.
+const CorrelationIDAttribute = "transire.correlation_id"
+
+type correlationKey struct{}
+
+// ContextWithCorrelationID returns a copy of ctx carrying id. Messages sent with the
+// returned context are stamped with it.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the correlation ID carried by ctx, or "".
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// NewCorrelationID returns a random correlation ID for a chain that starts here.
+func NewCorrelationID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// CorrelationAttributes returns attrs with the correlation ID of ctx added under
+// CorrelationIDAttribute. attrs is not modified, and an ID already present in attrs
+// is kept. Queue senders call it on every outgoing message.
+func CorrelationAttributes(ctx context.Context, attrs map[string]string) map[string]string {
+	id := CorrelationID(ctx)
+	if id == "" || attrs[CorrelationIDAttribute] != "" {
+		return attrs
+	}
+	out := make(map[string]string, len(attrs)+1)
+	for k, v := range attrs {
+		out[k] = v
+	}
+	out[CorrelationIDAttribute] = id
+	return out
+}
+
+// requestCorrelationID derives the correlation ID of an HTTP request from its context,
+// the X-Correlation-Id header, then the request ID, generating one when none is set.
+func requestCorrelationID(r *http.Request) string {
+	if id := CorrelationID(r.Context()); id != "" {
+		return id
+	}
+	if id := r.Header.Get(CorrelationIDHeader); id != "" {
+		return id
+	}
+	if id := requestID(r); id != "" {
+		return id
+	}
+	return NewCorrelationID()
+}
+
+// requestID returns the request ID already in r's context, else the X-Request-Id header.
+func requestID(r *http.Request) string {
+	if id := middleware.GetReqID(r.Context()); id != "" {
+		return id
+	}
+	return r.Header.Get(middleware.RequestIDHeader)
+}
+
+// withRequestID gives r a request ID: the one a dispatcher put in its context (API
+// Gateway's on AWS), else the X-Request-Id header, else a generated one. The ID is stored
+// where chi's middleware.GetReqID finds it and set as the X-Request-Id header, so a
+// RequestID middleware installed on the app's router keeps it rather than making another.
+func withRequestID(r *http.Request) *http.Request {
+	id := requestID(r)
+	if id == "" {
+		id = NewCorrelationID()
+	}
+	r = r.WithContext(context.WithValue(r.Context(), middleware.RequestIDKey, id))
+	r.Header.Set(middleware.RequestIDHeader, id)
+	return r
+}
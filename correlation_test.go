@@ -0,0 +1,78 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCorrelationAttributesStampsContextID(t *testing.T) {
+	attrs := map[string]string{"k": "v"}
+	ctx := ContextWithCorrelationID(context.Background(), "c-1")
+
+	got := CorrelationAttributes(ctx, attrs)
+	if got[CorrelationIDAttribute] != "c-1" || got["k"] != "v" {
+		t.Fatalf("unexpected attributes: %v", got)
+	}
+	if _, ok := attrs[CorrelationIDAttribute]; ok {
+		t.Fatalf("input attributes modified: %v", attrs)
+	}
+
+	explicit := map[string]string{CorrelationIDAttribute: "mine"}
+	if got := CorrelationAttributes(ctx, explicit); got[CorrelationIDAttribute] != "mine" {
+		t.Fatalf("expected explicit ID kept, got %v", got)
+	}
+	if got := CorrelationAttributes(context.Background(), nil); got != nil {
+		t.Fatalf("expected no attributes without an ID, got %v", got)
+	}
+}
+
+func TestQueueContextRestoresCorrelationID(t *testing.T) {
+	app := New()
+	msg := Message{ID: "m1", Queue: "work", Attributes: map[string]string{CorrelationIDAttribute: "c-1"}}
+	if got := CorrelationID(app.QueueContext(context.Background(), msg)); got != "c-1" {
+		t.Fatalf("expected c-1, got %q", got)
+	}
+	if got := CorrelationID(app.QueueContext(context.Background(), Message{ID: "m2"})); got == "" {
+		t.Fatalf("expected a generated correlation ID")
+	}
+}
+
+func TestInjectContextDerivesCorrelationID(t *testing.T) {
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{name: "correlation header", headers: map[string]string{"X-Correlation-Id": "c-1", "X-Request-Id": "r-1"}, want: "c-1"},
+		{name: "request id", headers: map[string]string{"X-Request-Id": "r-1"}, want: "r-1"},
+		{name: "generated"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			handler := InjectContext(noopSender{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx, _ := RequestContext(r)
+				got = CorrelationID(ctx)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if got == "" || (tc.want != "" && got != tc.want) {
+				t.Fatalf("expected correlation ID %q, got %q", tc.want, got)
+			}
+			if echoed := rr.Header().Get(CorrelationIDHeader); echoed != got {
+				t.Fatalf("expected response header %q, got %q", got, echoed)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	// Embed the zone database so cron timezones resolve on minimal Lambda images.
+	_ "time/tzdata"
+)
+
+// cronSpec is a parsed five-field cron expression (minute hour day-of-month month day-of-week).
+// Only the subset that maps onto EventBridge cron is accepted: restricting both
+// day-of-month and day-of-week is rejected so local and AWS runs agree.
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{name: "minute", min: 0, max: 59}
+	cronHour   = cronField{name: "hour", min: 0, max: 23}
+	cronDom    = cronField{name: "day-of-month", min: 1, max: 31}
+	cronMonth  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}}
+	cronDow = cronField{name: "day-of-week", min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}}
+)
+
+// ValidateCron reports whether expr is a supported five-field cron expression.
+func ValidateCron(expr string) error {
+	_, err := parseCron(expr)
+	return err
+}
+
+func parseCron(expr string) (*cronSpec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("transire: cron %q: expected 5 fields, got %d", expr, len(fields))
+	}
+	spec := &cronSpec{}
+	var err error
+	if spec.minute, _, err = cronMinute.parse(fields[0]); err != nil {
+		return nil, fmt.Errorf("transire: cron %q: %w", expr, err)
+	}
+	if spec.hour, _, err = cronHour.parse(fields[1]); err != nil {
+		return nil, fmt.Errorf("transire: cron %q: %w", expr, err)
+	}
+	if spec.dom, spec.domAny, err = cronDom.parse(fields[2]); err != nil {
+		return nil, fmt.Errorf("transire: cron %q: %w", expr, err)
+	}
+	if spec.month, _, err = cronMonth.parse(fields[3]); err != nil {
+		return nil, fmt.Errorf("transire: cron %q: %w", expr, err)
+	}
+	if spec.dow, spec.dowAny, err = cronDow.parse(fields[4]); err != nil {
+		return nil, fmt.Errorf("transire: cron %q: %w", expr, err)
+	}
+	if !spec.domAny && !spec.dowAny {
+		return nil, fmt.Errorf("transire: cron %q: day-of-month and day-of-week cannot both be restricted", expr)
+	}
+	// Sunday may be written as 0 or 7.
+	if spec.dow&(1<<7) != 0 {
+		spec.dow |= 1
+	}
+	return spec, nil
+}
+
+// parse returns the bitset of allowed values and whether the field is unrestricted.
+func (f cronField) parse(raw string) (uint64, bool, error) {
+	if raw == "*" || raw == "?" {
+		return f.all(), true, nil
+	}
+	var bits uint64
+	for _, part := range strings.Split(raw, ",") {
+		rangePart, step := part, 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n <= 0 {
+				return 0, false, fmt.Errorf("invalid %s step %q", f.name, part)
+			}
+			rangePart, step = part[:idx], n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = f.value(bounds[0]); err != nil {
+				return 0, false, err
+			}
+			if hi, err = f.value(bounds[1]); err != nil {
+				return 0, false, err
+			}
+			if lo > hi {
+				return 0, false, fmt.Errorf("invalid %s range %q", f.name, rangePart)
+			}
+		default:
+			v, err := f.value(rangePart)
+			if err != nil {
+				return 0, false, err
+			}
+			lo = v
+			if step == 1 {
+				hi = v
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, false, nil
+}
+
+func (f cronField) value(raw string) (int, error) {
+	if v, ok := f.names[strings.ToUpper(raw)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s value %q", f.name, raw)
+	}
+	return v, nil
+}
+
+func (f cronField) all() uint64 {
+	var bits uint64
+	for v := f.min; v <= f.max; v++ {
+		bits |= 1 << uint(v)
+	}
+	return bits
+}
+
+// next returns the first matching minute strictly after t, evaluated in t's location.
+func (c *cronSpec) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Five years covers every satisfiable expression (e.g. 29 Feb on a given weekday).
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.dom&(1<<uint(t.Day())) == 0 || c.dow&(1<<uint(t.Weekday())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,86 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronRejectsInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"0 0 1 * MON",
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	base := time.Date(2025, time.March, 7, 1, 30, 0, 0, time.UTC) // Friday
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, time.March, 7, 1, 45, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2025, time.March, 7, 2, 0, 0, 0, time.UTC)},
+		{"0 2 * * MON-FRI", time.Date(2025, time.March, 7, 2, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1-5", time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2025, time.March, 9, 9, 0, 0, 0, time.UTC)},
+		{"30 6 1 * ?", time.Date(2025, time.April, 1, 6, 30, 0, 0, time.UTC)},
+		{"0 0 29 FEB *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		spec, err := parseCron(tc.expr)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.expr, err)
+		}
+		if got := spec.next(base); !got.Equal(tc.want) {
+			t.Errorf("%q: expected %s, got %s", tc.expr, tc.want, got)
+		}
+	}
+}
+
+func TestScheduleNextHonoursLocation(t *testing.T) {
+	app := New()
+	app.RegisterCronHandler("nightly", "0 2 * * *", "America/New_York", func(ctx Context, at time.Time) error { return nil })
+
+	sched := app.Schedules()["nightly"]
+	got := sched.Next(time.Date(2025, time.January, 10, 12, 0, 0, 0, time.UTC))
+	want := time.Date(2025, time.January, 11, 7, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestScheduleNextFixedRate(t *testing.T) {
+	sched := Schedule{Every: time.Minute}
+	now := time.Now()
+	if got := sched.Next(now); !got.Equal(now.Add(time.Minute)) {
+		t.Fatalf("unexpected next for fixed rate: %s", got)
+	}
+	if got := (Schedule{}).Next(now); !got.IsZero() {
+		t.Fatalf("expected zero next for empty schedule, got %s", got)
+	}
+}
+
+func TestRegisterCronHandlerPanicsOnInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic for invalid cron expression")
+		}
+	}()
+	New().RegisterCronHandler("bad", "not a cron", "", func(ctx Context, at time.Time) error { return nil })
+}
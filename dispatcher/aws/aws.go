@@ -7,24 +7,41 @@ package aws
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	chiproxy "github.com/awslabs/aws-lambda-go-api-proxy/chi"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	transire "github.com/transire/transire"
 )
 
 const queueEnvPrefix = "TRANSIRE_QUEUE_"
 const queueNameEnvSuffix = "_NAME"
+const deadLetterURLEnvSuffix = "_DLQ_URL"
 const scheduleEnvPrefix = "TRANSIRE_SCHEDULE_"
+const topicEnvPrefix = "TRANSIRE_TOPIC_"
+
+// shutdownBudget fits the shutdown hooks into the ~500ms Lambda allows between SIGTERM and SIGKILL.
+const shutdownBudget = 400 * time.Millisecond
 
 // Dispatcher wires AWS events (API Gateway v2, SQS, EventBridge) into handlers.
 type Dispatcher struct {
@@ -36,13 +53,24 @@ func (d *Dispatcher) Name() string {
 	return "aws"
 }
 
-// Run sets up the Lambda handler for API Gateway, SQS, and EventBridge events.
+// Run sets up the Lambda handler for API Gateway, SQS, and EventBridge events. The app's
+// OnStart hooks run during the cold start and its OnShutdown hooks when Lambda retires
+// the execution environment (Lambda only signals this to functions with an extension).
 func (d *Dispatcher) Run(ctx context.Context, app *transire.App) error {
 	region := d.Region
 	if region == "" {
 		region = os.Getenv("AWS_REGION")
 	}
 
+	if app.Logger() == nil {
+		// CloudWatch Logs indexes JSON lines, so default to structured output.
+		app.SetLogger(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+	}
+	logger := app.Logger()
+	if app.Metrics() == nil {
+		app.SetMetrics(&emfMetrics{w: os.Stdout})
+	}
+
 	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
 	if err != nil {
 		return fmt.Errorf("load aws config: %w", err)
@@ -50,17 +78,32 @@ func (d *Dispatcher) Run(ctx context.Context, app *transire.App) error {
 
 	queueURLs := make(map[string]string)
 	queueNames := make(map[string]string)
+	deadLetterURLs := make(map[string]string)
 	scheduleNames := make(map[string]string)
 	for name := range app.QueueHandlers() {
 		envKey := queueEnvVar(name)
 		url := os.Getenv(envKey)
 		if url == "" {
-			log.Printf("queue %s missing URL in env %s; messages to this queue will fail\n", name, envKey)
+			logger.Warn("queue URL missing; messages to this queue will fail", "queue", name, "env", envKey)
 		}
 		queueURLs[name] = url
 
 		nameKey := queueNameEnvVar(name)
 		queueNames[name] = os.Getenv(nameKey)
+
+		if app.QueueConfig(name).MaxReceiveCount > 0 {
+			deadLetterURLs[name] = os.Getenv(deadLetterURLEnvVar(name))
+		}
+	}
+
+	topicARNs := make(map[string]string)
+	for name := range app.Topics() {
+		envKey := topicEnvVar(name)
+		arn := os.Getenv(envKey)
+		if arn == "" {
+			logger.Warn("topic ARN missing; publishes to this topic will fail", "topic", name, "env", envKey)
+		}
+		topicARNs[name] = arn
 	}
 
 	for name := range app.Schedules() {
@@ -68,15 +111,40 @@ func (d *Dispatcher) Run(ctx context.Context, app *transire.App) error {
 		scheduleNames[name] = os.Getenv(nameKey)
 	}
 
+	var leases *scheduleLeases
+	if table := os.Getenv(scheduleLockTableEnv); table != "" {
+		leases = &scheduleLeases{client: dynamodb.NewFromConfig(cfg), table: table, now: time.Now}
+	}
+
 	sqsClient := sqs.NewFromConfig(cfg)
 	queueSender := &awsQueueSender{
-		client: sqsClient,
-		urls:   queueURLs,
+		client:         sqsClient,
+		urls:           queueURLs,
+		deadLetterURLs: deadLetterURLs,
+		metrics:        app.Metrics(),
 	}
 	app.SetQueueSender(queueSender)
+	app.SetTopicPublisher(&snsTopicPublisher{
+		client: sns.NewFromConfig(cfg),
+		arns:   topicARNs,
+	})
+	if group := os.Getenv(timerGroupEnv); group != "" {
+		queueARNs := make(map[string]string)
+		for name := range app.QueueHandlers() {
+			queueARNs[name] = os.Getenv(queueARNEnvVar(name))
+		}
+		app.SetTimers(&awsTimers{
+			client:  newSchedulerClient(cfg),
+			group:   group,
+			roleARN: os.Getenv(timerRoleEnv),
+			queues:  queueARNs,
+			sender:  queueSender,
+			now:     time.Now,
+		})
+	}
 
 	root := chi.NewRouter()
-	root.Use(transire.InjectContext(app.QueueSender()))
+	root.Use(apiGatewayRequestID, app.ContextMiddleware())
 	root.Mount("/", app.Router())
 
 	adapter := chiproxy.NewV2(root)
@@ -86,6 +154,16 @@ func (d *Dispatcher) Run(ctx context.Context, app *transire.App) error {
 	}
 
 	handler := func(ctx context.Context, raw json.RawMessage) (any, error) {
+		if lc, ok := lambdacontext.FromContext(ctx); ok {
+			ctx = transire.ContextWithLogger(ctx, logger.With("lambda_request_id", lc.AwsRequestID))
+		}
+		// Lambda may freeze the process once the invocation returns, so export spans now.
+		defer func() {
+			if err := app.FlushTraces(ctx); err != nil {
+				logger.Warn("flush traces", "error", err)
+			}
+		}()
+
 		// Detect API Gateway HTTP event
 		if looksLikeAPIGateway(raw) {
 			var req events.APIGatewayV2HTTPRequest
@@ -101,7 +179,7 @@ func (d *Dispatcher) Run(ctx context.Context, app *transire.App) error {
 			if err := json.Unmarshal(raw, &sqsEvent); err != nil {
 				return nil, err
 			}
-			return nil, d.handleSQSEvent(ctx, app, sqsEvent, fqdnToLogical)
+			return d.handleSQSEvent(ctx, app, queueSender, sqsEvent, fqdnToLogical), nil
 		}
 
 		// Treat as EventBridge schedule
@@ -109,78 +187,465 @@ func (d *Dispatcher) Run(ctx context.Context, app *transire.App) error {
 		if err := json.Unmarshal(raw, &ev); err != nil {
 			return nil, err
 		}
-		return nil, d.handleSchedule(ctx, app, ev, fqdnToLogical)
+		return nil, d.handleSchedule(ctx, app, leases, ev, fqdnToLogical)
 	}
 
-	lambda.Start(handler)
+	if err := app.Start(ctx); err != nil {
+		return err
+	}
+	// Lambda handles one event at a time per execution environment, so there is no
+	// in-flight work to drain when it sends SIGTERM; only the shutdown hooks run.
+	lambda.StartWithOptions(handler, lambda.WithEnableSIGTERM(func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownBudget)
+		defer cancel()
+		if err := app.Shutdown(shutdownCtx); err != nil {
+			logger.Error("shutdown hooks failed", "error", err)
+		}
+		_ = app.ShutdownTraces(shutdownCtx)
+	}))
 	return nil
 }
 
-func (d *Dispatcher) handleSQSEvent(ctx context.Context, app *transire.App, ev events.SQSEvent, fqdnToLogical map[string]string) error {
+// handleSQSEvent runs each record through its queue handler and reports the records that
+// failed, so SQS (with ReportBatchItemFailures enabled) redelivers only those. Records for
+// queues with a batch handler are delivered to it together, split on FIFO queues so each
+// call holds one record per message group. On FIFO queues, records that follow a
+// redelivered record in the same message group are returned unprocessed.
+func (d *Dispatcher) handleSQSEvent(ctx context.Context, app *transire.App, sender *awsQueueSender, ev events.SQSEvent, fqdnToLogical map[string]string) events.SQSEventResponse {
+	resp := events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{}}
+	blocked := map[string]bool{}
+	fail := func(queueName string, record events.SQSMessage) {
+		resp.BatchItemFailures = append(resp.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: record.MessageId})
+		if group := record.Attributes["MessageGroupId"]; group != "" {
+			blocked[queueName+"/"+group] = true
+		}
+	}
+	isBlocked := func(queueName string, record events.SQSMessage) bool {
+		group := record.Attributes["MessageGroupId"]
+		return group != "" && blocked[queueName+"/"+group]
+	}
+	logger := app.NewContext(ctx).Logger
+
+	var batchQueues []string
+	batches := map[string][]events.SQSMessage{}
 	for _, record := range ev.Records {
 		queueFQDN := extractQueueName(record.EventSourceARN)
 		queueName := fqdnToLogical[queueFQDN]
 		if queueName == "" {
 			queueName = queueFQDN
 		}
-		handler, ok := app.QueueHandlers()[queueName]
+		if _, ok := app.BatchQueueHandler(queueName); ok {
+			if _, seen := batches[queueName]; !seen {
+				batchQueues = append(batchQueues, queueName)
+			}
+			batches[queueName] = append(batches[queueName], record)
+			continue
+		}
+		if isBlocked(queueName, record) {
+			fail(queueName, record)
+			continue
+		}
+		handler, ok := app.QueueHandler(queueName)
 		if !ok {
-			log.Printf("no handler for queue %s (fqdn %s)", queueName, queueFQDN)
+			logger.Error("no handler for queue", "queue", queueName, "fqdn", queueFQDN)
+			fail(queueName, record)
 			continue
 		}
 
-		msg := transire.Message{
-			ID:         record.MessageId,
-			Queue:      queueName,
-			Body:       []byte(record.Body),
-			Attributes: map[string]string{},
+		msg := sqsMessage(queueName, record)
+		hctx := app.QueueContext(ctx, msg)
+		if err := handler(hctx, msg); err != nil {
+			hctx.Logger.Error("queue handler failed", "error", err)
+			if sender.settleFailure(ctx, hctx.Logger, app.QueueConfig(queueName), msg, record, err) {
+				fail(queueName, record)
+			}
 		}
-		for k, v := range record.MessageAttributes {
-			msg.Attributes[k] = *v.StringValue
+	}
+
+	for _, queueName := range batchQueues {
+		handler, _ := app.BatchQueueHandler(queueName)
+		for _, round := range groupRounds(batches[queueName]) {
+			var records []events.SQSMessage
+			for _, record := range round {
+				if isBlocked(queueName, record) {
+					fail(queueName, record)
+					continue
+				}
+				records = append(records, record)
+			}
+			if len(records) == 0 {
+				continue
+			}
+			msgs := make([]transire.Message, len(records))
+			for i, record := range records {
+				msgs[i] = sqsMessage(queueName, record)
+			}
+
+			hctx := app.BatchContext(ctx, queueName, len(msgs))
+			failed := map[string]error{}
+			for _, f := range handler(hctx, msgs).Failures {
+				if f.Err == nil {
+					f.Err = errors.New("message reported as failed")
+				}
+				failed[f.MessageID] = f.Err
+			}
+			for i, record := range records {
+				err, ok := failed[record.MessageId]
+				if !ok {
+					continue
+				}
+				msgLogger := hctx.Logger.With("message_id", record.MessageId)
+				msgLogger.Error("batch handler failed message", "error", err)
+				if sender.settleFailure(ctx, msgLogger, app.QueueConfig(queueName), msgs[i], record, err) {
+					fail(queueName, record)
+				}
+			}
 		}
+	}
+	return resp
+}
 
-		if err := handler(transire.Context{
-			Context: ctx,
-			Queues:  app.QueueSender(),
-		}, msg); err != nil {
-			log.Printf("handler for queue %s failed: %v", queueName, err)
+// groupRounds splits records, in order, into the batches delivered to a batch handler. A
+// batch holds at most one record per FIFO message group, so a failed record blocks the
+// rest of its group before they are handled. Records without a group never split a batch.
+func groupRounds(records []events.SQSMessage) [][]events.SQSMessage {
+	var rounds [][]events.SQSMessage
+	var round []events.SQSMessage
+	groups := map[string]bool{}
+	for _, record := range records {
+		group := record.Attributes["MessageGroupId"]
+		if group != "" && groups[group] {
+			rounds = append(rounds, round)
+			round, groups = nil, map[string]bool{}
+		}
+		if group != "" {
+			groups[group] = true
 		}
+		round = append(round, record)
 	}
-	return nil
+	if len(round) > 0 {
+		rounds = append(rounds, round)
+	}
+	return rounds
 }
 
-func (d *Dispatcher) handleSchedule(ctx context.Context, app *transire.App, ev events.CloudWatchEvent, fqdnToLogical map[string]string) error {
+func sqsMessage(queueName string, record events.SQSMessage) transire.Message {
+	msg := transire.Message{
+		ID:         record.MessageId,
+		Queue:      queueName,
+		Body:       []byte(record.Body),
+		Attributes: map[string]string{},
+	}
+	for k, v := range record.MessageAttributes {
+		if v.StringValue != nil {
+			msg.Attributes[k] = *v.StringValue
+		}
+	}
+	return msg
+}
+
+// scheduleDetail is the detail of the EventBridge input the build gives schedule targets,
+// and of the events sent by "transire trigger".
+type scheduleDetail struct {
+	Metadata map[string]string `json:"metadata"`
+}
+
+// handleSchedule runs the schedule an EventBridge event is for. Schedules that must not
+// overlap run under a lease; a run skipped because another holds it returns nil, while a
+// queued run that cannot get it fails so that Lambda retries the invocation.
+func (d *Dispatcher) handleSchedule(ctx context.Context, app *transire.App, leases *scheduleLeases, ev events.CloudWatchEvent, fqdnToLogical map[string]string) error {
 	name := extractRuleName(ev.Resources)
 	if mapped := fqdnToLogical[name]; mapped != "" {
 		name = mapped
 	}
-	sched, ok := app.Schedules()[name]
+	handler, ok := app.ScheduleHandler(name)
 	if !ok {
-		log.Printf("no schedule handler for %s", name)
+		app.NewContext(ctx).Logger.Error("no schedule handler", "schedule", name)
 		return nil
 	}
-	return sched.Handler(transire.Context{
-		Context: ctx,
-		Queues:  app.QueueSender(),
-	}, ev.Time)
+	at := ev.Time
+	if at.IsZero() {
+		// Runs invoked after a deploy (WithRunOnStart) carry no event time.
+		at = time.Now()
+	}
+	var detail scheduleDetail
+	if len(ev.Detail) > 0 {
+		if err := json.Unmarshal(ev.Detail, &detail); err != nil {
+			app.ScheduleContext(ctx, name).Logger.Warn("ignoring invalid schedule event detail", "error", err)
+		}
+	}
+	hctx := app.ScheduleContext(transire.ContextWithScheduleData(ctx, detail.Metadata), name)
+	if policy := app.Schedules()[name].Concurrency; policy.Exclusive() {
+		if leases == nil {
+			hctx.Logger.Warn("schedule lock table missing; runs may overlap", "env", scheduleLockTableEnv)
+		} else {
+			owner := ev.ID
+			if lc, ok := lambdacontext.FromContext(ctx); ok {
+				owner = lc.AwsRequestID
+			}
+			release, err := leases.hold(ctx, name, owner, policy)
+			if errors.Is(err, errLeaseHeld) && policy == transire.ConcurrencySkip {
+				hctx.Logger.Warn("schedule run skipped; previous run still in progress", "at", at)
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("schedule %s: %w", name, err)
+			}
+			defer release()
+		}
+	}
+	return handler(hctx, at)
+}
+
+type snsAPI interface {
+	Publish(context.Context, *sns.PublishInput, ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// snsTopicPublisher publishes to SNS topics; subscription queues receive the raw payload.
+type snsTopicPublisher struct {
+	client snsAPI
+	arns   map[string]string
+}
+
+func (p *snsTopicPublisher) Publish(ctx context.Context, topic string, payload []byte) error {
+	arn := p.arns[topic]
+	if arn == "" {
+		return fmt.Errorf("topic %s has no ARN configured", topic)
+	}
+	in := &sns.PublishInput{
+		TopicArn: aws.String(arn),
+		Message:  aws.String(string(payload)),
+	}
+	// With raw message delivery, SNS message attributes reach subscribers as SQS attributes.
+	if attrs := transire.OutgoingAttributes(ctx, nil); len(attrs) > 0 {
+		in.MessageAttributes = make(map[string]snstypes.MessageAttributeValue, len(attrs))
+		for k, v := range attrs {
+			in.MessageAttributes[k] = snstypes.MessageAttributeValue{
+				DataType:    aws.String("String"),
+				StringValue: aws.String(v),
+			}
+		}
+	}
+	_, err := p.client.Publish(ctx, in)
+	return err
+}
+
+// SQS batch limits: at most 10 entries and 256 KiB of payload per SendMessageBatch call.
+const (
+	maxBatchEntries = 10
+	maxBatchBytes   = 256 * 1024
+)
+
+type sqsAPI interface {
+	SendMessage(context.Context, *sqs.SendMessageInput, ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+	SendMessageBatch(context.Context, *sqs.SendMessageBatchInput, ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+	ChangeMessageVisibility(context.Context, *sqs.ChangeMessageVisibilityInput, ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
 }
 
 type awsQueueSender struct {
-	client *sqs.Client
-	urls   map[string]string
+	client         sqsAPI
+	urls           map[string]string
+	deadLetterURLs map[string]string
+	metrics        transire.Metrics
+}
+
+// sent counts n messages sent to queue.
+func (s *awsQueueSender) sent(queue string, n int) {
+	if s.metrics == nil {
+		return
+	}
+	for i := 0; i < n; i++ {
+		s.metrics.MessageSent(queue)
+	}
+}
+
+// settleFailure applies the retry policy to a failed record and reports whether SQS
+// should redeliver it. Transient failures stay on the queue with their visibility
+// timeout set to the backoff delay. Permanent failures are forwarded to the
+// dead-letter queue (or dropped when there is none) and removed from the queue.
+func (s *awsQueueSender) settleFailure(ctx context.Context, logger *slog.Logger, cfg transire.QueueConfig, msg transire.Message, record events.SQSMessage, err error) bool {
+	if transire.IsPermanent(err) {
+		if cfg.MaxReceiveCount <= 0 {
+			logger.Warn("dropping message after permanent failure")
+			return false
+		}
+		dlqURL := s.deadLetterURLs[msg.Queue]
+		if dlqURL == "" {
+			logger.Warn("no dead-letter URL configured; leaving message to the redrive policy")
+			return true
+		}
+		opts := transire.SendOptions{Attributes: msg.Attributes}
+		if group := record.Attributes["MessageGroupId"]; group != "" {
+			// FIFO dead-letter queues need the group and a deduplication ID too.
+			opts.GroupID = group
+			opts.DeduplicationID = msg.ID
+		}
+		if _, sendErr := s.client.SendMessage(ctx, sendMessageInput(dlqURL, msg.Body, opts)); sendErr != nil {
+			logger.Error("move message to dead-letter queue", "dead_letter_queue", transire.DeadLetterQueueName(msg.Queue), "error", sendErr)
+			return true
+		}
+		return false
+	}
+
+	receives, convErr := strconv.Atoi(record.Attributes["ApproximateReceiveCount"])
+	if convErr != nil || receives < 1 {
+		receives = 1
+	}
+	delay := cfg.RetryPolicy.Delay(err, receives)
+	if url := s.urls[msg.Queue]; url != "" && record.ReceiptHandle != "" {
+		_, visErr := s.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+			QueueUrl:          aws.String(url),
+			ReceiptHandle:     aws.String(record.ReceiptHandle),
+			VisibilityTimeout: int32((delay + time.Second - 1) / time.Second),
+		})
+		if visErr != nil {
+			logger.Error("set retry delay", "error", visErr)
+		}
+	}
+	return true
 }
 
 func (s *awsQueueSender) Send(ctx context.Context, queue string, payload []byte) error {
+	return s.SendWithOptions(ctx, queue, payload, transire.SendOptions{})
+}
+
+// SendWithOptions maps SendOptions onto the SQS delay, attribute, and FIFO parameters.
+// The correlation ID and span context of ctx are sent as message attributes.
+func (s *awsQueueSender) SendWithOptions(ctx context.Context, queue string, payload []byte, opts transire.SendOptions) error {
+	url := s.urls[queue]
+	if url == "" {
+		return fmt.Errorf("queue %s has no URL configured", queue)
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	opts.Attributes = transire.OutgoingAttributes(ctx, opts.Attributes)
+	if _, err := s.client.SendMessage(ctx, sendMessageInput(url, payload, opts)); err != nil {
+		return err
+	}
+	s.sent(queue, 1)
+	return nil
+}
+
+// SendBatch sends entries with SendMessageBatch, chunked to the SQS limits.
+// Entries rejected by SQS or too large to send are reported in a *transire.BatchError.
+func (s *awsQueueSender) SendBatch(ctx context.Context, queue string, entries []transire.BatchEntry) error {
 	url := s.urls[queue]
 	if url == "" {
 		return fmt.Errorf("queue %s has no URL configured", queue)
 	}
 
-	_, err := s.client.SendMessage(ctx, &sqs.SendMessageInput{
+	entries = append([]transire.BatchEntry(nil), entries...)
+	for i := range entries {
+		entries[i].Options.Attributes = transire.OutgoingAttributes(ctx, entries[i].Options.Attributes)
+	}
+
+	batchErr := &transire.BatchError{Queue: queue}
+	fail := func(idx int, err error) {
+		batchErr.Failed = append(batchErr.Failed, transire.BatchFailure{Index: idx, Err: err})
+	}
+
+	for _, chunk := range planBatches(entries, fail) {
+		in := &sqs.SendMessageBatchInput{QueueUrl: aws.String(url)}
+		for _, idx := range chunk {
+			msg := sendMessageInput(url, entries[idx].Body, entries[idx].Options)
+			in.Entries = append(in.Entries, sqstypes.SendMessageBatchRequestEntry{
+				Id:                     aws.String(strconv.Itoa(idx)),
+				MessageBody:            msg.MessageBody,
+				DelaySeconds:           msg.DelaySeconds,
+				MessageAttributes:      msg.MessageAttributes,
+				MessageDeduplicationId: msg.MessageDeduplicationId,
+				MessageGroupId:         msg.MessageGroupId,
+			})
+		}
+		out, err := s.client.SendMessageBatch(ctx, in)
+		if err != nil {
+			for _, idx := range chunk {
+				fail(idx, err)
+			}
+			continue
+		}
+		for _, f := range out.Failed {
+			idx, convErr := strconv.Atoi(aws.ToString(f.Id))
+			if convErr != nil {
+				continue
+			}
+			fail(idx, fmt.Errorf("%s: %s", aws.ToString(f.Code), aws.ToString(f.Message)))
+		}
+	}
+
+	s.sent(queue, len(entries)-len(batchErr.Failed))
+	if len(batchErr.Failed) > 0 {
+		sort.Slice(batchErr.Failed, func(i, j int) bool { return batchErr.Failed[i].Index < batchErr.Failed[j].Index })
+		return batchErr
+	}
+	return nil
+}
+
+// planBatches groups entry indexes into SendMessageBatch calls that respect the SQS limits.
+// Entries that can never be sent are passed to fail instead.
+func planBatches(entries []transire.BatchEntry, fail func(int, error)) [][]int {
+	var chunks [][]int
+	var current []int
+	currentBytes := 0
+	for i, entry := range entries {
+		if err := entry.Options.Validate(); err != nil {
+			fail(i, err)
+			continue
+		}
+		size := entrySize(entry)
+		if size > maxBatchBytes {
+			fail(i, fmt.Errorf("message of %d bytes exceeds the SQS limit of %d", size, maxBatchBytes))
+			continue
+		}
+		if len(current) == maxBatchEntries || currentBytes+size > maxBatchBytes {
+			chunks = append(chunks, current)
+			current, currentBytes = nil, 0
+		}
+		current = append(current, i)
+		currentBytes += size
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// entrySize approximates how SQS counts message size: body plus attribute names, types, and values.
+func entrySize(entry transire.BatchEntry) int {
+	size := len(entry.Body)
+	for k, v := range entry.Options.Attributes {
+		size += len(k) + len("String") + len(v)
+	}
+	return size
+}
+
+func sendMessageInput(url string, payload []byte, opts transire.SendOptions) *sqs.SendMessageInput {
+	in := &sqs.SendMessageInput{
 		QueueUrl:    aws.String(url),
 		MessageBody: aws.String(string(payload)),
-	})
-	return err
+	}
+	if opts.Delay > 0 {
+		// SQS delays are whole seconds; round up so messages are never early.
+		in.DelaySeconds = int32((opts.Delay + time.Second - 1) / time.Second)
+	}
+	if len(opts.Attributes) > 0 {
+		in.MessageAttributes = make(map[string]sqstypes.MessageAttributeValue, len(opts.Attributes))
+		for k, v := range opts.Attributes {
+			in.MessageAttributes[k] = sqstypes.MessageAttributeValue{
+				DataType:    aws.String("String"),
+				StringValue: aws.String(v),
+			}
+		}
+	}
+	if opts.DeduplicationID != "" {
+		in.MessageDeduplicationId = aws.String(opts.DeduplicationID)
+	}
+	if opts.GroupID != "" {
+		in.MessageGroupId = aws.String(opts.GroupID)
+	}
+	return in
 }
 
 func queueEnvVar(queue string) string {
@@ -195,6 +660,18 @@ func queueNameEnvVar(queue string) string {
 	return queueEnvPrefix + name + queueNameEnvSuffix
 }
 
+func deadLetterURLEnvVar(queue string) string {
+	name := strings.ToUpper(queue)
+	name = strings.ReplaceAll(name, "-", "_")
+	return queueEnvPrefix + name + deadLetterURLEnvSuffix
+}
+
+func topicEnvVar(topic string) string {
+	name := strings.ToUpper(topic)
+	name = strings.ReplaceAll(name, "-", "_")
+	return topicEnvPrefix + name + "_ARN"
+}
+
 func scheduleNameEnvVar(name string) string {
 	up := strings.ToUpper(name)
 	up = strings.ReplaceAll(up, "-", "_")
@@ -218,6 +695,17 @@ func extractRuleName(resources []string) string {
 	return parts[len(parts)-1]
 }
 
+// apiGatewayRequestID makes API Gateway's request ID the request's ID, which
+// ContextMiddleware logs and handlers read with chi's middleware.GetReqID.
+func apiGatewayRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rc, ok := core.GetAPIGatewayV2ContextFromContext(r.Context()); ok && rc.RequestID != "" {
+			r = r.WithContext(context.WithValue(r.Context(), middleware.RequestIDKey, rc.RequestID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func looksLikeAPIGateway(raw json.RawMessage) bool {
 	return strings.Contains(string(raw), `"requestContext"`) && strings.Contains(string(raw), `"http"`)
 }
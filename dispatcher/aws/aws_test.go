@@ -4,7 +4,28 @@
 
 package aws
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	chiproxy "github.com/awslabs/aws-lambda-go-api-proxy/chi"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	transire "github.com/transire/transire"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
 
 func TestQueueEnvVar(t *testing.T) {
 	got := queueEnvVar("hello-queue")
@@ -51,3 +72,474 @@ func TestInvert(t *testing.T) {
 		t.Fatalf("unexpected invert result: %+v", out)
 	}
 }
+
+func TestSendMessageInputMapsOptions(t *testing.T) {
+	in := sendMessageInput("url", []byte("body"), transire.SendOptions{
+		Delay:           1500 * time.Millisecond,
+		Attributes:      map[string]string{"tenant": "acme"},
+		DeduplicationID: "dedup",
+		GroupID:         "group",
+	})
+	if aws.ToString(in.QueueUrl) != "url" || aws.ToString(in.MessageBody) != "body" {
+		t.Fatalf("unexpected url/body: %+v", in)
+	}
+	if in.DelaySeconds != 2 {
+		t.Fatalf("expected delay rounded up to 2s, got %d", in.DelaySeconds)
+	}
+	attr, ok := in.MessageAttributes["tenant"]
+	if !ok || aws.ToString(attr.StringValue) != "acme" || aws.ToString(attr.DataType) != "String" {
+		t.Fatalf("unexpected attributes: %+v", in.MessageAttributes)
+	}
+	if aws.ToString(in.MessageDeduplicationId) != "dedup" || aws.ToString(in.MessageGroupId) != "group" {
+		t.Fatalf("unexpected fifo fields: %+v", in)
+	}
+
+	plain := sendMessageInput("url", []byte("body"), transire.SendOptions{})
+	if plain.DelaySeconds != 0 || plain.MessageAttributes != nil || plain.MessageGroupId != nil {
+		t.Fatalf("zero options should leave input bare: %+v", plain)
+	}
+}
+
+type fakeSQS struct {
+	sent       []*sqs.SendMessageInput
+	batches    [][]sqstypes.SendMessageBatchRequestEntry
+	failIDs    map[string]bool
+	visibility []*sqs.ChangeMessageVisibilityInput
+}
+
+func (f *fakeSQS) SendMessage(ctx context.Context, in *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	f.sent = append(f.sent, in)
+	return &sqs.SendMessageOutput{}, nil
+}
+
+func (f *fakeSQS) ChangeMessageVisibility(ctx context.Context, in *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	f.visibility = append(f.visibility, in)
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+func (f *fakeSQS) SendMessageBatch(ctx context.Context, in *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	f.batches = append(f.batches, in.Entries)
+	out := &sqs.SendMessageBatchOutput{}
+	for _, e := range in.Entries {
+		if f.failIDs[aws.ToString(e.Id)] {
+			out.Failed = append(out.Failed, sqstypes.BatchResultErrorEntry{Id: e.Id, Code: aws.String("InternalError"), Message: aws.String("boom")})
+		}
+	}
+	return out, nil
+}
+
+func TestSendBatchChunksByCount(t *testing.T) {
+	client := &fakeSQS{}
+	sender := &awsQueueSender{client: client, urls: map[string]string{"q": "url"}}
+	entries := make([]transire.BatchEntry, 25)
+	for i := range entries {
+		entries[i] = transire.BatchEntry{Body: []byte("x")}
+	}
+	if err := sender.SendBatch(context.Background(), "q", entries); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.batches) != 3 || len(client.batches[0]) != 10 || len(client.batches[2]) != 5 {
+		t.Fatalf("unexpected chunking: %d batches", len(client.batches))
+	}
+}
+
+func TestSendBatchChunksBySize(t *testing.T) {
+	client := &fakeSQS{}
+	sender := &awsQueueSender{client: client, urls: map[string]string{"q": "url"}}
+	big := make([]byte, 100*1024)
+	entries := []transire.BatchEntry{{Body: big}, {Body: big}, {Body: big}}
+	if err := sender.SendBatch(context.Background(), "q", entries); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.batches) != 2 || len(client.batches[0]) != 2 || len(client.batches[1]) != 1 {
+		t.Fatalf("unexpected size chunking: %+v", client.batches)
+	}
+}
+
+func TestSendBatchReportsFailures(t *testing.T) {
+	client := &fakeSQS{failIDs: map[string]bool{"1": true}}
+	sender := &awsQueueSender{client: client, urls: map[string]string{"q": "url"}}
+	entries := []transire.BatchEntry{
+		{Body: []byte("ok")},
+		{Body: []byte("rejected")},
+		{Body: make([]byte, maxBatchBytes+1)},
+	}
+	err := sender.SendBatch(context.Background(), "q", entries)
+	var batchErr *transire.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected BatchError, got %v", err)
+	}
+	if len(batchErr.Failed) != 2 || batchErr.Failed[0].Index != 1 || batchErr.Failed[1].Index != 2 {
+		t.Fatalf("unexpected failures: %+v", batchErr.Failed)
+	}
+	if len(client.batches) != 1 || len(client.batches[0]) != 2 {
+		t.Fatalf("oversized entry should not be sent: %+v", client.batches)
+	}
+}
+
+func TestHandleSQSEventReportsFailedRecords(t *testing.T) {
+	app := transire.New()
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		if string(msg.Body) == "bad" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	ev := events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "m1", Body: "good", EventSourceARN: "arn:aws:sqs:eu-west-2:123:app-work-dev"},
+		{MessageId: "m2", Body: "bad", EventSourceARN: "arn:aws:sqs:eu-west-2:123:app-work-dev"},
+		{MessageId: "m3", Body: "orphan", EventSourceARN: "arn:aws:sqs:eu-west-2:123:app-unknown-dev"},
+	}}
+	d := &Dispatcher{}
+	sender := &awsQueueSender{client: &fakeSQS{}, urls: map[string]string{"work": "work-url"}}
+	resp := d.handleSQSEvent(context.Background(), app, sender, ev, map[string]string{"app-work-dev": "work"})
+
+	if len(resp.BatchItemFailures) != 2 {
+		t.Fatalf("expected 2 failures, got %+v", resp.BatchItemFailures)
+	}
+	if resp.BatchItemFailures[0].ItemIdentifier != "m2" || resp.BatchItemFailures[1].ItemIdentifier != "m3" {
+		t.Fatalf("unexpected failures: %+v", resp.BatchItemFailures)
+	}
+
+	ok := d.handleSQSEvent(context.Background(), app, sender, events.SQSEvent{Records: ev.Records[:1]}, map[string]string{"app-work-dev": "work"})
+	if ok.BatchItemFailures == nil || len(ok.BatchItemFailures) != 0 {
+		t.Fatalf("expected empty failure list, got %+v", ok.BatchItemFailures)
+	}
+}
+
+func TestHandleSQSEventLogsWithInvocationFields(t *testing.T) {
+	var buf bytes.Buffer
+	app := transire.New()
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		ctx.Logger.Info("handled")
+		return nil
+	})
+
+	ctx := transire.ContextWithLogger(context.Background(), slog.New(slog.NewJSONHandler(&buf, nil)).With("lambda_request_id", "req-1"))
+	ev := events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "m1", Body: "hi", EventSourceARN: "arn:aws:sqs:eu-west-2:123:app-work-dev"},
+	}}
+	d := &Dispatcher{}
+	d.handleSQSEvent(ctx, app, &awsQueueSender{client: &fakeSQS{}}, ev, map[string]string{"app-work-dev": "work"})
+
+	for _, want := range []string{`"lambda_request_id":"req-1"`, `"queue":"work"`, `"message_id":"m1"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("expected %s in log output %q", want, buf.String())
+		}
+	}
+}
+
+func TestHandleSQSEventAppliesRetryPolicy(t *testing.T) {
+	app := transire.New()
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		switch string(msg.Body) {
+		case "poison":
+			return transire.Permanent(errors.New("bad payload"))
+		case "throttled":
+			return transire.RetryAfter(errors.New("slow down"), 90*time.Second)
+		}
+		return errors.New("transient")
+	}, transire.WithDeadLetterQueue(5), transire.WithRetryPolicy(transire.RetryPolicy{InitialBackoff: 10 * time.Second}))
+
+	arn := "arn:aws:sqs:eu-west-2:123:app-work-dev"
+	ev := events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "m1", ReceiptHandle: "r1", Body: "poison", EventSourceARN: arn},
+		{MessageId: "m2", ReceiptHandle: "r2", Body: "throttled", EventSourceARN: arn},
+		{MessageId: "m3", ReceiptHandle: "r3", Body: "flaky", EventSourceARN: arn, Attributes: map[string]string{"ApproximateReceiveCount": "3"}},
+	}}
+	client := &fakeSQS{}
+	sender := &awsQueueSender{client: client, urls: map[string]string{"work": "work-url"}, deadLetterURLs: map[string]string{"work": "dlq-url"}}
+	resp := (&Dispatcher{}).handleSQSEvent(context.Background(), app, sender, ev, map[string]string{"app-work-dev": "work"})
+
+	if len(resp.BatchItemFailures) != 2 || resp.BatchItemFailures[0].ItemIdentifier != "m2" || resp.BatchItemFailures[1].ItemIdentifier != "m3" {
+		t.Fatalf("unexpected failures: %+v", resp.BatchItemFailures)
+	}
+	if len(client.sent) != 1 || aws.ToString(client.sent[0].QueueUrl) != "dlq-url" || aws.ToString(client.sent[0].MessageBody) != "poison" {
+		t.Fatalf("poison message should be forwarded to the dead-letter queue: %+v", client.sent)
+	}
+	if len(client.visibility) != 2 {
+		t.Fatalf("expected 2 visibility changes, got %+v", client.visibility)
+	}
+	if v := client.visibility[0]; aws.ToString(v.ReceiptHandle) != "r2" || v.VisibilityTimeout != 90 {
+		t.Fatalf("retry-after should set visibility to 90s: %+v", v)
+	}
+	if v := client.visibility[1]; aws.ToString(v.ReceiptHandle) != "r3" || v.VisibilityTimeout != 40 {
+		t.Fatalf("third receive should back off 40s: %+v", v)
+	}
+}
+
+func TestHandleSQSEventDeliversBatches(t *testing.T) {
+	app := transire.New()
+	var got [][]string
+	app.RegisterBatchQueueHandler("bulk", func(ctx transire.Context, msgs []transire.Message) transire.BatchResult {
+		var ids []string
+		var r transire.BatchResult
+		for _, msg := range msgs {
+			ids = append(ids, msg.ID)
+			if string(msg.Body) == "bad" {
+				r.Fail(msg, errors.New("rejected"))
+			}
+		}
+		got = append(got, ids)
+		return r
+	})
+
+	arn := "arn:aws:sqs:eu-west-2:123:app-bulk-dev"
+	ev := events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "m1", Body: "good", EventSourceARN: arn},
+		{MessageId: "m2", Body: "bad", EventSourceARN: arn},
+		{MessageId: "m3", Body: "good", EventSourceARN: arn},
+	}}
+	sender := &awsQueueSender{client: &fakeSQS{}, urls: map[string]string{"bulk": "bulk-url"}}
+	resp := (&Dispatcher{}).handleSQSEvent(context.Background(), app, sender, ev, map[string]string{"app-bulk-dev": "bulk"})
+
+	if len(got) != 1 || len(got[0]) != 3 {
+		t.Fatalf("expected a single batch of 3, got %v", got)
+	}
+	if len(resp.BatchItemFailures) != 1 || resp.BatchItemFailures[0].ItemIdentifier != "m2" {
+		t.Fatalf("unexpected failures: %+v", resp.BatchItemFailures)
+	}
+}
+
+func TestHandleSQSEventHoldsFIFOGroupsAfterFailure(t *testing.T) {
+	app := transire.New()
+	var handled []string
+	app.RegisterQueueHandler("billing", func(ctx transire.Context, msg transire.Message) error {
+		handled = append(handled, msg.ID)
+		if msg.ID == "a1" {
+			return errors.New("boom")
+		}
+		return nil
+	}, transire.WithFIFO())
+
+	arn := "arn:aws:sqs:eu-west-2:123:app-billing-dev.fifo"
+	record := func(id, group string) events.SQSMessage {
+		return events.SQSMessage{MessageId: id, ReceiptHandle: "r-" + id, EventSourceARN: arn, Attributes: map[string]string{"MessageGroupId": group}}
+	}
+	ev := events.SQSEvent{Records: []events.SQSMessage{record("a1", "a"), record("b1", "b"), record("a2", "a"), record("b2", "b")}}
+	sender := &awsQueueSender{client: &fakeSQS{}, urls: map[string]string{"billing": "billing-url"}}
+	resp := (&Dispatcher{}).handleSQSEvent(context.Background(), app, sender, ev, map[string]string{"app-billing-dev.fifo": "billing"})
+
+	if strings.Join(handled, ",") != "a1,b1,b2" {
+		t.Fatalf("records after a failure in the same group should not run, handled %v", handled)
+	}
+	if len(resp.BatchItemFailures) != 2 || resp.BatchItemFailures[0].ItemIdentifier != "a1" || resp.BatchItemFailures[1].ItemIdentifier != "a2" {
+		t.Fatalf("unexpected failures: %+v", resp.BatchItemFailures)
+	}
+}
+
+func TestHandleSQSEventHoldsFIFOGroupsInBatches(t *testing.T) {
+	app := transire.New()
+	var got []string
+	app.RegisterBatchQueueHandler("billing", func(ctx transire.Context, msgs []transire.Message) transire.BatchResult {
+		var ids []string
+		var r transire.BatchResult
+		for _, msg := range msgs {
+			ids = append(ids, msg.ID)
+			if msg.ID == "a1" {
+				r.Fail(msg, errors.New("boom"))
+			}
+		}
+		got = append(got, strings.Join(ids, ","))
+		return r
+	}, transire.WithFIFO())
+
+	arn := "arn:aws:sqs:eu-west-2:123:app-billing-dev.fifo"
+	record := func(id, group string) events.SQSMessage {
+		return events.SQSMessage{MessageId: id, ReceiptHandle: "r-" + id, EventSourceARN: arn, Attributes: map[string]string{"MessageGroupId": group}}
+	}
+	ev := events.SQSEvent{Records: []events.SQSMessage{record("a1", "a"), record("b1", "b"), record("a2", "a"), record("b2", "b"), record("a3", "a")}}
+	sender := &awsQueueSender{client: &fakeSQS{}, urls: map[string]string{"billing": "billing-url"}}
+	resp := (&Dispatcher{}).handleSQSEvent(context.Background(), app, sender, ev, map[string]string{"app-billing-dev.fifo": "billing"})
+
+	if strings.Join(got, " | ") != "a1,b1 | b2" {
+		t.Fatalf("records after a failure in the same group should not reach the handler, got batches %v", got)
+	}
+	var failed []string
+	for _, f := range resp.BatchItemFailures {
+		failed = append(failed, f.ItemIdentifier)
+	}
+	if strings.Join(failed, ",") != "a1,a2,a3" {
+		t.Fatalf("unexpected failures: %v", failed)
+	}
+}
+
+type fakeSNS struct {
+	published []*sns.PublishInput
+}
+
+func (f *fakeSNS) Publish(ctx context.Context, in *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	f.published = append(f.published, in)
+	return &sns.PublishOutput{}, nil
+}
+
+func TestSNSTopicPublisher(t *testing.T) {
+	client := &fakeSNS{}
+	publisher := &snsTopicPublisher{client: client, arns: map[string]string{"order-created": "arn:aws:sns:eu-west-2:123:app-order-created-dev"}}
+
+	if err := publisher.Publish(context.Background(), "order-created", []byte(`{"id":1}`)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if len(client.published) != 1 || aws.ToString(client.published[0].TopicArn) != "arn:aws:sns:eu-west-2:123:app-order-created-dev" || aws.ToString(client.published[0].Message) != `{"id":1}` {
+		t.Fatalf("unexpected publish: %+v", client.published)
+	}
+	if err := publisher.Publish(context.Background(), "missing", nil); err == nil {
+		t.Fatalf("expected error for unconfigured topic")
+	}
+	if got := topicEnvVar("order-created"); got != "TRANSIRE_TOPIC_ORDER_CREATED_ARN" {
+		t.Fatalf("unexpected env var: %s", got)
+	}
+}
+
+func TestCorrelationIDPropagatesAcrossHops(t *testing.T) {
+	client := &fakeSQS{}
+	sender := &awsQueueSender{client: client, urls: map[string]string{"summary": "summary-url"}}
+	app := transire.New()
+	app.SetQueueSender(sender)
+	var seen string
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		seen = transire.CorrelationID(ctx)
+		return ctx.Queues.Send(ctx, "summary", msg.Body)
+	})
+
+	ev := events.SQSEvent{Records: []events.SQSMessage{{
+		MessageId:      "m1",
+		Body:           "hi",
+		EventSourceARN: "arn:aws:sqs:eu-west-2:123:app-work-dev",
+		MessageAttributes: map[string]events.SQSMessageAttribute{
+			transire.CorrelationIDAttribute: {DataType: "String", StringValue: aws.String("c-1")},
+		},
+	}}}
+	d := &Dispatcher{}
+	d.handleSQSEvent(context.Background(), app, sender, ev, map[string]string{"app-work-dev": "work"})
+
+	if seen != "c-1" {
+		t.Fatalf("expected restored correlation ID c-1, got %q", seen)
+	}
+	if len(client.sent) != 1 {
+		t.Fatalf("expected one forwarded message, got %d", len(client.sent))
+	}
+	if got := aws.ToString(client.sent[0].MessageAttributes[transire.CorrelationIDAttribute].StringValue); got != "c-1" {
+		t.Fatalf("expected forwarded correlation ID c-1, got %q", got)
+	}
+
+	sns := &fakeSNS{}
+	publisher := &snsTopicPublisher{client: sns, arns: map[string]string{"t": "arn"}}
+	if err := publisher.Publish(transire.ContextWithCorrelationID(context.Background(), "c-2"), "t", nil); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if got := aws.ToString(sns.published[0].MessageAttributes[transire.CorrelationIDAttribute].StringValue); got != "c-2" {
+		t.Fatalf("expected published correlation ID c-2, got %q", got)
+	}
+}
+
+func TestAPIGatewayRequestIDReachesHandlers(t *testing.T) {
+	var buf bytes.Buffer
+	app := transire.New()
+	app.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	var seen string
+	app.Router().Use(middleware.RequestID)
+	app.Router().Get("/orders", func(w http.ResponseWriter, r *http.Request) {
+		seen = middleware.GetReqID(r.Context())
+		ctx, _ := transire.RequestContext(r)
+		ctx.Logger.Info("handled")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	root := chi.NewRouter()
+	root.Use(apiGatewayRequestID, app.ContextMiddleware())
+	root.Mount("/", app.Router())
+
+	req := events.APIGatewayV2HTTPRequest{RawPath: "/orders", RequestContext: events.APIGatewayV2HTTPRequestContext{
+		RequestID: "apigw-1",
+		HTTP:      events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet, Path: "/orders"},
+	}}
+	if _, err := chiproxy.NewV2(root).ProxyWithContextV2(context.Background(), req); err != nil {
+		t.Fatalf("proxy: %v", err)
+	}
+	if seen != "apigw-1" || !strings.Contains(buf.String(), `"request_id":"apigw-1"`) {
+		t.Fatalf("expected API Gateway request ID, handler saw %q and logged %s", seen, buf.String())
+	}
+}
+
+func TestSendStampsTraceContext(t *testing.T) {
+	client := &fakeSQS{}
+	sender := &awsQueueSender{client: client, urls: map[string]string{"work": "work-url"}}
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "send")
+	defer span.End()
+
+	if err := sender.Send(ctx, "work", []byte("x")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	traceparent := aws.ToString(client.sent[0].MessageAttributes["traceparent"].StringValue)
+	if !strings.Contains(traceparent, span.SpanContext().TraceID().String()) {
+		t.Fatalf("expected traceparent for trace %s, got %q", span.SpanContext().TraceID(), traceparent)
+	}
+}
+
+func TestHandleSQSEventRecoversPanicsPerRecord(t *testing.T) {
+	app := transire.New()
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		if string(msg.Body) == "bad" {
+			panic("nil map")
+		}
+		return nil
+	})
+
+	ev := events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "m1", Body: "bad", EventSourceARN: "arn:aws:sqs:eu-west-2:123:app-work-dev"},
+		{MessageId: "m2", Body: "good", EventSourceARN: "arn:aws:sqs:eu-west-2:123:app-work-dev"},
+	}}
+	d := &Dispatcher{}
+	resp := d.handleSQSEvent(context.Background(), app, &awsQueueSender{client: &fakeSQS{}}, ev, map[string]string{"app-work-dev": "work"})
+	if len(resp.BatchItemFailures) != 1 || resp.BatchItemFailures[0].ItemIdentifier != "m1" {
+		t.Fatalf("expected only the panicking record to fail, got %+v", resp.BatchItemFailures)
+	}
+}
+
+func TestHandleScheduleRunsDeployInvocations(t *testing.T) {
+	app := transire.New()
+	var got time.Time
+	app.RegisterScheduleHandler("warm", time.Hour, func(ctx transire.Context, at time.Time) error {
+		got = at
+		return nil
+	}, transire.WithRunOnStart())
+
+	// The payload the run-on-deploy custom resource sends.
+	var ev events.CloudWatchEvent
+	if err := json.Unmarshal([]byte(`{"source":"transire.deploy","resources":["app-warm-dev"]}`), &ev); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	before := time.Now()
+	d := &Dispatcher{}
+	if err := d.handleSchedule(context.Background(), app, nil, ev, map[string]string{"app-warm-dev": "warm"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Before(before) {
+		t.Fatalf("expected the run stamped with the invocation time, got %v", got)
+	}
+}
+
+func TestHandleScheduleDeliversEventMetadata(t *testing.T) {
+	app := transire.New()
+	var got transire.ScheduleInvocation
+	app.RegisterScheduleHandler("report", time.Hour, func(ctx transire.Context, at time.Time) error {
+		got, _ = transire.ScheduleInvocationFromContext(ctx)
+		return nil
+	}, transire.WithMetadata("kind", "daily"), transire.WithMetadata("team", "billing"))
+
+	// The input the build gives a rule target, as sent by "transire trigger --data".
+	var ev events.CloudWatchEvent
+	raw := `{"source":"transire.schedule","resources":["app-report-dev"],"time":"2025-06-01T09:00:00Z","detail":{"metadata":{"kind":"backfill"}}}`
+	if err := json.Unmarshal([]byte(raw), &ev); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	d := &Dispatcher{}
+	if err := d.handleSchedule(context.Background(), app, nil, ev, map[string]string{"app-report-dev": "report"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Metadata["kind"] != "backfill" || got.Metadata["team"] != "billing" || !got.At.Equal(ev.Time) {
+		t.Fatalf("unexpected invocation %+v", got)
+	}
+}
@@ -0,0 +1,116 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	transire "github.com/transire/transire"
+)
+
+const scheduleLockTableEnv = "TRANSIRE_SCHEDULE_LOCK_TABLE"
+
+// maxLeaseTTL bounds a lease taken without a deadline to the longest Lambda invocation.
+const maxLeaseTTL = 15 * time.Minute
+
+// leasePoll is how often a ConcurrencyQueue run retries a held lease.
+const leasePoll = time.Second
+
+// errLeaseHeld is returned when a schedule's lease is held by another run.
+var errLeaseHeld = errors.New("schedule lease held by another run")
+
+type dynamoAPI interface {
+	PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(context.Context, *dynamodb.DeleteItemInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+// scheduleLeases keeps the runs of exclusive schedules from overlapping across Lambda
+// invocations. A run holds an item keyed by the schedule name until it returns or the
+// lease expires, so a crashed invocation cannot hold the schedule forever.
+type scheduleLeases struct {
+	client dynamoAPI
+	table  string
+	now    func() time.Time
+}
+
+// hold takes the lease on schedule for owner, following policy: ConcurrencySkip returns
+// errLeaseHeld at once when another run holds it, while ConcurrencyQueue waits for it
+// for up to half of the time left before ctx's deadline. The returned func releases it.
+func (l *scheduleLeases) hold(ctx context.Context, schedule, owner string, policy transire.Concurrency) (func(), error) {
+	expires := l.now().Add(maxLeaseTTL)
+	if deadline, ok := ctx.Deadline(); ok {
+		expires = deadline
+	}
+	waitUntil := l.now()
+	if policy == transire.ConcurrencyQueue {
+		waitUntil = waitUntil.Add(expires.Sub(waitUntil) / 2)
+	}
+	for {
+		err := l.acquire(ctx, schedule, owner, expires)
+		if err == nil {
+			return func() { l.release(context.WithoutCancel(ctx), schedule, owner) }, nil
+		}
+		if !errors.Is(err, errLeaseHeld) || !l.now().Add(leasePoll).Before(waitUntil) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(leasePoll):
+		}
+	}
+}
+
+// acquire writes the lease item unless an unexpired one exists.
+func (l *scheduleLeases) acquire(ctx context.Context, schedule, owner string, expires time.Time) error {
+	_, err := l.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(l.table),
+		Item: map[string]ddbtypes.AttributeValue{
+			"schedule":  &ddbtypes.AttributeValueMemberS{Value: schedule},
+			"owner":     &ddbtypes.AttributeValueMemberS{Value: owner},
+			"expiresAt": &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(expires.Unix(), 10)},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(#schedule) OR expiresAt < :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#schedule": "schedule",
+		},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":now": &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(l.now().Unix(), 10)},
+		},
+	})
+	var held *ddbtypes.ConditionalCheckFailedException
+	if errors.As(err, &held) {
+		return errLeaseHeld
+	}
+	if err != nil {
+		return fmt.Errorf("acquire schedule lease: %w", err)
+	}
+	return nil
+}
+
+// release deletes the lease item if owner still holds it. Failures are left to the
+// lease expiry.
+func (l *scheduleLeases) release(ctx context.Context, schedule, owner string) {
+	_, _ = l.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(l.table),
+		Key: map[string]ddbtypes.AttributeValue{
+			"schedule": &ddbtypes.AttributeValueMemberS{Value: schedule},
+		},
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "owner",
+		},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":owner": &ddbtypes.AttributeValueMemberS{Value: owner},
+		},
+	})
+}
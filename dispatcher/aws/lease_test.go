@@ -0,0 +1,111 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package aws
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	transire "github.com/transire/transire"
+)
+
+// fakeDynamo evaluates the lease conditions against an in-memory table.
+type fakeDynamo struct {
+	items map[string]map[string]ddbtypes.AttributeValue
+}
+
+func (f *fakeDynamo) PutItem(ctx context.Context, in *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	key := in.Item["schedule"].(*ddbtypes.AttributeValueMemberS).Value
+	if held, ok := f.items[key]; ok {
+		expires, _ := strconv.ParseInt(held["expiresAt"].(*ddbtypes.AttributeValueMemberN).Value, 10, 64)
+		now, _ := strconv.ParseInt(in.ExpressionAttributeValues[":now"].(*ddbtypes.AttributeValueMemberN).Value, 10, 64)
+		if expires >= now {
+			return nil, &ddbtypes.ConditionalCheckFailedException{}
+		}
+	}
+	f.items[key] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamo) DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	key := in.Key["schedule"].(*ddbtypes.AttributeValueMemberS).Value
+	owner := in.ExpressionAttributeValues[":owner"].(*ddbtypes.AttributeValueMemberS).Value
+	if held, ok := f.items[key]; !ok || held["owner"].(*ddbtypes.AttributeValueMemberS).Value != owner {
+		return nil, &ddbtypes.ConditionalCheckFailedException{}
+	}
+	delete(f.items, key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func TestHandleScheduleHoldsLease(t *testing.T) {
+	for _, policy := range []transire.Concurrency{transire.ConcurrencySkip, transire.ConcurrencyQueue} {
+		t.Run(string(policy), func(t *testing.T) {
+			app := transire.New()
+			runs := 0
+			app.RegisterScheduleHandler("reconcile", time.Minute, func(ctx transire.Context, at time.Time) error {
+				runs++
+				return nil
+			}, transire.WithConcurrency(policy))
+
+			now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			db := &fakeDynamo{items: map[string]map[string]ddbtypes.AttributeValue{}}
+			leases := &scheduleLeases{client: db, table: "locks", now: func() time.Time { return now }}
+			ev := events.CloudWatchEvent{ID: "ev-1", Resources: []string{"arn:aws:events:eu-west-2:123:rule/app-reconcile-dev"}}
+			names := map[string]string{"app-reconcile-dev": "reconcile"}
+			d := &Dispatcher{}
+
+			if err := d.handleSchedule(context.Background(), app, leases, ev, names); err != nil || runs != 1 {
+				t.Fatalf("expected the run to take the lease, err=%v runs=%d", err, runs)
+			}
+			if len(db.items) != 0 {
+				t.Fatalf("expected the lease released after the run")
+			}
+
+			release, err := leases.hold(context.Background(), "reconcile", "other", policy)
+			if err != nil {
+				t.Fatalf("hold: %v", err)
+			}
+			ctx, cancel := context.WithDeadline(context.Background(), now.Add(time.Second))
+			defer cancel()
+			err = d.handleSchedule(ctx, app, leases, ev, names)
+			if runs != 1 {
+				t.Fatalf("expected no run while the lease is held, got %d", runs)
+			}
+			if policy == transire.ConcurrencySkip && err != nil {
+				t.Fatalf("expected a skipped run to succeed, got %v", err)
+			}
+			if policy == transire.ConcurrencyQueue && err == nil {
+				t.Fatalf("expected a queued run that cannot get the lease to fail")
+			}
+
+			release()
+			now = now.Add(maxLeaseTTL + time.Second)
+			if err := d.handleSchedule(context.Background(), app, leases, ev, names); err != nil || runs != 2 {
+				t.Fatalf("expected a run once the lease is free, err=%v runs=%d", err, runs)
+			}
+		})
+	}
+}
+
+func TestExpiredLeaseIsTakenOver(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := &fakeDynamo{items: map[string]map[string]ddbtypes.AttributeValue{}}
+	leases := &scheduleLeases{client: db, table: "locks", now: func() time.Time { return now }}
+	if _, err := leases.hold(context.Background(), "reconcile", "crashed", transire.ConcurrencySkip); err != nil {
+		t.Fatalf("hold: %v", err)
+	}
+	if _, err := leases.hold(context.Background(), "reconcile", "next", transire.ConcurrencySkip); err != errLeaseHeld {
+		t.Fatalf("expected the lease held, got %v", err)
+	}
+	now = now.Add(maxLeaseTTL + time.Second)
+	if _, err := leases.hold(context.Background(), "reconcile", "next", transire.ConcurrencySkip); err != nil {
+		t.Fatalf("expected an expired lease taken over, got %v", err)
+	}
+}
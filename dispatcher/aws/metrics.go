@@ -0,0 +1,91 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package aws
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// metricsNamespace is the CloudWatch namespace of the metrics Transire emits.
+const metricsNamespace = "Transire"
+
+// emfMetrics writes each handler invocation and queue send as a CloudWatch Embedded
+// Metric Format record. Lambda ships stdout to CloudWatch Logs, which extracts the
+// metrics without any API calls. In-flight counts are not emitted: each Lambda
+// execution environment runs one invocation at a time.
+type emfMetrics struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+func (m *emfMetrics) HandlerStarted(kind, name string) {}
+
+func (m *emfMetrics) HandlerFinished(kind, name string, duration time.Duration, err error) {
+	errors := 0
+	if err != nil {
+		errors = 1
+	}
+	m.write([]string{"Kind", "Name"}, []emfMetric{
+		{Name: "Invocations", Unit: "Count"},
+		{Name: "Errors", Unit: "Count"},
+		{Name: "Duration", Unit: "Milliseconds"},
+	}, map[string]any{
+		"Kind":        kind,
+		"Name":        name,
+		"Invocations": 1,
+		"Errors":      errors,
+		"Duration":    float64(duration) / float64(time.Millisecond),
+	})
+}
+
+func (m *emfMetrics) MessageSent(queue string) {
+	m.write([]string{"Queue"}, []emfMetric{{Name: "MessagesSent", Unit: "Count"}}, map[string]any{
+		"Queue":        queue,
+		"MessagesSent": 1,
+	})
+}
+
+// write emits one EMF record with the given dimension keys, metric definitions, and values.
+func (m *emfMetrics) write(dimensions []string, metrics []emfMetric, values map[string]any) {
+	now := time.Now
+	if m.now != nil {
+		now = m.now
+	}
+	values["_aws"] = emfMetadata{
+		Timestamp: now().UnixMilli(),
+		CloudWatchMetrics: []emfDirective{{
+			Namespace:  metricsNamespace,
+			Dimensions: [][]string{dimensions},
+			Metrics:    metrics,
+		}},
+	}
+	line, err := json.Marshal(values)
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, _ = m.w.Write(append(line, '\n'))
+}
@@ -0,0 +1,48 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEMFMetricsRecordsInvocations(t *testing.T) {
+	var buf bytes.Buffer
+	m := &emfMetrics{w: &buf, now: func() time.Time { return time.UnixMilli(1700000000000) }}
+	m.HandlerFinished("queue", "work", 1500*time.Microsecond, errors.New("boom"))
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if record["Kind"] != "queue" || record["Name"] != "work" || record["Errors"] != float64(1) || record["Duration"] != 1.5 {
+		t.Fatalf("unexpected record: %v", record)
+	}
+	meta := record["_aws"].(map[string]any)
+	if meta["Timestamp"] != float64(1700000000000) {
+		t.Fatalf("unexpected timestamp: %v", meta["Timestamp"])
+	}
+	directive := meta["CloudWatchMetrics"].([]any)[0].(map[string]any)
+	if directive["Namespace"] != metricsNamespace || len(directive["Metrics"].([]any)) != 3 {
+		t.Fatalf("unexpected directive: %v", directive)
+	}
+}
+
+func TestQueueSenderCountsSentMessages(t *testing.T) {
+	var buf bytes.Buffer
+	sender := &awsQueueSender{client: &fakeSQS{}, urls: map[string]string{"work": "url"}, metrics: &emfMetrics{w: &buf}}
+	if err := sender.Send(context.Background(), "work", []byte("x")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"MessagesSent":1`) || !strings.Contains(buf.String(), `"Queue":"work"`) {
+		t.Fatalf("expected a send record, got %q", buf.String())
+	}
+}
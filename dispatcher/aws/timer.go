@@ -0,0 +1,287 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/smithy-go"
+	transire "github.com/transire/transire"
+)
+
+const timerGroupEnv = "TRANSIRE_TIMER_GROUP"
+const timerRoleEnv = "TRANSIRE_TIMER_ROLE_ARN"
+const queueARNEnvSuffix = "_ARN"
+
+// maxTimerPayload is the longest EventBridge Scheduler target input.
+const maxTimerPayload = 8192
+
+// errScheduleNotFound is returned by schedulerAPI for a schedule that does not exist.
+var errScheduleNotFound = errors.New("schedule not found")
+
+type schedulerAPI interface {
+	CreateSchedule(ctx context.Context, in createScheduleInput) error
+	DeleteSchedule(ctx context.Context, group, name string) error
+}
+
+// createScheduleInput is the body of an EventBridge Scheduler CreateSchedule request;
+// Name goes in the path.
+type createScheduleInput struct {
+	Name                       string             `json:"-"`
+	GroupName                  string             `json:"GroupName"`
+	ScheduleExpression         string             `json:"ScheduleExpression"`
+	ScheduleExpressionTimezone string             `json:"ScheduleExpressionTimezone"`
+	FlexibleTimeWindow         flexibleTimeWindow `json:"FlexibleTimeWindow"`
+	ActionAfterCompletion      string             `json:"ActionAfterCompletion"`
+	Target                     scheduleTarget     `json:"Target"`
+}
+
+type flexibleTimeWindow struct {
+	Mode string `json:"Mode"`
+}
+
+type scheduleTarget struct {
+	Arn     string `json:"Arn"`
+	RoleArn string `json:"RoleArn"`
+	Input   string `json:"Input"`
+}
+
+// awsTimers are one-time EventBridge Scheduler schedules in the app's schedule group,
+// named by the timer ID, that send their payload to the queue and delete themselves.
+type awsTimers struct {
+	client schedulerAPI
+	group  string
+	// roleARN is the role the scheduler assumes to send to the queues.
+	roleARN string
+	// queues maps queue names to ARNs.
+	queues map[string]string
+	// sender sends timers that are already due.
+	sender transire.QueueSender
+	now    func() time.Time
+}
+
+// ScheduleAt creates the timer's schedule. A timer that is already due is sent at once
+// and its ID, which has no schedule, cannot be cancelled.
+func (t *awsTimers) ScheduleAt(ctx context.Context, queue string, at time.Time, payload []byte) (string, error) {
+	arn := t.queues[queue]
+	if arn == "" {
+		return "", fmt.Errorf("transire: queue %s has no ARN for timers (%s)", queue, queueARNEnvVar(queue))
+	}
+	if strings.HasSuffix(arn, transire.FIFOSuffix) {
+		return "", fmt.Errorf("transire: timers cannot target FIFO queue %s", queue)
+	}
+	// The scheduler delivers the input as the message body, which SQS requires to be
+	// non-empty text.
+	if len(payload) == 0 || len(payload) > maxTimerPayload || !utf8.Valid(payload) {
+		return "", fmt.Errorf("transire: timer payload for queue %s must be 1 to %d bytes of UTF-8 text", queue, maxTimerPayload)
+	}
+	id := newTimerID()
+	if !at.After(t.now()) {
+		return id, t.sender.Send(ctx, queue, payload)
+	}
+	// at() takes whole seconds, so round up rather than fire early.
+	at = at.UTC().Add(time.Second - 1).Truncate(time.Second)
+	err := t.client.CreateSchedule(ctx, createScheduleInput{
+		Name:                       id,
+		GroupName:                  t.group,
+		ScheduleExpression:         "at(" + at.Format("2006-01-02T15:04:05") + ")",
+		ScheduleExpressionTimezone: "UTC",
+		FlexibleTimeWindow:         flexibleTimeWindow{Mode: "OFF"},
+		ActionAfterCompletion:      "DELETE",
+		Target:                     scheduleTarget{Arn: arn, RoleArn: t.roleARN, Input: string(payload)},
+	})
+	if err != nil {
+		return "", fmt.Errorf("schedule timer for queue %s: %w", queue, err)
+	}
+	return id, nil
+}
+
+// Cancel deletes the timer's schedule.
+func (t *awsTimers) Cancel(ctx context.Context, id string) error {
+	err := t.client.DeleteSchedule(ctx, t.group, id)
+	if errors.Is(err, errScheduleNotFound) {
+		return transire.ErrTimerNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("cancel timer %s: %w", id, err)
+	}
+	return nil
+}
+
+// Now returns the wall clock.
+func (t *awsTimers) Now() time.Time {
+	return t.now()
+}
+
+// schedulerClient makes the two EventBridge Scheduler calls timers need. The SDK's
+// scheduler client is not among this module's dependencies, so requests are signed with
+// SigV4 directly and retried with the SDK's standard retryer, which backs off on
+// throttling and transient errors.
+type schedulerClient struct {
+	http        aws.HTTPClient
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	retryer     aws.Retryer
+	region      string
+	endpoint    string
+}
+
+func newSchedulerClient(cfg aws.Config) *schedulerClient {
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	var retryer aws.Retryer
+	if cfg.Retryer != nil {
+		retryer = cfg.Retryer()
+	} else {
+		retryer = retry.NewStandard(func(o *retry.StandardOptions) {
+			if cfg.RetryMaxAttempts > 0 {
+				o.MaxAttempts = cfg.RetryMaxAttempts
+			}
+		})
+	}
+	endpoint := "https://scheduler." + cfg.Region + ".amazonaws.com"
+	if strings.HasPrefix(cfg.Region, "cn-") {
+		endpoint += ".cn"
+	}
+	if cfg.BaseEndpoint != nil {
+		endpoint = strings.TrimSuffix(*cfg.BaseEndpoint, "/")
+	}
+	return &schedulerClient{
+		http:        client,
+		credentials: cfg.Credentials,
+		signer:      v4.NewSigner(),
+		retryer:     retryer,
+		region:      cfg.Region,
+		endpoint:    endpoint,
+	}
+}
+
+func (c *schedulerClient) CreateSchedule(ctx context.Context, in createScheduleInput) error {
+	return c.do(ctx, http.MethodPost, "/schedules/"+url.PathEscape(in.Name), nil, in)
+}
+
+func (c *schedulerClient) DeleteSchedule(ctx context.Context, group, name string) error {
+	return c.do(ctx, http.MethodDelete, "/schedules/"+url.PathEscape(name), url.Values{"groupName": {group}}, nil)
+}
+
+// schedulerError is an error response from EventBridge Scheduler. The retryer classifies
+// it by code and status, and a ResourceNotFoundException matches errScheduleNotFound.
+type schedulerError struct {
+	smithy.GenericAPIError
+	status int
+}
+
+func (e *schedulerError) HTTPStatusCode() int {
+	return e.status
+}
+
+func (e *schedulerError) Is(target error) bool {
+	return target == errScheduleNotFound && e.Code == "ResourceNotFoundException"
+}
+
+// do sends a request, retrying it while the retryer allows.
+func (c *schedulerClient) do(ctx context.Context, method, path string, query url.Values, body any) error {
+	var raw []byte
+	if body != nil {
+		var err error
+		if raw, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		err := c.send(ctx, method, path, query, raw)
+		if err == nil || attempt >= c.retryer.MaxAttempts() || !c.retryer.IsErrorRetryable(err) {
+			return err
+		}
+		delay, delayErr := c.retryer.RetryDelay(attempt, err)
+		if delayErr != nil {
+			return err
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// send makes one signed request, returning a *schedulerError for an error response.
+func (c *schedulerClient) send(ctx context.Context, method, path string, query url.Values, raw []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	req.URL.RawQuery = query.Encode()
+	req.Header.Set("Content-Type", "application/json")
+	if c.credentials == nil {
+		return errors.New("scheduler: no AWS credentials")
+	}
+	creds, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("scheduler: retrieve credentials: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	if err := c.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(sum[:]), "scheduler", c.region, time.Now()); err != nil {
+		return fmt.Errorf("scheduler: sign request: %w", err)
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("scheduler: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+	var apiErr struct {
+		Type    string `json:"__type"`
+		Message string `json:"Message"`
+	}
+	_ = json.NewDecoder(res.Body).Decode(&apiErr)
+	// The code is in the X-Amzn-Errortype header, else the body's __type, either of which
+	// may carry a ":" or "#" qualified suffix or prefix.
+	code, _, _ := strings.Cut(res.Header.Get("X-Amzn-Errortype"), ":")
+	if code == "" {
+		code = apiErr.Type[strings.LastIndex(apiErr.Type, "#")+1:]
+	}
+	if code == "" {
+		code = http.StatusText(res.StatusCode)
+	}
+	return &schedulerError{
+		GenericAPIError: smithy.GenericAPIError{Code: code, Message: apiErr.Message},
+		status:          res.StatusCode,
+	}
+}
+
+func queueARNEnvVar(queue string) string {
+	name := strings.ToUpper(queue)
+	name = strings.ReplaceAll(name, "-", "_")
+	return queueEnvPrefix + name + queueARNEnvSuffix
+}
+
+// newTimerID returns a random timer ID, which is also a valid schedule name.
+func newTimerID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,204 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/smithy-go"
+	transire "github.com/transire/transire"
+)
+
+// fakeScheduler keeps created schedules by name.
+type fakeScheduler struct {
+	schedules map[string]createScheduleInput
+}
+
+func (f *fakeScheduler) CreateSchedule(ctx context.Context, in createScheduleInput) error {
+	f.schedules[in.Name] = in
+	return nil
+}
+
+func (f *fakeScheduler) DeleteSchedule(ctx context.Context, group, name string) error {
+	if in, ok := f.schedules[name]; !ok || in.GroupName != group {
+		return errScheduleNotFound
+	}
+	delete(f.schedules, name)
+	return nil
+}
+
+func TestTimersCreateOneTimeSchedules(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	scheduler := &fakeScheduler{schedules: map[string]createScheduleInput{}}
+	client := &fakeSQS{}
+	timers := &awsTimers{
+		client:  scheduler,
+		group:   "app-timers-dev",
+		roleARN: "arn:aws:iam::123:role/timers",
+		queues: map[string]string{
+			"reminders": "arn:aws:sqs:eu-west-2:123:app-reminders-dev",
+			"ordered":   "arn:aws:sqs:eu-west-2:123:app-ordered-dev.fifo",
+		},
+		sender: &awsQueueSender{client: client, urls: map[string]string{"reminders": "url"}},
+		now:    func() time.Time { return now },
+	}
+	app := transire.New()
+	app.SetTimers(timers)
+	ctx := app.NewContext(context.Background())
+
+	id, err := ctx.ScheduleAfter("reminders", 36*time.Hour+500*time.Millisecond, []byte(`{"user":"u-1"}`))
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	in := scheduler.schedules[id]
+	if in.ScheduleExpression != "at(2025-06-03T00:00:01)" || in.ScheduleExpressionTimezone != "UTC" || in.ActionAfterCompletion != "DELETE" {
+		t.Fatalf("unexpected schedule %+v", in)
+	}
+	if in.GroupName != "app-timers-dev" || in.Target.Arn != timers.queues["reminders"] || in.Target.RoleArn != timers.roleARN || in.Target.Input != `{"user":"u-1"}` {
+		t.Fatalf("unexpected target %+v", in)
+	}
+	if err := ctx.CancelScheduled(id); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if err := ctx.CancelScheduled(id); !errors.Is(err, transire.ErrTimerNotFound) {
+		t.Fatalf("expected a deleted schedule not found, got %v", err)
+	}
+
+	if _, err := ctx.ScheduleAt("reminders", now.Add(-time.Minute), []byte("due")); err != nil || len(client.sent) != 1 || len(scheduler.schedules) != 0 {
+		t.Fatalf("expected a due timer sent at once, err=%v sent=%d", err, len(client.sent))
+	}
+	for name, queue := range map[string]string{"fifo": "ordered", "unknown": "missing"} {
+		if _, err := ctx.ScheduleAfter(queue, time.Hour, []byte("x")); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := ctx.ScheduleAfter("reminders", time.Hour, []byte{0xff}); err == nil {
+		t.Fatalf("expected a binary payload rejected")
+	}
+}
+
+func TestSchedulerClientSignsRequests(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.RequestURI())
+		if auth := r.Header.Get("Authorization"); !strings.Contains(auth, "/eu-west-2/scheduler/aws4_request") {
+			t.Errorf("unexpected authorization %q", auth)
+		}
+		if r.Method == http.MethodDelete {
+			w.Header().Set("X-Amzn-Errortype", "ResourceNotFoundException:")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"Message":"Schedule t-1 does not exist."}`))
+			return
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["ScheduleExpression"] != "at(2025-06-03T00:00:00)" || body["Name"] != nil {
+			t.Errorf("unexpected body %v: %v", body, err)
+		}
+		_, _ = w.Write([]byte(`{"ScheduleArn":"arn"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := &schedulerClient{
+		http: server.Client(),
+		credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+		}),
+		signer:   v4.NewSigner(),
+		retryer:  retry.NewStandard(),
+		region:   "eu-west-2",
+		endpoint: server.URL,
+	}
+	err := client.CreateSchedule(context.Background(), createScheduleInput{Name: "t-1", GroupName: "g", ScheduleExpression: "at(2025-06-03T00:00:00)"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := client.DeleteSchedule(context.Background(), "g", "t-1"); !errors.Is(err, errScheduleNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if strings.Join(calls, ",") != "POST /schedules/t-1,DELETE /schedules/t-1?groupName=g" {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+}
+
+func TestSchedulerClientRetriesThrottling(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.Header().Set("X-Amzn-Errortype", "ThrottlingException:")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"__type":"com.amazonaws.scheduler#InternalServerException"}`))
+		default:
+			_, _ = w.Write([]byte(`{"ScheduleArn":"arn"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := newSchedulerClient(aws.Config{
+		Region:       "eu-west-2",
+		HTTPClient:   server.Client(),
+		BaseEndpoint: aws.String(server.URL),
+		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+		}),
+		Retryer: func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+			})
+		},
+	})
+	if err := client.CreateSchedule(context.Background(), createScheduleInput{Name: "t-1"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestSchedulerClientClassifiesErrorsByCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"__type":"UnknownOperationException","Message":"no such route"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := newSchedulerClient(aws.Config{
+		Region:       "eu-west-2",
+		HTTPClient:   server.Client(),
+		BaseEndpoint: aws.String(server.URL),
+		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+		}),
+	})
+	err := client.DeleteSchedule(context.Background(), "g", "t-1")
+	var apiErr smithy.APIError
+	if errors.Is(err, errScheduleNotFound) || !errors.As(err, &apiErr) || apiErr.ErrorCode() != "UnknownOperationException" {
+		t.Fatalf("expected an UnknownOperationException, got %v", err)
+	}
+}
+
+func TestSchedulerClientResolvesEndpoints(t *testing.T) {
+	for region, want := range map[string]string{
+		"eu-west-2":      "https://scheduler.eu-west-2.amazonaws.com",
+		"cn-northwest-1": "https://scheduler.cn-northwest-1.amazonaws.com.cn",
+	} {
+		if got := newSchedulerClient(aws.Config{Region: region}).endpoint; got != want {
+			t.Fatalf("%s: expected %s, got %s", region, want, got)
+		}
+	}
+}
@@ -6,20 +6,48 @@ package local
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	transire "github.com/transire/transire"
 )
 
+// DefaultDrainTimeout bounds how long Run takes to shut down: waiting for in-flight work,
+// then running the OnShutdown hooks.
+const DefaultDrainTimeout = 30 * time.Second
+
+// shutdownHookShare reserves 1/shutdownHookShare of the drain timeout for the OnShutdown
+// hooks and trace export.
+const shutdownHookShare = 4
+
+// DefaultHandlerTimeout bounds each queue handler invocation, matching the default
+// Lambda timeout of deployed apps.
+const DefaultHandlerTimeout = 30 * time.Second
+
 // Dispatcher provides a lightweight dispatcher for local development and testing.
 type Dispatcher struct {
 	HTTPAddr string
+	// DrainTimeout bounds the whole shutdown, including the OnShutdown hooks;
+	// TRANSIRE_DRAIN_TIMEOUT or DefaultDrainTimeout apply when zero.
+	DrainTimeout time.Duration
+	// HandlerTimeout bounds each queue handler invocation; TRANSIRE_HANDLER_TIMEOUT or
+	// DefaultHandlerTimeout apply when zero.
+	HandlerTimeout time.Duration
+	// Clock drives the schedules; TRANSIRE_FAKE_CLOCK selects a FakeClock when nil
+	// (see resolveClock), and the wall clock applies otherwise.
+	Clock Clock
+	// TimersPath is the file pending timers are kept in; TRANSIRE_TIMERS_FILE or
+	// DefaultTimersPath apply when empty.
+	TimersPath string
 }
 
 // Name identifies the dispatcher.
@@ -27,33 +55,155 @@ func (d *Dispatcher) Name() string {
 	return "local"
 }
 
-// Run starts the HTTP server and wires in local queue handling.
+// Run starts the HTTP server and wires in local queue handling. It runs the app's
+// OnStart hooks first and, when ctx is done or the process receives SIGINT or SIGTERM,
+// shuts down gracefully within the drain timeout: the HTTP server and schedules stop,
+// in-flight handlers drain, and the OnShutdown hooks run.
 func (d *Dispatcher) Run(ctx context.Context, app *transire.App) error {
 	addr := resolveAddr(d.HTTPAddr)
 
 	ensureQueueSender(app)
-	root := buildHandler(app)
+	if sender, ok := app.QueueSender().(*queueSender); ok && d.HandlerTimeout > 0 {
+		sender.timeout = d.HandlerTimeout
+	}
+	clock, err := d.resolveClock()
+	if err != nil {
+		return err
+	}
+	schedules := newScheduler(app, clock)
+	if timers, ok := app.Timers().(*timerStore); ok {
+		if err := timers.open(d.timersPath(), schedules); err != nil {
+			return err
+		}
+		schedules.timers = timers
+	}
+	root := buildHandler(app, schedules)
+
+	if err := app.Start(ctx); err != nil {
+		return err
+	}
 
-	startSchedules(ctx, app)
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	schedules.start(ctx)
 
 	server := &http.Server{
 		Addr:    addr,
 		Handler: root,
 	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	app.Logger().Info("transire local dispatcher listening", "addr", addr)
+
+	select {
+	case <-ctx.Done():
+	case err = <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+	}
+	stop()
+	return errors.Join(err, d.shutdown(app, server, schedules))
+}
+
+// shutdown drains in-flight work in order: HTTP requests, schedule runs, then queue
+// messages, which may still be sent by the handlers being drained.
+func (d *Dispatcher) shutdown(app *transire.App, server *http.Server, schedules *scheduler) error {
+	timeout := d.drainTimeout()
+	app.Logger().Info("transire local dispatcher draining", "timeout", timeout)
+	// The whole shutdown shares one deadline. The drain stops short of it so a slow
+	// drain leaves the hooks and trace export their share.
+	hookCtx, cancelHooks := context.WithTimeout(context.Background(), timeout)
+	defer cancelHooks()
+	ctx, cancel := context.WithTimeout(hookCtx, timeout-timeout/shutdownHookShare)
+	defer cancel()
+
+	var errs []error
+	if err := server.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("shut down http server: %w", err))
+	}
 
+	done := make(chan struct{})
 	go func() {
-		<-ctx.Done()
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		_ = server.Shutdown(shutdownCtx)
+		schedules.Wait()
+		close(done)
 	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("schedule runs still in flight: %w", ctx.Err()))
+	}
 
-	log.Printf("transire local dispatcher listening on %s\n", addr)
+	if drainer, ok := app.QueueSender().(interface{ Drain(context.Context) error }); ok {
+		if err := drainer.Drain(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := app.Shutdown(hookCtx); err != nil {
+		errs = append(errs, err)
+	}
+	_ = app.ShutdownTraces(hookCtx)
+	return errors.Join(errs...)
+}
 
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		return err
+func (d *Dispatcher) drainTimeout() time.Duration {
+	if d.DrainTimeout > 0 {
+		return d.DrainTimeout
 	}
-	return nil
+	if env := os.Getenv("TRANSIRE_DRAIN_TIMEOUT"); env != "" {
+		if timeout, err := time.ParseDuration(env); err == nil && timeout > 0 {
+			return timeout
+		}
+	}
+	return DefaultDrainTimeout
+}
+
+// timersPath resolves the file pending timers are kept in.
+func (d *Dispatcher) timersPath() string {
+	if d.TimersPath != "" {
+		return d.TimersPath
+	}
+	if env := os.Getenv("TRANSIRE_TIMERS_FILE"); env != "" {
+		return env
+	}
+	return DefaultTimersPath
+}
+
+// resolveClock returns the configured clock. TRANSIRE_FAKE_CLOCK selects a FakeClock
+// starting at its RFC 3339 value, or at the current time when set to "now".
+func (d *Dispatcher) resolveClock() (Clock, error) {
+	if d.Clock != nil {
+		return d.Clock, nil
+	}
+	env := os.Getenv("TRANSIRE_FAKE_CLOCK")
+	if env == "" {
+		return realClock{}, nil
+	}
+	if env == "now" {
+		return NewFakeClock(time.Now()), nil
+	}
+	start, err := time.Parse(time.RFC3339, env)
+	if err != nil {
+		return nil, fmt.Errorf("TRANSIRE_FAKE_CLOCK: %w", err)
+	}
+	return NewFakeClock(start), nil
+}
+
+// handlerTimeout resolves the queue handler timeout when none is configured.
+func handlerTimeout(configured time.Duration) time.Duration {
+	if configured > 0 {
+		return configured
+	}
+	if env := os.Getenv("TRANSIRE_HANDLER_TIMEOUT"); env != "" {
+		if timeout, err := time.ParseDuration(env); err == nil && timeout > 0 {
+			return timeout
+		}
+	}
+	return DefaultHandlerTimeout
 }
 
 func resolveAddr(addr string) string {
@@ -72,17 +222,34 @@ func resolveAddr(addr string) string {
 	return ":8080"
 }
 
+// ensureQueueSender installs the in-process queue sender, topic publisher, timers, metrics
+// registry, and a human-readable logger unless the app already has them.
 func ensureQueueSender(app *transire.App) {
+	if app.Logger() == nil {
+		app.SetLogger(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	}
+	if app.Metrics() == nil {
+		app.SetMetrics(newPromMetrics())
+	}
 	if app.QueueSender() == nil {
 		app.SetQueueSender(&queueSender{app: app})
 	}
+	if app.TopicPublisher() == nil {
+		app.SetTopicPublisher(&topicPublisher{app: app})
+	}
+	if app.Timers() == nil {
+		app.SetTimers(newTimerStore(app))
+	}
 }
 
-func buildHandler(app *transire.App) http.Handler {
+// buildHandler serves the app's routes and the /_transire admin endpoints. The clock
+// endpoints are only served when schedules is set, and the timer endpoints when it has
+// timers.
+func buildHandler(app *transire.App, schedules *scheduler) http.Handler {
 	ensureQueueSender(app)
 
 	root := chi.NewRouter()
-	root.Use(transire.InjectContext(app.QueueSender()))
+	root.Use(app.ContextMiddleware())
 
 	root.Route("/_transire", func(r chi.Router) {
 		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -90,6 +257,16 @@ func buildHandler(app *transire.App) http.Handler {
 			_, _ = w.Write([]byte("ok"))
 		})
 
+		r.Get("/metrics", func(w http.ResponseWriter, r *http.Request) {
+			metrics, ok := app.Metrics().(interface{ WritePrometheus(io.Writer) error })
+			if !ok {
+				http.Error(w, "the configured metrics do not support Prometheus output", http.StatusNotImplemented)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+			_ = metrics.WritePrometheus(w)
+		})
+
 		r.Post("/queues/{name}", func(w http.ResponseWriter, r *http.Request) {
 			queue := chi.URLParam(r, "name")
 			body, err := io.ReadAll(r.Body)
@@ -97,7 +274,44 @@ func buildHandler(app *transire.App) http.Handler {
 				http.Error(w, "failed to read body", http.StatusBadRequest)
 				return
 			}
-			if err := app.QueueSender().Send(r.Context(), queue, body); err != nil {
+			opts := transire.SendOptions{
+				GroupID:         r.URL.Query().Get("group"),
+				DeduplicationID: r.URL.Query().Get("dedup"),
+			}
+			if err := transire.SendWithOptions(r.Context(), app.QueueSender(), queue, body, opts); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		r.Get("/queues/{name}/dead-letters", func(w http.ResponseWriter, r *http.Request) {
+			queue := chi.URLParam(r, "name")
+			if _, ok := app.QueueHandlers()[queue]; !ok {
+				http.NotFound(w, r)
+				return
+			}
+			sender, ok := app.QueueSender().(*queueSender)
+			if !ok {
+				http.Error(w, "dead letters are only tracked by the local queue sender", http.StatusNotImplemented)
+				return
+			}
+			letters := sender.DeadLetters(queue)
+			if letters == nil {
+				letters = []DeadLetter{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(letters)
+		})
+
+		r.Post("/topics/{name}", func(w http.ResponseWriter, r *http.Request) {
+			topic := chi.URLParam(r, "name")
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "failed to read body", http.StatusBadRequest)
+				return
+			}
+			if err := app.TopicPublisher().Publish(r.Context(), topic, body); err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
@@ -111,72 +325,83 @@ func buildHandler(app *transire.App) http.Handler {
 				http.NotFound(w, r)
 				return
 			}
-			if sched.Handler == nil {
+			handler, ok := app.ScheduleHandler(sched.Name)
+			if !ok {
 				http.Error(w, "schedule handler missing", http.StatusBadRequest)
 				return
 			}
-			if err := sched.Handler(transire.Context{
-				Context: r.Context(),
-				Queues:  app.QueueSender(),
-			}, time.Now()); err != nil {
+			// An optional JSON object body is the run's data, overlaying the schedule's
+			// metadata.
+			var data map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&data); err != nil && !errors.Is(err, io.EOF) {
+				http.Error(w, "invalid schedule data: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			ctx := transire.ContextWithScheduleData(r.Context(), data)
+			var err error
+			if schedules != nil {
+				// Triggers honour the schedule's concurrency policy, like its ticks.
+				var found bool
+				found, err = schedules.Trigger(ctx, sched.Name, schedules.now())
+				if !found {
+					err = handler(app.ScheduleContext(ctx, sched.Name), schedules.now())
+				}
+			} else {
+				err = handler(app.ScheduleContext(ctx, sched.Name), time.Now())
+			}
+			if errors.Is(err, errRunInProgress) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
 			w.WriteHeader(http.StatusAccepted)
 		})
-	})
 
-	root.Mount("/", app.Router())
-	return root
-}
+		if schedules == nil {
+			return
+		}
 
-type queueSender struct {
-	app *transire.App
-}
+		if schedules.timers != nil {
+			r.Get("/timers", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(schedules.timers.List())
+			})
 
-func (q *queueSender) Send(ctx context.Context, queue string, payload []byte) error {
-	handler, ok := q.app.QueueHandlers()[queue]
-	if !ok {
-		return fmt.Errorf("queue %q not registered", queue)
-	}
+			r.Delete("/timers/{id}", func(w http.ResponseWriter, r *http.Request) {
+				err := schedules.timers.Cancel(r.Context(), chi.URLParam(r, "id"))
+				if errors.Is(err, transire.ErrTimerNotFound) {
+					http.NotFound(w, r)
+					return
+				}
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+		}
 
-	go func() {
-		handler(transire.Context{
-			Context: ctx,
-			Queues:  q,
-		}, transire.Message{
-			ID:         fmt.Sprintf("local-%d", time.Now().UnixNano()),
-			Queue:      queue,
-			Body:       payload,
-			Attributes: map[string]string{},
+		r.Get("/clock", func(w http.ResponseWriter, r *http.Request) {
+			_, fake := schedules.clock.(*FakeClock)
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{"now": schedules.now(), "fake": fake})
 		})
-	}()
-
-	return nil
-}
 
-func startSchedules(ctx context.Context, app *transire.App) {
-	for name, sched := range app.Schedules() {
-		interval := sched.Every
-		if interval <= 0 {
-			log.Printf("schedule %s has non-positive interval; skipping\n", name)
-			continue
-		}
-		s := sched
-		go func() {
-			ticker := time.NewTicker(interval)
-			defer ticker.Stop()
-			for {
-				select {
-				case t := <-ticker.C:
-					_ = s.Handler(transire.Context{
-						Context: ctx,
-						Queues:  app.QueueSender(),
-					}, t)
-				case <-ctx.Done():
-					return
-				}
+		r.Post("/clock/advance", func(w http.ResponseWriter, r *http.Request) {
+			by, err := time.ParseDuration(r.URL.Query().Get("by"))
+			if err != nil || by <= 0 {
+				http.Error(w, "by must be a positive duration such as 1h or 24h", http.StatusBadRequest)
+				return
 			}
-		}()
-	}
+			runs := schedules.Advance(r.Context(), by)
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{"now": schedules.now(), "runs": runs})
+		})
+	})
+
+	root.Mount("/", app.Router())
+	return root
 }
@@ -1,14 +1,23 @@
 package local
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/transire/transire"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestAdminQueueEndpointDispatches(t *testing.T) {
@@ -20,7 +29,7 @@ func TestAdminQueueEndpointDispatches(t *testing.T) {
 		return nil
 	})
 
-	server := httptest.NewServer(buildHandler(app))
+	server := httptest.NewServer(buildHandler(app, nil))
 	t.Cleanup(server.Close)
 
 	res, err := http.Post(server.URL+"/_transire/queues/demo-queue", "application/octet-stream", strings.NewReader("payload"))
@@ -50,7 +59,7 @@ func TestAdminScheduleEndpointInvokesHandler(t *testing.T) {
 		return nil
 	})
 
-	server := httptest.NewServer(buildHandler(app))
+	server := httptest.NewServer(buildHandler(app, nil))
 	t.Cleanup(server.Close)
 
 	res, err := http.Post(server.URL+"/_transire/schedules/tick", "application/json", nil)
@@ -72,3 +81,1316 @@ func TestAdminScheduleEndpointInvokesHandler(t *testing.T) {
 		t.Fatalf("schedule handler not invoked")
 	}
 }
+
+func TestQueueSenderHonoursSendOptions(t *testing.T) {
+	app := transire.New()
+	received := make(chan transire.Message, 1)
+	app.RegisterQueueHandler("delayed", func(ctx transire.Context, msg transire.Message) error {
+		received <- msg
+		return nil
+	})
+	ensureQueueSender(app)
+
+	start := time.Now()
+	err := transire.SendWithOptions(context.Background(), app.QueueSender(), "delayed", []byte("later"), transire.SendOptions{
+		Delay:      50 * time.Millisecond,
+		Attributes: map[string]string{"tenant": "acme"},
+	})
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		if time.Since(start) < 50*time.Millisecond {
+			t.Fatalf("message delivered before delay elapsed")
+		}
+		if msg.Attributes["tenant"] != "acme" {
+			t.Fatalf("attributes not propagated: %#v", msg.Attributes)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("delayed message not delivered")
+	}
+}
+
+func TestQueueSenderSendBatchIsAllOrNothing(t *testing.T) {
+	app := transire.New()
+	received := make(chan transire.Message, 3)
+	app.RegisterQueueHandler("fanout", func(ctx transire.Context, msg transire.Message) error {
+		received <- msg
+		return nil
+	})
+	ensureQueueSender(app)
+
+	bad := []transire.BatchEntry{{Body: []byte("a")}, {Body: []byte("b"), Options: transire.SendOptions{Delay: time.Hour}}}
+	err := transire.SendBatch(context.Background(), app.QueueSender(), "fanout", bad)
+	var batchErr *transire.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Failed) != 1 || batchErr.Failed[0].Index != 1 {
+		t.Fatalf("expected entry 1 to fail, got %v", err)
+	}
+
+	good := []transire.BatchEntry{{Body: []byte("a")}, {Body: []byte("b")}, {Body: []byte("c")}}
+	if err := transire.SendBatch(context.Background(), app.QueueSender(), "fanout", good); err != nil {
+		t.Fatalf("batch send failed: %v", err)
+	}
+	ids := map[string]bool{}
+	for range good {
+		select {
+		case msg := <-received:
+			ids[msg.ID] = true
+		case <-time.After(time.Second):
+			t.Fatalf("batch message not delivered")
+		}
+	}
+	if len(ids) != 3 {
+		t.Fatalf("expected unique message IDs, got %v", ids)
+	}
+	select {
+	case msg := <-received:
+		t.Fatalf("rejected batch should not deliver messages, got %q", msg.Body)
+	default:
+	}
+}
+
+func TestAdminScheduleEndpointAppliesMiddleware(t *testing.T) {
+	app := transire.New()
+	wrapped := make(chan string, 1)
+	app.UseSchedule(func(next transire.ScheduleHandler) transire.ScheduleHandler {
+		return func(ctx transire.Context, at time.Time) error {
+			wrapped <- "schedule"
+			return next(ctx, at)
+		}
+	})
+	app.UseQueue(func(next transire.QueueHandler) transire.QueueHandler {
+		return func(ctx transire.Context, msg transire.Message) error {
+			wrapped <- "queue:" + msg.Queue
+			return next(ctx, msg)
+		}
+	})
+	app.RegisterScheduleHandler("tick", time.Minute, func(ctx transire.Context, at time.Time) error {
+		return ctx.Queues.Send(ctx, "work", []byte("tick"))
+	})
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error { return nil })
+
+	server := httptest.NewServer(buildHandler(app, nil))
+	t.Cleanup(server.Close)
+
+	res, err := http.Post(server.URL+"/_transire/schedules/tick", "application/json", nil)
+	if err != nil {
+		t.Fatalf("post failed: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusAccepted {
+		t.Fatalf("unexpected status: %d", res.StatusCode)
+	}
+
+	for _, want := range []string{"schedule", "queue:work"} {
+		select {
+		case got := <-wrapped:
+			if got != want {
+				t.Fatalf("expected %s middleware, got %s", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s middleware not invoked", want)
+		}
+	}
+}
+
+func TestFailingMessagesAreDeadLettered(t *testing.T) {
+	app := transire.New()
+	var receives atomic.Int32
+	app.RegisterQueueHandler("flaky", func(ctx transire.Context, msg transire.Message) error {
+		receives.Add(1)
+		return errors.New("always fails")
+	}, transire.WithDeadLetterQueue(3), transire.WithRetryPolicy(transire.RetryPolicy{InitialBackoff: time.Millisecond}))
+
+	server := httptest.NewServer(buildHandler(app, nil))
+	t.Cleanup(server.Close)
+
+	res, err := http.Post(server.URL+"/_transire/queues/flaky", "application/octet-stream", strings.NewReader("poison"))
+	if err != nil {
+		t.Fatalf("post failed: %v", err)
+	}
+	res.Body.Close()
+
+	var letters []DeadLetter
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		res, err := http.Get(server.URL + "/_transire/queues/flaky/dead-letters")
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		letters = nil
+		if err := json.NewDecoder(res.Body).Decode(&letters); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		res.Body.Close()
+		if len(letters) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(letters) != 1 || letters[0].Body != "poison" || letters[0].Error != "always fails" {
+		t.Fatalf("unexpected dead letters: %+v", letters)
+	}
+	if got := receives.Load(); got != 3 {
+		t.Fatalf("expected 3 receives before dead-lettering, got %d", got)
+	}
+}
+
+func TestQueueSenderBoundsConcurrency(t *testing.T) {
+	app := transire.New()
+	var active, peak, done atomic.Int32
+	app.RegisterQueueHandler("bounded", func(ctx transire.Context, msg transire.Message) error {
+		n := active.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		active.Add(-1)
+		done.Add(1)
+		return nil
+	}, transire.WithMaxConcurrency(2))
+
+	sender := &queueSender{app: app}
+	for i := 0; i < 8; i++ {
+		if err := sender.Send(context.Background(), "bounded", []byte("x")); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for done.Load() < 8 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := done.Load(); got != 8 {
+		t.Fatalf("expected 8 deliveries, got %d", got)
+	}
+	if got := peak.Load(); got > 2 {
+		t.Fatalf("expected at most 2 concurrent handlers, got %d", got)
+	}
+}
+
+func TestQueueHandlerLoggerCarriesMessageFields(t *testing.T) {
+	app := transire.New()
+	var buf strings.Builder
+	var mu sync.Mutex
+	app.SetLogger(slog.New(slog.NewJSONHandler(lockedWriter{&mu, &buf}, nil)))
+	done := make(chan struct{})
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		ctx.Logger.Info("handled")
+		close(done)
+		return nil
+	})
+
+	sender := &queueSender{app: app}
+	if err := sender.Send(context.Background(), "work", []byte("x")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("handler not invoked")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, want := range []string{`"kind":"queue"`, `"queue":"work"`, `"message_id":"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("expected %s in log output %q", want, buf.String())
+		}
+	}
+}
+
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+func TestPermanentFailuresSkipRedelivery(t *testing.T) {
+	app := transire.New()
+	var receives atomic.Int32
+	app.RegisterQueueHandler("poison", func(ctx transire.Context, msg transire.Message) error {
+		receives.Add(1)
+		return transire.Permanent(errors.New("bad payload"))
+	}, transire.WithDeadLetterQueue(5))
+
+	sender := &queueSender{app: app}
+	if err := sender.Send(context.Background(), "poison", []byte("x")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(sender.DeadLetters("poison")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if letters := sender.DeadLetters("poison"); len(letters) != 1 || letters[0].Error != "bad payload" {
+		t.Fatalf("unexpected dead letters: %+v", letters)
+	}
+	if got := receives.Load(); got != 1 {
+		t.Fatalf("expected a single receive, got %d", got)
+	}
+}
+
+func TestTransientFailuresBackOff(t *testing.T) {
+	app := transire.New()
+	var mu sync.Mutex
+	var times []time.Time
+	app.RegisterQueueHandler("flaky", func(ctx transire.Context, msg transire.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		times = append(times, time.Now())
+		if len(times) < 3 {
+			return transire.RetryAfter(errors.New("busy"), 30*time.Millisecond)
+		}
+		return nil
+	})
+
+	sender := &queueSender{app: app}
+	if err := sender.Send(context.Background(), "flaky", []byte("x")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(times)
+		mu.Unlock()
+		if n >= 3 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(times) != 3 {
+		t.Fatalf("expected 3 receives, got %d", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < 30*time.Millisecond {
+			t.Fatalf("receive %d redelivered after %s, expected at least 30ms", i+1, gap)
+		}
+	}
+}
+
+func TestBatchQueueHandlerReceivesAccumulatedBatches(t *testing.T) {
+	app := transire.New()
+	var mu sync.Mutex
+	var sizes []int
+	attempts := map[string]int{}
+	app.RegisterBatchQueueHandler("bulk", func(ctx transire.Context, msgs []transire.Message) transire.BatchResult {
+		mu.Lock()
+		defer mu.Unlock()
+		sizes = append(sizes, len(msgs))
+		var r transire.BatchResult
+		for _, msg := range msgs {
+			attempts[string(msg.Body)]++
+			if string(msg.Body) == "retry" && attempts["retry"] == 1 {
+				r.Fail(msg, errors.New("try again"))
+			}
+		}
+		return r
+	}, transire.WithBatchSize(3), transire.WithBatchWindow(50*time.Millisecond), transire.WithMaxConcurrency(2),
+		transire.WithRetryPolicy(transire.RetryPolicy{InitialBackoff: time.Millisecond}))
+
+	sender := &queueSender{app: app}
+	entries := []transire.BatchEntry{{Body: []byte("a")}, {Body: []byte("b")}, {Body: []byte("retry")}, {Body: []byte("c")}}
+	if err := transire.SendBatch(context.Background(), sender, "bulk", entries); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		done := attempts["retry"] == 2 && attempts["c"] == 1
+		mu.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts["a"] != 1 || attempts["b"] != 1 || attempts["c"] != 1 || attempts["retry"] != 2 {
+		t.Fatalf("unexpected attempts: %v", attempts)
+	}
+	for _, n := range sizes {
+		if n > 3 {
+			t.Fatalf("batch of %d exceeds the batch size: %v", n, sizes)
+		}
+	}
+	if sizes[0] != 3 {
+		t.Fatalf("expected the first batch to fill to 3, got %v", sizes)
+	}
+}
+
+func TestFIFOBatchesHoldGroupsAfterFailure(t *testing.T) {
+	app := transire.New()
+	var mu sync.Mutex
+	var handled []string
+	failed := false
+	app.RegisterBatchQueueHandler("billing", func(ctx transire.Context, msgs []transire.Message) transire.BatchResult {
+		mu.Lock()
+		defer mu.Unlock()
+		var r transire.BatchResult
+		for _, msg := range msgs {
+			handled = append(handled, string(msg.Body))
+			if string(msg.Body) == "a1" && !failed {
+				failed = true
+				r.Fail(msg, errors.New("try again"))
+			}
+		}
+		return r
+	}, transire.WithFIFO(), transire.WithBatchSize(10), transire.WithBatchWindow(20*time.Millisecond),
+		transire.WithRetryPolicy(transire.RetryPolicy{InitialBackoff: time.Millisecond}))
+
+	sender := &queueSender{app: app}
+	for _, body := range []string{"a1", "b1", "a2", "b2"} {
+		opts := transire.SendOptions{GroupID: body[:1], DeduplicationID: body}
+		if err := sender.SendWithOptions(context.Background(), "billing", []byte(body), opts); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		done := len(handled) == 5
+		mu.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var group []string
+	for _, body := range handled {
+		if body[0] == 'a' {
+			group = append(group, body)
+		}
+	}
+	if len(handled) != 5 || strings.Join(group, ",") != "a1,a1,a2" {
+		t.Fatalf("expected a2 handled once, after a1 succeeded, got %v", handled)
+	}
+}
+
+func TestFIFOQueueSerializesGroups(t *testing.T) {
+	app := transire.New()
+	var mu sync.Mutex
+	active := map[string]bool{}
+	var order []string
+	overlap := false
+	failedOnce := false
+	app.RegisterQueueHandler("billing", func(ctx transire.Context, msg transire.Message) error {
+		group := msg.Attributes["group"]
+		mu.Lock()
+		if active[group] {
+			overlap = true
+		}
+		active[group] = true
+		mu.Unlock()
+
+		time.Sleep(2 * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		active[group] = false
+		if string(msg.Body) == "a2" && !failedOnce {
+			failedOnce = true
+			return errors.New("try again")
+		}
+		order = append(order, string(msg.Body))
+		return nil
+	}, transire.WithContentBasedDeduplication(), transire.WithRetryPolicy(transire.RetryPolicy{InitialBackoff: 5 * time.Millisecond}))
+
+	sender := &queueSender{app: app}
+	for _, body := range []string{"a1", "b1", "a2", "b2", "a3", "b3", "a1"} {
+		group := body[:1]
+		opts := transire.SendOptions{GroupID: group, Attributes: map[string]string{"group": group}}
+		if err := sender.SendWithOptions(context.Background(), "billing", []byte(body), opts); err != nil {
+			t.Fatalf("send %s: %v", body, err)
+		}
+	}
+	if err := sender.Send(context.Background(), "billing", []byte("x")); err == nil {
+		t.Fatalf("expected FIFO send without a group to fail")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(order)
+		mu.Unlock()
+		if n == 6 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if overlap {
+		t.Fatalf("messages of one group were processed concurrently")
+	}
+	var a, b []string
+	for _, body := range order {
+		if body[0] == 'a' {
+			a = append(a, body)
+		} else {
+			b = append(b, body)
+		}
+	}
+	if strings.Join(a, ",") != "a1,a2,a3" || strings.Join(b, ",") != "b1,b2,b3" {
+		t.Fatalf("expected per-group order with the duplicate dropped, got %v", order)
+	}
+}
+
+func TestTopicFansOutToSubscribers(t *testing.T) {
+	app := transire.New()
+	var mu sync.Mutex
+	got := map[string]string{}
+	for _, sub := range []string{"billing", "email"} {
+		app.RegisterTopicSubscriber("order-created", sub, func(ctx transire.Context, msg transire.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got[sub] = string(msg.Body)
+			return nil
+		})
+	}
+
+	server := httptest.NewServer(buildHandler(app, nil))
+	t.Cleanup(server.Close)
+
+	res, err := http.Post(server.URL+"/_transire/topics/order-created", "application/json", strings.NewReader(`{"id":1}`))
+	if err != nil {
+		t.Fatalf("post failed: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", res.StatusCode)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(got)
+		mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got["billing"] != `{"id":1}` || got["email"] != `{"id":1}` {
+		t.Fatalf("expected both subscribers to receive the message, got %v", got)
+	}
+
+	if err := app.TopicPublisher().Publish(context.Background(), "unknown", nil); err == nil {
+		t.Fatalf("expected error for a topic without subscribers")
+	}
+}
+
+func TestCorrelationIDFollowsHTTPToQueueChain(t *testing.T) {
+	app := transire.New()
+	ids := make(chan string, 2)
+	app.Router().Post("/work", func(w http.ResponseWriter, r *http.Request) {
+		ctx, _ := transire.RequestContext(r)
+		if err := ctx.Queues.Send(ctx, "work", []byte("x")); err != nil {
+			t.Errorf("send: %v", err)
+		}
+	})
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		ids <- msg.Attributes[transire.CorrelationIDAttribute]
+		return ctx.Queues.Send(ctx, "summary", msg.Body)
+	})
+	app.RegisterQueueHandler("summary", func(ctx transire.Context, msg transire.Message) error {
+		ids <- transire.CorrelationID(ctx)
+		return nil
+	})
+
+	server := httptest.NewServer(buildHandler(app, nil))
+	t.Cleanup(server.Close)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/work", nil)
+	req.Header.Set(transire.CorrelationIDHeader, "c-1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get(transire.CorrelationIDHeader); got != "c-1" {
+		t.Fatalf("expected echoed correlation ID, got %q", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case id := <-ids:
+			if id != "c-1" {
+				t.Fatalf("hop %d: expected correlation ID c-1, got %q", i, id)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("hop %d not reached", i)
+		}
+	}
+}
+
+func TestQueueSpansJoinSenderTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	app := transire.New()
+	app.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	app.Router().Post("/work", func(w http.ResponseWriter, r *http.Request) {
+		ctx, _ := transire.RequestContext(r)
+		if err := ctx.Queues.Send(ctx, "work", []byte("x")); err != nil {
+			t.Errorf("send: %v", err)
+		}
+	})
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		return nil
+	})
+
+	server := httptest.NewServer(buildHandler(app, nil))
+	t.Cleanup(server.Close)
+	resp, err := http.Post(server.URL+"/work", "text/plain", nil)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for len(exporter.GetSpans()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected server and consumer spans, got %d", len(spans))
+	}
+	byName := map[string]tracetest.SpanStub{}
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+	httpSpan, consumer := byName["POST /work"], byName["queue work"]
+	if consumer.Parent.SpanID() != httpSpan.SpanContext.SpanID() || consumer.SpanContext.TraceID() != httpSpan.SpanContext.TraceID() {
+		t.Fatalf("expected consumer span to be a child of the server span: %+v", spans)
+	}
+}
+
+func TestAdminMetricsEndpoint(t *testing.T) {
+	app := transire.New()
+	done := make(chan struct{})
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		defer close(done)
+		return transire.Permanent(errors.New("bad"))
+	})
+
+	server := httptest.NewServer(buildHandler(app, nil))
+	t.Cleanup(server.Close)
+	if err := app.QueueSender().Send(context.Background(), "work", []byte("x")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	<-done
+
+	var body string
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		res, err := http.Get(server.URL + "/_transire/metrics")
+		if err != nil {
+			t.Fatalf("get metrics: %v", err)
+		}
+		raw, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		body = string(raw)
+		if strings.Contains(body, `transire_handler_invocations_total{kind="queue",name="work"} 1`) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	for _, want := range []string{
+		`transire_handler_invocations_total{kind="queue",name="work"} 1`,
+		`transire_handler_errors_total{kind="queue",name="work"} 1`,
+		`transire_handler_in_flight{kind="queue",name="work"} 0`,
+		`transire_handler_duration_seconds_count{kind="queue",name="work"} 1`,
+		`transire_queue_messages_sent_total{queue="work"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in metrics:\n%s", want, body)
+		}
+	}
+}
+
+func TestPanickingHandlerIsDeadLettered(t *testing.T) {
+	app := transire.New()
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		panic("nil map")
+	}, transire.WithDeadLetterQueue(1))
+
+	sender := &queueSender{app: app}
+	if err := sender.Send(context.Background(), "work", []byte("x")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(sender.DeadLetters("work")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if letters := sender.DeadLetters("work"); len(letters) != 1 || !strings.Contains(letters[0].Error, "handler panic: nil map") {
+		t.Fatalf("unexpected dead letters: %+v", letters)
+	}
+}
+
+func TestDrainWaitsForHandlerChains(t *testing.T) {
+	app := transire.New()
+	release := make(chan struct{})
+	var summarized atomic.Bool
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		<-release
+		return ctx.Queues.Send(ctx, "summary", msg.Body)
+	})
+	app.RegisterQueueHandler("summary", func(ctx transire.Context, msg transire.Message) error {
+		time.Sleep(10 * time.Millisecond)
+		summarized.Store(true)
+		return nil
+	})
+
+	sender := &queueSender{app: app}
+	if err := sender.Send(context.Background(), "work", []byte("x")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	drained := make(chan error, 1)
+	go func() { drained <- sender.Drain(context.Background()) }()
+	deadline := time.Now().Add(time.Second)
+	for sender.Send(context.Background(), "work", nil) == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := sender.Send(context.Background(), "work", nil); !errors.Is(err, transire.ErrShuttingDown) {
+		t.Fatalf("expected sends rejected while draining, got %v", err)
+	}
+	close(release)
+
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Fatalf("drain: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("drain did not finish")
+	}
+	if !summarized.Load() {
+		t.Fatalf("expected the chained message handled before the drain finished")
+	}
+}
+
+func TestDrainTimesOut(t *testing.T) {
+	app := transire.New()
+	block := make(chan struct{})
+	t.Cleanup(func() { close(block) })
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		<-block
+		return nil
+	})
+
+	sender := &queueSender{app: app}
+	if err := sender.Send(context.Background(), "work", nil); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := sender.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected drain timeout, got %v", err)
+	}
+}
+
+func TestRunDrainsAndRunsLifecycleHooks(t *testing.T) {
+	app := transire.New()
+	var events []string
+	var mu sync.Mutex
+	record := func(e string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+	app.OnStart(func(ctx context.Context) error { record("start"); return nil })
+	app.OnShutdown(func(ctx context.Context) error { record("shutdown"); return nil })
+	started := make(chan struct{})
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		record("handled")
+		return nil
+	})
+
+	ensureQueueSender(app)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	d := &Dispatcher{HTTPAddr: "127.0.0.1:0", DrainTimeout: time.Second}
+	go func() { done <- d.Run(ctx, app) }()
+
+	if err := app.QueueSender().Send(context.Background(), "work", nil); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("run did not return")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(events, ","); got != "start,handled,shutdown" {
+		t.Fatalf("unexpected lifecycle: %s", got)
+	}
+}
+
+func TestShutdownFitsHooksInTheDrainTimeout(t *testing.T) {
+	app := transire.New()
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	started := make(chan struct{})
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		close(started)
+		<-release
+		return nil
+	})
+	hookDeadline := make(chan time.Time, 1)
+	app.OnShutdown(func(ctx context.Context) error {
+		deadline, _ := ctx.Deadline()
+		hookDeadline <- deadline
+		return nil
+	})
+
+	ensureQueueSender(app)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	timeout := 400 * time.Millisecond
+	d := &Dispatcher{HTTPAddr: "127.0.0.1:0", DrainTimeout: timeout}
+	go func() { done <- d.Run(ctx, app) }()
+
+	if err := app.QueueSender().Send(context.Background(), "work", nil); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	<-started
+	stopped := time.Now()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatalf("expected the stuck handler reported")
+		}
+	case <-time.After(2 * timeout):
+		t.Fatalf("run did not return within the drain timeout")
+	}
+	select {
+	case deadline := <-hookDeadline:
+		if deadline.After(stopped.Add(timeout+timeout/4)) || !deadline.After(time.Now()) {
+			t.Fatalf("expected the hooks to keep time before the shared deadline, got %v", deadline.Sub(stopped))
+		}
+	default:
+		t.Fatalf("expected the shutdown hooks run after a slow drain")
+	}
+}
+
+func TestDuplicateFIFOMessagesAreNotCountedAsSent(t *testing.T) {
+	app := transire.New()
+	metrics := newPromMetrics()
+	app.SetMetrics(metrics)
+	app.RegisterQueueHandler("billing", func(ctx transire.Context, msg transire.Message) error {
+		return nil
+	}, transire.WithContentBasedDeduplication())
+
+	sender := &queueSender{app: app}
+	for i := 0; i < 2; i++ {
+		if err := sender.SendWithOptions(context.Background(), "billing", []byte("a"), transire.SendOptions{GroupID: "g"}); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	if got := metrics.sent["billing"]; got != 1 {
+		t.Fatalf("expected the duplicate not counted, got %d sent", got)
+	}
+}
+
+func TestDeliveryOutlivesSenderContext(t *testing.T) {
+	app := transire.New()
+	type result struct {
+		err         error
+		correlation string
+	}
+	got := make(chan result, 1)
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		time.Sleep(10 * time.Millisecond)
+		got <- result{ctx.Err(), transire.CorrelationID(ctx)}
+		return nil
+	})
+
+	sender := &queueSender{app: app}
+	ctx, cancel := context.WithCancel(transire.ContextWithCorrelationID(context.Background(), "corr-1"))
+	if err := sender.Send(ctx, "work", nil); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	cancel()
+
+	select {
+	case r := <-got:
+		if r.err != nil || r.correlation != "corr-1" {
+			t.Fatalf("expected a live context carrying the correlation ID, got err=%v correlation=%q", r.err, r.correlation)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("handler did not run")
+	}
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	app := transire.New()
+	got := make(chan error, 1)
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		<-ctx.Done()
+		got <- ctx.Err()
+		return nil
+	})
+
+	sender := &queueSender{app: app, timeout: 20 * time.Millisecond}
+	if err := sender.Send(context.Background(), "work", nil); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	select {
+	case err := <-got:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected deadline exceeded, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("handler was not timed out")
+	}
+}
+
+func TestClockAdvanceFiresTicksInOrder(t *testing.T) {
+	app := transire.New()
+	var mu sync.Mutex
+	var fired []string
+	record := func(ctx transire.Context, at time.Time) error {
+		mu.Lock()
+		defer mu.Unlock()
+		fired = append(fired, at.Format("15:04"))
+		return nil
+	}
+	app.RegisterScheduleHandler("every-40m", 40*time.Minute, record)
+	app.RegisterCronHandler("hourly", "0 * * * *", "", record)
+
+	clock := NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	schedules := newScheduler(app, clock)
+	server := httptest.NewServer(buildHandler(app, schedules))
+	t.Cleanup(server.Close)
+
+	res, err := http.Post(server.URL+"/_transire/clock/advance?by=2h", "", nil)
+	if err != nil {
+		t.Fatalf("advance: %v", err)
+	}
+	defer res.Body.Close()
+	var body struct {
+		Now  time.Time     `json:"now"`
+		Runs []ScheduleRun `json:"runs"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var runs []string
+	for _, run := range body.Runs {
+		runs = append(runs, run.Schedule+"@"+run.At.Format("15:04"))
+	}
+	if got := strings.Join(runs, ","); got != "every-40m@00:40,hourly@01:00,every-40m@01:20,every-40m@02:00,hourly@02:00" {
+		t.Fatalf("unexpected runs: %s", got)
+	}
+	if got := strings.Join(fired, ","); got != "00:40,01:00,01:20,02:00,02:00" {
+		t.Fatalf("unexpected handler times: %s", got)
+	}
+	if !body.Now.Equal(clock.Now()) || clock.Now().Hour() != 2 {
+		t.Fatalf("expected the fake clock moved to 02:00, got %v", clock.Now())
+	}
+}
+
+func TestSchedulesFollowFakeClock(t *testing.T) {
+	app := transire.New()
+	fired := make(chan time.Time, 1)
+	app.RegisterScheduleHandler("tick", time.Hour, func(ctx transire.Context, at time.Time) error {
+		fired <- at
+		return nil
+	})
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	schedules := newScheduler(app, clock)
+	ctx, cancel := context.WithCancel(context.Background())
+	schedules.start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		schedules.Wait()
+	})
+
+	select {
+	case at := <-fired:
+		t.Fatalf("schedule fired before the clock moved: %v", at)
+	case <-time.After(20 * time.Millisecond):
+	}
+	clock.Advance(time.Hour)
+	select {
+	case at := <-fired:
+		if !at.Equal(start.Add(time.Hour)) {
+			t.Fatalf("unexpected tick time %v", at)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("schedule did not fire when the clock reached its tick")
+	}
+}
+
+func TestScheduleConcurrencyPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy transire.Concurrency
+		// overlap is how many runs start while the first is blocked; total is how many
+		// run once it is released.
+		overlap, total int
+	}{
+		{transire.ConcurrencyAllow, 2, 2},
+		{transire.ConcurrencySkip, 1, 1},
+		{transire.ConcurrencyQueue, 1, 2},
+	} {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			app := transire.New()
+			started := make(chan time.Time, 4)
+			release := make(chan struct{})
+			app.RegisterScheduleHandler("reconcile", time.Hour, func(ctx transire.Context, at time.Time) error {
+				started <- at
+				<-release
+				return nil
+			}, transire.WithConcurrency(tc.policy))
+
+			start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			clock := NewFakeClock(start)
+			schedules := newScheduler(app, clock)
+			ctx, cancel := context.WithCancel(context.Background())
+			schedules.start(ctx)
+
+			clock.Advance(time.Hour)
+			<-started
+			if tc.policy == transire.ConcurrencySkip {
+				found, err := schedules.Trigger(ctx, "reconcile", clock.Now())
+				if !found || !errors.Is(err, errRunInProgress) {
+					t.Fatalf("expected a trigger during a run to be skipped, got %v", err)
+				}
+			}
+			clock.Advance(time.Hour)
+			overlap := 1
+			for overlap < 2 {
+				select {
+				case <-started:
+					overlap++
+					continue
+				case <-time.After(50 * time.Millisecond):
+				}
+				break
+			}
+			if overlap != tc.overlap {
+				t.Fatalf("expected %d overlapping runs, got %d", tc.overlap, overlap)
+			}
+
+			close(release)
+			total := overlap
+			deadline := time.After(time.Second)
+			for total < tc.total {
+				select {
+				case <-started:
+					total++
+				case <-deadline:
+					t.Fatalf("expected %d runs, got %d", tc.total, total)
+				}
+			}
+			cancel()
+			schedules.Wait()
+			if len(started) != 0 {
+				t.Fatalf("expected %d runs, got %d", tc.total, total+len(started))
+			}
+		})
+	}
+}
+
+func TestQueuedScheduleRunDoesNotHoldUpAdvance(t *testing.T) {
+	app := transire.New()
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	app.RegisterScheduleHandler("reconcile", time.Hour, func(ctx transire.Context, at time.Time) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}, transire.WithConcurrency(transire.ConcurrencyQueue))
+
+	clock := NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	schedules := newScheduler(app, clock)
+	ctx, cancel := context.WithCancel(context.Background())
+	schedules.start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		schedules.Wait()
+	})
+
+	clock.Advance(time.Hour)
+	<-started
+	advanced := make(chan struct{})
+	go func() {
+		schedules.Advance(ctx, 30*time.Minute)
+		close(advanced)
+	}()
+	select {
+	case <-advanced:
+	case <-time.After(time.Second):
+		t.Fatalf("advance blocked on the queued run in progress")
+	}
+	close(release)
+}
+
+func TestScheduleLoopDropsStaleClockWaits(t *testing.T) {
+	app := transire.New()
+	app.RegisterScheduleHandler("reconcile", time.Hour, func(ctx transire.Context, at time.Time) error { return nil })
+
+	clock := NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	schedules := newScheduler(app, clock)
+	ctx, cancel := context.WithCancel(context.Background())
+	schedules.start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		schedules.Wait()
+	})
+
+	waiters := func() int {
+		clock.mu.Lock()
+		defer clock.mu.Unlock()
+		return len(clock.waiters)
+	}
+	for i := 0; i < 5; i++ {
+		schedules.Advance(ctx, time.Minute)
+		for deadline := time.Now().Add(time.Second); waiters() != 1 && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if n := waiters(); n != 1 {
+		t.Fatalf("expected one clock wait, got %d", n)
+	}
+}
+
+func TestScheduleRunOnStartAndJitter(t *testing.T) {
+	app := transire.New()
+	fired := make(chan time.Time, 4)
+	app.RegisterScheduleHandler("warm", time.Hour, func(ctx transire.Context, at time.Time) error {
+		fired <- at
+		return nil
+	}, transire.WithRunOnStart(), transire.WithJitter(10*time.Minute))
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	schedules := newScheduler(app, clock)
+	ctx, cancel := context.WithCancel(context.Background())
+	schedules.start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		schedules.Wait()
+	})
+
+	select {
+	case at := <-fired:
+		if !at.Equal(start) {
+			t.Fatalf("unexpected start run time %v", at)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("schedule did not run on start")
+	}
+	// The tick fires somewhere in its jitter window but is reported at its nominal time.
+	clock.Advance(time.Hour + 10*time.Minute)
+	select {
+	case at := <-fired:
+		if !at.Equal(start.Add(time.Hour)) {
+			t.Fatalf("unexpected tick time %v", at)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("schedule did not fire by the end of its jitter window")
+	}
+}
+
+func TestAdminScheduleEndpointDeliversData(t *testing.T) {
+	app := transire.New()
+	var got transire.ScheduleInvocation
+	app.RegisterScheduleHandler("report", time.Hour, func(ctx transire.Context, at time.Time) error {
+		got, _ = transire.ScheduleInvocationFromContext(ctx)
+		return nil
+	}, transire.WithMetadata("kind", "daily"), transire.WithMetadata("team", "billing"))
+
+	schedules := newScheduler(app, NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	server := httptest.NewServer(buildHandler(app, schedules))
+	t.Cleanup(server.Close)
+
+	res, err := http.Post(server.URL+"/_transire/schedules/report", "application/json", strings.NewReader(`{"kind":"backfill"}`))
+	if err != nil {
+		t.Fatalf("post failed: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusAccepted {
+		t.Fatalf("unexpected status: %d", res.StatusCode)
+	}
+	if got.Schedule != "report" || got.Metadata["kind"] != "backfill" || got.Metadata["team"] != "billing" {
+		t.Fatalf("unexpected invocation %+v", got)
+	}
+
+	res, err = http.Post(server.URL+"/_transire/schedules/report", "application/json", strings.NewReader(`["kind"]`))
+	if err != nil {
+		t.Fatalf("post failed: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected invalid data rejected, got %d", res.StatusCode)
+	}
+}
+
+func TestScheduleEnvironmentOverrides(t *testing.T) {
+	t.Setenv("TRANSIRE_SCHEDULE_NIGHTLY_REPORT_ENABLED", "false")
+	t.Setenv("TRANSIRE_SCHEDULE_RECONCILE_RATE", "15m")
+	t.Setenv("TRANSIRE_SCHEDULE_DIGEST_CRON", "30 * * * *")
+	app := transire.New()
+	noop := func(ctx transire.Context, at time.Time) error { return nil }
+	app.RegisterCronHandler("nightly-report", "0 2 * * *", "", noop)
+	app.RegisterScheduleHandler("reconcile", time.Hour, noop)
+	app.RegisterScheduleHandler("digest", time.Hour, noop)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	schedules := newScheduler(app, NewFakeClock(start))
+	next := map[string]time.Time{}
+	for _, e := range schedules.entries {
+		next[e.name] = e.next
+	}
+	if _, ok := next["nightly-report"]; ok || len(next) != 2 {
+		t.Fatalf("expected nightly-report disabled, got %v", next)
+	}
+	if !next["reconcile"].Equal(start.Add(15*time.Minute)) || !next["digest"].Equal(start.Add(30*time.Minute)) {
+		t.Fatalf("expected overridden rates, got %v", next)
+	}
+}
+
+func TestTimersPersistAndFireOnScheduleTime(t *testing.T) {
+	app := transire.New()
+	received := make(chan string, 4)
+	app.RegisterQueueHandler("reminders", func(ctx transire.Context, msg transire.Message) error {
+		received <- string(msg.Body)
+		return nil
+	})
+	app.RegisterQueueHandler("ordered", func(ctx transire.Context, msg transire.Message) error { return nil }, transire.WithFIFO())
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	path := t.TempDir() + "/timers.json"
+	clock := NewFakeClock(start)
+	schedules := newScheduler(app, clock)
+	ensureQueueSender(app)
+	timers := app.Timers().(*timerStore)
+	if err := timers.open(path, schedules); err != nil {
+		t.Fatalf("open timers: %v", err)
+	}
+	schedules.timers = timers
+	server := httptest.NewServer(buildHandler(app, schedules))
+	t.Cleanup(server.Close)
+
+	ctx := app.NewContext(context.Background())
+	if _, err := ctx.ScheduleAfter("reminders", 24*time.Hour, []byte("tomorrow")); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	cancelled, err := ctx.ScheduleAt("reminders", start.Add(48*time.Hour), []byte("cancelled"))
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	if _, err := ctx.ScheduleAt("reminders", start.Add(36*time.Hour), []byte("later")); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	if err := ctx.CancelScheduled(cancelled); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if err := ctx.CancelScheduled(cancelled); !errors.Is(err, transire.ErrTimerNotFound) {
+		t.Fatalf("expected a cancelled timer not found, got %v", err)
+	}
+	if _, err := ctx.ScheduleAfter("ordered", time.Hour, nil); err == nil {
+		t.Fatalf("expected timers to reject FIFO queues")
+	}
+	if _, err := ctx.ScheduleAfter("missing", time.Hour, nil); err == nil {
+		t.Fatalf("expected timers to reject unknown queues")
+	}
+
+	res, err := http.Get(server.URL + "/_transire/timers")
+	if err != nil {
+		t.Fatalf("list timers: %v", err)
+	}
+	var listed []transire.Timer
+	err = json.NewDecoder(res.Body).Decode(&listed)
+	res.Body.Close()
+	if err != nil || len(listed) != 2 || string(listed[0].Payload) != "tomorrow" || string(listed[1].Payload) != "later" {
+		t.Fatalf("unexpected timers %+v: %v", listed, err)
+	}
+
+	schedules.Advance(context.Background(), 30*time.Hour)
+	select {
+	case body := <-received:
+		if body != "tomorrow" {
+			t.Fatalf("unexpected delivery %q", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timer not delivered by Advance")
+	}
+
+	// A restarted dispatcher picks the pending timer up from the file and sends it once due.
+	restarted := newScheduler(app, NewFakeClock(start.Add(40*time.Hour)))
+	reopened := newTimerStore(app)
+	if err := reopened.open(path, restarted); err != nil {
+		t.Fatalf("reopen timers: %v", err)
+	}
+	restarted.timers = reopened
+	if got := reopened.List(); len(got) != 1 || string(got[0].Payload) != "later" {
+		t.Fatalf("unexpected persisted timers %+v", got)
+	}
+	runCtx, cancel := context.WithCancel(context.Background())
+	restarted.start(runCtx)
+	t.Cleanup(func() {
+		cancel()
+		restarted.Wait()
+	})
+	select {
+	case body := <-received:
+		if body != "later" {
+			t.Fatalf("unexpected delivery %q", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("overdue timer not delivered on start")
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the timers file removed once none are pending, got %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/_transire/timers/"+cancelled, nil)
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("delete timer: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a cancelled timer not found, got %d", res.StatusCode)
+	}
+}
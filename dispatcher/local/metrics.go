@@ -0,0 +1,152 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package local
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the handler duration histogram.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type handlerKey struct {
+	kind, name string
+}
+
+type handlerStats struct {
+	invocations int64
+	errors      int64
+	inFlight    int64
+	buckets     []int64
+	sum         float64
+}
+
+// promMetrics keeps handler and send metrics in memory and renders them in the
+// Prometheus text exposition format.
+type promMetrics struct {
+	mu       sync.Mutex
+	handlers map[handlerKey]*handlerStats
+	sent     map[string]int64
+}
+
+func newPromMetrics() *promMetrics {
+	return &promMetrics{
+		handlers: map[handlerKey]*handlerStats{},
+		sent:     map[string]int64{},
+	}
+}
+
+func (m *promMetrics) stats(kind, name string) *handlerStats {
+	key := handlerKey{kind, name}
+	s, ok := m.handlers[key]
+	if !ok {
+		s = &handlerStats{buckets: make([]int64, len(durationBuckets))}
+		m.handlers[key] = s
+	}
+	return s
+}
+
+func (m *promMetrics) HandlerStarted(kind, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats(kind, name).inFlight++
+}
+
+func (m *promMetrics) HandlerFinished(kind, name string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.stats(kind, name)
+	s.inFlight--
+	s.invocations++
+	if err != nil {
+		s.errors++
+	}
+	seconds := duration.Seconds()
+	s.sum += seconds
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			s.buckets[i]++
+		}
+	}
+}
+
+func (m *promMetrics) MessageSent(queue string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent[queue]++
+}
+
+// WritePrometheus writes all metrics in the Prometheus text format, sorted by label.
+func (m *promMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]handlerKey, 0, len(m.handlers))
+	for k := range m.handlers {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		return keys[i].name < keys[j].name
+	})
+
+	var b strings.Builder
+	counter := func(name, help string, value func(*handlerStats) int64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "%s{%s} %d\n", name, handlerLabels(k), value(m.handlers[k]))
+		}
+	}
+	counter("transire_handler_invocations_total", "Handler invocations.", func(s *handlerStats) int64 { return s.invocations })
+	counter("transire_handler_errors_total", "Handler invocations that returned an error.", func(s *handlerStats) int64 { return s.errors })
+
+	b.WriteString("# HELP transire_handler_in_flight Handler invocations currently running.\n# TYPE transire_handler_in_flight gauge\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "transire_handler_in_flight{%s} %d\n", handlerLabels(k), m.handlers[k].inFlight)
+	}
+
+	b.WriteString("# HELP transire_handler_duration_seconds Handler invocation duration.\n# TYPE transire_handler_duration_seconds histogram\n")
+	for _, k := range keys {
+		s := m.handlers[k]
+		labels := handlerLabels(k)
+		for i, bound := range durationBuckets {
+			fmt.Fprintf(&b, "transire_handler_duration_seconds_bucket{%s,le=%q} %d\n", labels, strconv.FormatFloat(bound, 'g', -1, 64), s.buckets[i])
+		}
+		fmt.Fprintf(&b, "transire_handler_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, s.invocations)
+		fmt.Fprintf(&b, "transire_handler_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(s.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "transire_handler_duration_seconds_count{%s} %d\n", labels, s.invocations)
+	}
+
+	queues := make([]string, 0, len(m.sent))
+	for q := range m.sent {
+		queues = append(queues, q)
+	}
+	sort.Strings(queues)
+	b.WriteString("# HELP transire_queue_messages_sent_total Messages sent to a queue.\n# TYPE transire_queue_messages_sent_total counter\n")
+	for _, q := range queues {
+		fmt.Fprintf(&b, "transire_queue_messages_sent_total{queue=\"%s\"} %d\n", escapeLabel(q), m.sent[q])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func handlerLabels(k handlerKey) string {
+	return fmt.Sprintf("kind=\"%s\",name=\"%s\"", escapeLabel(k.kind), escapeLabel(k.name))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,550 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package local
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	transire "github.com/transire/transire"
+)
+
+// defaultConcurrency bounds the workers per queue when no WithMaxConcurrency option is set.
+const defaultConcurrency = 10
+
+// defaultBatchSize matches the SQS event source default for batch handlers.
+const defaultBatchSize = 10
+
+// messageSeq keeps IDs unique when a batch is enqueued within the same nanosecond.
+var messageSeq atomic.Uint64
+
+// queueSender delivers messages in-process through a bounded worker pool per queue.
+type queueSender struct {
+	app *transire.App
+
+	mu          sync.Mutex
+	queues      map[string]*localQueue
+	deadLetters map[string][]DeadLetter
+	// dedup records when each FIFO deduplication ID was last accepted, keyed by queue.
+	dedup map[string]map[string]time.Time
+	// timeout bounds each handler invocation; see handlerTimeout.
+	timeout time.Duration
+
+	// active counts accepted messages that are not yet settled, including delayed and
+	// backed-off ones; idle is closed when it drops to zero during a drain.
+	active   int
+	idle     chan struct{}
+	draining bool
+	closed   bool
+}
+
+// runningHandlerKey marks contexts of running handlers, whose sends are still accepted while
+// the sender drains so that handler chains can complete.
+type runningHandlerKey struct{}
+
+type delivery struct {
+	// ctx carries the sender's values, such as its correlation ID and span, but not
+	// its cancellation: like an SQS message, a delivery outlives the request that sent it.
+	ctx     context.Context
+	handler transire.QueueHandler
+	msg     transire.Message
+	// group and dedupID are set for FIFO queues.
+	group   string
+	dedupID string
+	// receives counts completed deliveries of msg.
+	receives int
+}
+
+// localQueue is an unbounded queue of pending deliveries drained by the queue's workers.
+// FIFO queues hand out each message group to one worker at a time, in send order.
+type localQueue struct {
+	mu       sync.Mutex
+	ready    *sync.Cond
+	pending  []delivery
+	fifo     bool
+	inFlight map[string]bool
+}
+
+func (q *queueSender) Send(ctx context.Context, queue string, payload []byte) error {
+	return q.SendWithOptions(ctx, queue, payload, transire.SendOptions{})
+}
+
+// SendWithOptions delivers the message after opts.Delay with opts.Attributes and the
+// correlation ID and span context of ctx attached.
+func (q *queueSender) SendWithOptions(ctx context.Context, queue string, payload []byte, opts transire.SendOptions) error {
+	if err := q.accepting(ctx); err != nil {
+		return err
+	}
+	d, err := q.prepare(ctx, queue, payload, opts)
+	if err != nil {
+		return err
+	}
+	q.deliver(ctx, d, opts.Delay)
+	return nil
+}
+
+// SendBatch validates every entry before delivering any, so a batch is enqueued all-or-nothing.
+func (q *queueSender) SendBatch(ctx context.Context, queue string, entries []transire.BatchEntry) error {
+	if err := q.accepting(ctx); err != nil {
+		return err
+	}
+	ds := make([]delivery, len(entries))
+	batchErr := &transire.BatchError{Queue: queue}
+	for i, entry := range entries {
+		d, err := q.prepare(ctx, queue, entry.Body, entry.Options)
+		if err != nil {
+			batchErr.Failed = append(batchErr.Failed, transire.BatchFailure{Index: i, Err: err})
+			continue
+		}
+		ds[i] = d
+	}
+	if len(batchErr.Failed) > 0 {
+		return batchErr
+	}
+	for i, d := range ds {
+		q.deliver(ctx, d, entries[i].Options.Delay)
+	}
+	return nil
+}
+
+// sent counts a message sent to queue in the app's metrics.
+func (q *queueSender) sent(queue string) {
+	if m := q.app.Metrics(); m != nil {
+		m.MessageSent(queue)
+	}
+}
+
+func (q *queueSender) prepare(ctx context.Context, queue string, payload []byte, opts transire.SendOptions) (delivery, error) {
+	handler, ok := q.app.QueueHandler(queue)
+	if !ok {
+		return delivery{}, fmt.Errorf("queue %q not registered", queue)
+	}
+	cfg := q.app.QueueConfig(queue)
+	if err := cfg.ValidateSend(queue, opts); err != nil {
+		return delivery{}, err
+	}
+
+	attrs := make(map[string]string, len(opts.Attributes)+1)
+	for k, v := range transire.OutgoingAttributes(ctx, opts.Attributes) {
+		attrs[k] = v
+	}
+	d := delivery{
+		handler: handler,
+		msg: transire.Message{
+			ID:         fmt.Sprintf("local-%d-%d", time.Now().UnixNano(), messageSeq.Add(1)),
+			Queue:      queue,
+			Body:       payload,
+			Attributes: attrs,
+		},
+	}
+	if cfg.FIFO {
+		d.group = opts.GroupID
+		d.dedupID = opts.DeduplicationID
+		if d.dedupID == "" {
+			sum := sha256.Sum256(payload)
+			d.dedupID = hex.EncodeToString(sum[:])
+		}
+	}
+	return d, nil
+}
+
+// deliver enqueues the message for the queue's workers once delay has elapsed.
+// FIFO messages repeating a deduplication ID within the deduplication window are dropped
+// without being counted as sent.
+func (q *queueSender) deliver(ctx context.Context, d delivery, delay time.Duration) {
+	if d.dedupID != "" && q.duplicate(d.msg.Queue, d.dedupID) {
+		q.app.QueueContext(ctx, d.msg).Logger.Info("dropping duplicate message", "deduplication_id", d.dedupID)
+		return
+	}
+	q.sent(d.msg.Queue)
+	q.track(1)
+	lq := q.queue(d.msg.Queue)
+	d.ctx = context.WithoutCancel(ctx)
+	if delay > 0 {
+		time.AfterFunc(delay, func() { lq.push(d) })
+		return
+	}
+	lq.push(d)
+}
+
+// duplicate reports whether id was accepted on queue within the deduplication window,
+// recording it otherwise.
+func (q *queueSender) duplicate(queue, id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	seen := q.dedup[queue]
+	if seen == nil {
+		if q.dedup == nil {
+			q.dedup = map[string]map[string]time.Time{}
+		}
+		seen = map[string]time.Time{}
+		q.dedup[queue] = seen
+	}
+	for k, at := range seen {
+		if now.Sub(at) >= transire.DeduplicationWindow {
+			delete(seen, k)
+		}
+	}
+	if _, ok := seen[id]; ok {
+		return true
+	}
+	seen[id] = now
+	return false
+}
+
+// queue returns the named queue, starting its workers on first use.
+func (q *queueSender) queue(name string) *localQueue {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if lq, ok := q.queues[name]; ok {
+		return lq
+	}
+	if q.queues == nil {
+		q.queues = map[string]*localQueue{}
+	}
+	cfg := q.app.QueueConfig(name)
+	lq := &localQueue{fifo: cfg.FIFO, inFlight: map[string]bool{}}
+	lq.ready = sync.NewCond(&lq.mu)
+	q.queues[name] = lq
+
+	workers := cfg.MaxConcurrency
+	if workers <= 0 {
+		workers = defaultConcurrency
+	}
+	batch, isBatch := q.app.BatchQueueHandler(name)
+	for i := 0; i < workers; i++ {
+		if isBatch {
+			go q.workBatches(lq, batch, cfg)
+		} else {
+			go q.work(lq)
+		}
+	}
+	return lq
+}
+
+func (q *queueSender) work(lq *localQueue) {
+	for {
+		q.process(lq, lq.pop())
+	}
+}
+
+// workBatches feeds a batch handler up to BatchSize messages at a time, waiting up to
+// BatchWindow for a batch to fill.
+func (q *queueSender) workBatches(lq *localQueue, handler transire.BatchQueueHandler, cfg transire.QueueConfig) {
+	size := cfg.BatchSize
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	for {
+		q.processBatch(lq, handler, lq.popBatch(size, cfg.BatchWindow))
+	}
+}
+
+// process runs the handler once, bounded by the handler timeout, and settles a failure.
+func (q *queueSender) process(lq *localQueue, d delivery) {
+	d.receives++
+	ctx, cancel := context.WithTimeout(d.ctx, handlerTimeout(q.timeout))
+	defer cancel()
+	hctx := q.app.QueueContext(context.WithValue(ctx, runningHandlerKey{}, true), d.msg)
+	hctx.Queues = q
+	err := d.handler(hctx, d.msg)
+	if err != nil {
+		if delay, retry := q.settle(hctx.Logger, d, err); retry {
+			lq.redeliver(delay, d)
+			return
+		}
+	}
+	lq.release(d.group)
+	q.track(-1)
+}
+
+// processBatch runs the batch handler once and settles each reported failure.
+// The batch runs on a background context bounded by the handler timeout, since its
+// messages may come from many senders.
+// On FIFO queues, messages that follow a failure in the same group are returned to the
+// queue with it so the group stays in order.
+func (q *queueSender) processBatch(lq *localQueue, handler transire.BatchQueueHandler, ds []delivery) {
+	msgs := make([]transire.Message, len(ds))
+	for i := range ds {
+		ds[i].receives++
+		msgs[i] = ds[i].msg
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), handlerTimeout(q.timeout))
+	defer cancel()
+	hctx := q.app.BatchContext(context.WithValue(ctx, runningHandlerKey{}, true), ds[0].msg.Queue, len(ds))
+	hctx.Queues = q
+	result := handler(hctx, msgs)
+
+	failed := make(map[string]error, len(result.Failures))
+	for _, f := range result.Failures {
+		if f.Err == nil {
+			f.Err = errors.New("message reported as failed")
+		}
+		failed[f.MessageID] = f.Err
+	}
+
+	requeued := 0
+	var groups []string
+	held := map[string][]delivery{}
+	delays := map[string]time.Duration{}
+	blocked := map[string]bool{}
+	for _, d := range ds {
+		if _, seen := held[d.group]; !seen {
+			groups = append(groups, d.group)
+			held[d.group] = nil
+		}
+		if lq.fifo && blocked[d.group] {
+			held[d.group] = append(held[d.group], d)
+			continue
+		}
+		err, ok := failed[d.msg.ID]
+		if !ok {
+			continue
+		}
+		delay, retry := q.settle(hctx.Logger.With("message_id", d.msg.ID), d, err)
+		if !lq.fifo {
+			if retry {
+				lq.redeliver(delay, d)
+				requeued++
+			}
+			continue
+		}
+		blocked[d.group] = true
+		if retry {
+			held[d.group] = append(held[d.group], d)
+			delays[d.group] = delay
+		}
+	}
+	for _, group := range groups {
+		if len(held[group]) > 0 {
+			lq.redeliver(delays[group], held[group]...)
+			requeued += len(held[group])
+		} else {
+			lq.release(group)
+		}
+	}
+	q.track(requeued - len(ds))
+}
+
+// accepting reports ErrShuttingDown once the sender is closed, or while it drains
+// unless ctx belongs to a running handler.
+func (q *queueSender) accepting(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed || (q.draining && ctx.Value(runningHandlerKey{}) == nil) {
+		return transire.ErrShuttingDown
+	}
+	return nil
+}
+
+// track adjusts the count of unsettled messages by n.
+func (q *queueSender) track(n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.active += n
+	if q.active == 0 && q.idle != nil {
+		close(q.idle)
+		q.idle = nil
+	}
+}
+
+// Drain stops accepting new sends and waits until every accepted message, including
+// those sent by handlers during the drain, has been handled or dead-lettered, or ctx
+// is done. The sender rejects all sends afterwards.
+func (q *queueSender) Drain(ctx context.Context) error {
+	q.mu.Lock()
+	q.draining = true
+	var idle chan struct{}
+	if q.active > 0 {
+		if q.idle == nil {
+			q.idle = make(chan struct{})
+		}
+		idle = q.idle
+	}
+	q.mu.Unlock()
+
+	if idle != nil {
+		select {
+		case <-idle:
+		case <-ctx.Done():
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	if q.active > 0 {
+		return fmt.Errorf("%d queue messages not handled before the drain timeout: %w", q.active, ctx.Err())
+	}
+	return nil
+}
+
+// settle handles a failed receive and reports whether and when to redeliver. Transient
+// failures are redelivered after the queue's retry policy delay until MaxReceiveCount
+// receives, then dead-lettered; permanent failures are dead-lettered immediately. Queues
+// without a dead-letter queue drop permanent failures and redeliver the rest
+// indefinitely, as SQS does.
+func (q *queueSender) settle(logger *slog.Logger, d delivery, err error) (time.Duration, bool) {
+	cfg := q.app.QueueConfig(d.msg.Queue)
+	logger.Warn("queue handler failed", "receive", d.receives, "error", err)
+
+	permanent := transire.IsPermanent(err)
+	if permanent && cfg.MaxReceiveCount <= 0 {
+		logger.Warn("dropping message after permanent failure")
+		return 0, false
+	}
+	if permanent || (cfg.MaxReceiveCount > 0 && d.receives >= cfg.MaxReceiveCount) {
+		q.deadLetter(d.msg, err)
+		logger.Warn("message moved to dead-letter queue", "dead_letter_queue", transire.DeadLetterQueueName(d.msg.Queue))
+		return 0, false
+	}
+	return cfg.RetryPolicy.Delay(err, d.receives), true
+}
+
+func (lq *localQueue) push(d delivery) {
+	lq.mu.Lock()
+	lq.pending = append(lq.pending, d)
+	lq.mu.Unlock()
+	lq.ready.Broadcast()
+}
+
+// redeliver returns ds to the queue after delay. On FIFO queues they go back to the
+// front, ahead of later messages in their group, which stays held until then.
+func (lq *localQueue) redeliver(delay time.Duration, ds ...delivery) {
+	time.AfterFunc(delay, func() {
+		lq.mu.Lock()
+		if lq.fifo {
+			lq.pending = append(append([]delivery(nil), ds...), lq.pending...)
+			delete(lq.inFlight, ds[0].group)
+		} else {
+			lq.pending = append(lq.pending, ds...)
+		}
+		lq.mu.Unlock()
+		lq.ready.Broadcast()
+	})
+}
+
+// release lets workers take the next message of a FIFO group.
+func (lq *localQueue) release(group string) {
+	if !lq.fifo {
+		return
+	}
+	lq.mu.Lock()
+	delete(lq.inFlight, group)
+	lq.mu.Unlock()
+	lq.ready.Broadcast()
+}
+
+func (lq *localQueue) pop() delivery {
+	return lq.popBatch(1, 0)[0]
+}
+
+// popBatch blocks until at least one delivery can be taken, then waits up to window for
+// size deliveries before returning what it has.
+func (lq *localQueue) popBatch(size int, window time.Duration) []delivery {
+	lq.mu.Lock()
+	defer lq.mu.Unlock()
+	var idx []int
+	for {
+		for idx = lq.eligible(size); len(idx) == 0; idx = lq.eligible(size) {
+			lq.ready.Wait()
+		}
+		if window > 0 && len(idx) < size {
+			expired := false
+			timer := time.AfterFunc(window, func() {
+				lq.mu.Lock()
+				expired = true
+				lq.mu.Unlock()
+				lq.ready.Broadcast()
+			})
+			for !expired && len(idx) < size {
+				lq.ready.Wait()
+				idx = lq.eligible(size)
+			}
+			timer.Stop()
+		}
+		// Another worker may have drained the queue while this one waited.
+		if len(idx) > 0 {
+			break
+		}
+	}
+
+	batch := make([]delivery, 0, len(idx))
+	rest := make([]delivery, 0, len(lq.pending)-len(idx))
+	for i, d := range lq.pending {
+		if len(batch) < len(idx) && idx[len(batch)] == i {
+			batch = append(batch, d)
+			continue
+		}
+		rest = append(rest, d)
+	}
+	lq.pending = rest
+	if lq.fifo {
+		for _, d := range batch {
+			lq.inFlight[d.group] = true
+		}
+	}
+	return batch
+}
+
+// eligible returns the indexes of up to size pending deliveries that can be taken now.
+// Messages in a FIFO group another worker holds are skipped, and a batch takes one
+// message per group, so a failure holds the rest of the group before they are handled.
+func (lq *localQueue) eligible(size int) []int {
+	var idx []int
+	taken := map[string]bool{}
+	for i, d := range lq.pending {
+		if len(idx) == size {
+			break
+		}
+		if lq.fifo && (lq.inFlight[d.group] || taken[d.group]) {
+			continue
+		}
+		taken[d.group] = true
+		idx = append(idx, i)
+	}
+	return idx
+}
+
+// DeadLetter is a message that exhausted its receives on a local queue.
+type DeadLetter struct {
+	ID         string            `json:"id"`
+	Queue      string            `json:"queue"`
+	Body       string            `json:"body"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Error      string            `json:"error"`
+	FailedAt   time.Time         `json:"failedAt"`
+}
+
+func (q *queueSender) deadLetter(msg transire.Message, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.deadLetters == nil {
+		q.deadLetters = map[string][]DeadLetter{}
+	}
+	q.deadLetters[msg.Queue] = append(q.deadLetters[msg.Queue], DeadLetter{
+		ID:         msg.ID,
+		Queue:      msg.Queue,
+		Body:       string(msg.Body),
+		Attributes: msg.Attributes,
+		Error:      err.Error(),
+		FailedAt:   time.Now(),
+	})
+}
+
+// DeadLetters returns the messages dead-lettered from queue.
+func (q *queueSender) DeadLetters(queue string) []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]DeadLetter(nil), q.deadLetters[queue]...)
+}
@@ -0,0 +1,351 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	transire "github.com/transire/transire"
+)
+
+// ScheduleRun is a schedule tick fired by Advance.
+type ScheduleRun struct {
+	Schedule string    `json:"schedule"`
+	At       time.Time `json:"at"`
+	Error    string    `json:"error,omitempty"`
+	// Skipped is set when the tick was dropped because a run was in progress.
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// errRunInProgress is returned for a tick of a ConcurrencySkip schedule dropped because a
+// run was in progress.
+var errRunInProgress = errors.New("schedule run already in progress")
+
+// Environment overrides of a schedule, set by "transire run" from the local env of
+// transire.yaml: TRANSIRE_SCHEDULE_<NAME>_ENABLED, _RATE, and _CRON.
+const (
+	scheduleEnvPrefix     = "TRANSIRE_SCHEDULE_"
+	scheduleEnabledSuffix = "_ENABLED"
+	scheduleRateSuffix    = "_RATE"
+	scheduleCronSuffix    = "_CRON"
+)
+
+// scheduler fires the app's schedules on a Clock. Schedule time is the clock's time plus
+// an offset that Advance grows when the clock cannot be moved.
+type scheduler struct {
+	app   *transire.App
+	clock Clock
+
+	mu     sync.Mutex
+	offset time.Duration
+
+	// entries are sorted by name.
+	entries []*scheduleEntry
+	// timers, when set, fire on the schedule time alongside the entries.
+	timers *timerStore
+	wg     sync.WaitGroup
+}
+
+type scheduleEntry struct {
+	name    string
+	sched   transire.Schedule
+	handler transire.ScheduleHandler
+
+	// mu is held while the entry's ticks are claimed, so Advance and the entry's loop
+	// never fire the same tick twice.
+	mu   sync.Mutex
+	next time.Time
+	// due is when the loop fires next: next plus the run's jitter.
+	due time.Time
+	// wake interrupts the loop's wait when Advance moves next.
+	wake chan struct{}
+	// slot is held by the run in progress of an exclusive schedule; nil when runs may
+	// overlap.
+	slot chan struct{}
+}
+
+// newScheduler prepares the app's schedules, applying their environment overrides and
+// skipping those that are disabled or can never fire.
+func newScheduler(app *transire.App, clock Clock) *scheduler {
+	s := &scheduler{app: app, clock: clock}
+	logger := app.NewContext(context.Background()).Logger
+	now := clock.Now()
+	for name, sched := range app.Schedules() {
+		handler, ok := app.ScheduleHandler(name)
+		if !ok {
+			logger.Warn("schedule has no handler; skipping", "schedule", name)
+			continue
+		}
+		sched, enabled, err := overrideSchedule(sched)
+		if err != nil {
+			logger.Warn("ignoring schedule override", "schedule", name, "error", err)
+		}
+		if !enabled {
+			logger.Info("schedule disabled in this environment; skipping", "schedule", name)
+			continue
+		}
+		if sched.Cron == "" && sched.Every <= 0 {
+			logger.Warn("schedule has non-positive interval; skipping", "schedule", name)
+			continue
+		}
+		next := sched.Next(now)
+		if next.IsZero() {
+			logger.Warn("schedule cron never fires; skipping", "schedule", name, "cron", sched.Cron)
+			continue
+		}
+		e := &scheduleEntry{
+			name:    name,
+			sched:   sched,
+			handler: handler,
+			wake:    make(chan struct{}, 1),
+		}
+		s.setNext(e, next)
+		if sched.Concurrency.Exclusive() {
+			e.slot = make(chan struct{}, 1)
+		}
+		s.entries = append(s.entries, e)
+	}
+	sort.Slice(s.entries, func(i, j int) bool { return s.entries[i].name < s.entries[j].name })
+	return s
+}
+
+// overrideSchedule applies the environment overrides of sched and reports whether it is
+// enabled. An invalid override is returned as an error alongside the unchanged schedule.
+func overrideSchedule(sched transire.Schedule) (transire.Schedule, bool, error) {
+	key := scheduleEnvPrefix + strings.ToUpper(strings.ReplaceAll(sched.Name, "-", "_"))
+	if raw := os.Getenv(key + scheduleEnabledSuffix); raw != "" {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return sched, true, fmt.Errorf("%s: %w", key+scheduleEnabledSuffix, err)
+		}
+		if !enabled {
+			return sched, false, nil
+		}
+	}
+	if raw := os.Getenv(key + scheduleCronSuffix); raw != "" {
+		rescheduled, err := sched.Reschedule(0, raw)
+		if err != nil {
+			return sched, true, err
+		}
+		return rescheduled, true, nil
+	}
+	if raw := os.Getenv(key + scheduleRateSuffix); raw != "" {
+		every, err := time.ParseDuration(raw)
+		if err != nil || every <= 0 {
+			return sched, true, fmt.Errorf("%s: invalid rate %q", key+scheduleRateSuffix, raw)
+		}
+		rescheduled, err := sched.Reschedule(every, "")
+		return rescheduled, true, err
+	}
+	return sched, true, nil
+}
+
+// setNext moves e to the tick at, drawing the run's jitter.
+func (s *scheduler) setNext(e *scheduleEntry, at time.Time) {
+	e.next, e.due = at, at
+	if !at.IsZero() && e.sched.Jitter > 0 {
+		e.due = at.Add(rand.N(e.sched.Jitter))
+	}
+}
+
+// now returns the schedule time.
+func (s *scheduler) now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clock.Now().Add(s.offset)
+}
+
+// start runs each schedule until ctx is done. Wait returns once every schedule loop has
+// stopped and its in-flight run has returned.
+func (s *scheduler) start(ctx context.Context) {
+	if s.timers != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.timers.loop(ctx)
+		}()
+	}
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.loop(ctx, e)
+		}()
+	}
+}
+
+// Wait blocks until the schedule loops have stopped.
+func (s *scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// loop fires e on each of its ticks, passing the tick time to the handler, after a run
+// on start when the schedule asks for one. Ticks missed while the loop was busy are fired
+// in order. Runs of a ConcurrencyQueue schedule are made by the loop itself, so its ticks
+// wait for the run in progress; other runs start in the background.
+func (s *scheduler) loop(ctx context.Context, e *scheduleEntry) {
+	if e.sched.RunOnStart {
+		s.dispatch(ctx, e, s.now())
+	}
+	for {
+		e.mu.Lock()
+		next, due := e.next, e.due
+		e.mu.Unlock()
+		if next.IsZero() {
+			return
+		}
+		wait, stop := s.clock.After(due.Sub(s.now()))
+		select {
+		case <-wait:
+		case <-e.wake:
+			stop()
+			continue
+		case <-ctx.Done():
+			stop()
+			return
+		}
+		// Claim the due ticks under e.mu, then run them without it so a queued run does
+		// not hold up Advance.
+		var ticks []time.Time
+		e.mu.Lock()
+		for !e.next.IsZero() && !e.due.After(s.now()) {
+			ticks = append(ticks, e.next)
+			s.setNext(e, e.sched.Next(e.next))
+		}
+		e.mu.Unlock()
+		for _, at := range ticks {
+			if ctx.Err() != nil {
+				return
+			}
+			s.dispatch(ctx, e, at)
+		}
+	}
+}
+
+// dispatch fires e for the tick at from its loop: inline for a ConcurrencyQueue schedule,
+// in the background otherwise.
+func (s *scheduler) dispatch(ctx context.Context, e *scheduleEntry, at time.Time) {
+	if e.sched.Concurrency == transire.ConcurrencyQueue {
+		_ = s.fire(ctx, e, at)
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		_ = s.fire(ctx, e, at)
+	}()
+}
+
+// Advance moves schedule time forward by d, firing every tick in between across all
+// schedules in time order, and returns the runs. Ticks fire at their nominal times,
+// without jitter. Timers that come due are sent in order with the ticks. A FakeClock is
+// advanced; any other clock is offset by d.
+func (s *scheduler) Advance(ctx context.Context, d time.Duration) []ScheduleRun {
+	for _, e := range s.entries {
+		e.mu.Lock()
+	}
+	defer func() {
+		for _, e := range s.entries {
+			e.mu.Unlock()
+			select {
+			case e.wake <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	target := s.now().Add(d)
+	runs := []ScheduleRun{}
+	for {
+		var due *scheduleEntry
+		for _, e := range s.entries {
+			if !e.next.IsZero() && !e.next.After(target) && (due == nil || e.next.Before(due.next)) {
+				due = e
+			}
+		}
+		if s.timers != nil {
+			if at, ok := s.timers.next(); ok && !at.After(target) && (due == nil || at.Before(due.next)) {
+				s.timers.fire(ctx, at)
+				continue
+			}
+		}
+		if due == nil {
+			break
+		}
+		at := due.next
+		s.setNext(due, due.sched.Next(at))
+		run := ScheduleRun{Schedule: due.name, At: at}
+		if err := s.fire(ctx, due, at); errors.Is(err, errRunInProgress) {
+			run.Skipped = true
+		} else if err != nil {
+			run.Error = err.Error()
+		}
+		runs = append(runs, run)
+	}
+
+	if fc, ok := s.clock.(*FakeClock); ok {
+		fc.Advance(d)
+	} else {
+		s.mu.Lock()
+		s.offset += d
+		s.mu.Unlock()
+	}
+	if s.timers != nil {
+		// The timer loop waits on the clock, which an offset does not move.
+		s.timers.notify()
+	}
+	return runs
+}
+
+// Trigger runs the named schedule once at at, outside its ticks but under its concurrency
+// policy. It reports false when there is no such schedule.
+func (s *scheduler) Trigger(ctx context.Context, name string, at time.Time) (bool, error) {
+	for _, e := range s.entries {
+		if e.name == name {
+			return true, s.fire(ctx, e, at)
+		}
+	}
+	return false, nil
+}
+
+// fire runs e for the tick at under its concurrency policy. A ConcurrencySkip tick that
+// finds a run in progress returns errRunInProgress; a ConcurrencyQueue tick waits for it.
+func (s *scheduler) fire(ctx context.Context, e *scheduleEntry, at time.Time) error {
+	if e.slot != nil {
+		if e.sched.Concurrency == transire.ConcurrencySkip {
+			select {
+			case e.slot <- struct{}{}:
+			default:
+				s.app.ScheduleContext(ctx, e.name).Log().Warn("schedule run skipped; previous run still in progress", "at", at)
+				return errRunInProgress
+			}
+		} else {
+			e.slot <- struct{}{}
+		}
+		defer func() { <-e.slot }()
+	}
+	return runSchedule(ctx, s.app, e.name, e.handler, at)
+}
+
+// runSchedule invokes a schedule handler for one run, logging and returning its error.
+// The run is not cancelled when ctx is, so a shutdown lets it finish, and its sends are
+// accepted while the queues drain.
+func runSchedule(ctx context.Context, app *transire.App, name string, handler transire.ScheduleHandler, at time.Time) error {
+	hctx := app.ScheduleContext(context.WithValue(context.WithoutCancel(ctx), runningHandlerKey{}, true), name)
+	err := handler(hctx, at)
+	if err != nil {
+		hctx.Logger.Error("schedule handler failed", "error", err)
+	}
+	return err
+}
@@ -0,0 +1,281 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package local
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	transire "github.com/transire/transire"
+)
+
+// DefaultTimersPath is where the local dispatcher keeps pending timers when neither
+// Dispatcher.TimersPath nor TRANSIRE_TIMERS_FILE is set, relative to the working directory.
+const DefaultTimersPath = ".transire/timers.json"
+
+// timerStore is the local transire.Timers. Until Run opens it, timers are kept in memory
+// on the wall clock; once open, pending timers are kept in a JSON file so that they
+// survive restarts of "transire run", and fire on the scheduler's time, so a FakeClock or
+// Advance moves them too.
+type timerStore struct {
+	app *transire.App
+
+	mu   sync.Mutex
+	path string
+	now  func() time.Time
+	// after waits on the clock now reads.
+	after   func(time.Duration) (<-chan time.Time, func())
+	pending map[string]transire.Timer
+	// wake interrupts the loop's wait when a timer is added or removed.
+	wake chan struct{}
+}
+
+func newTimerStore(app *transire.App) *timerStore {
+	return &timerStore{
+		app:     app,
+		now:     time.Now,
+		after:   realClock{}.After,
+		pending: map[string]transire.Timer{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// open moves the timers onto the schedules' time and loads the pending timers kept at
+// path, which later changes are saved to. An empty path keeps timers in memory only.
+func (t *timerStore) open(path string, schedules *scheduler) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.path, t.now, t.after = path, schedules.now, schedules.clock.After
+	if path == "" {
+		return nil
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t.save()
+	}
+	if err != nil {
+		return fmt.Errorf("read timers: %w", err)
+	}
+	var timers []transire.Timer
+	if err := json.Unmarshal(raw, &timers); err != nil {
+		return fmt.Errorf("read timers %s: %w", path, err)
+	}
+	for _, timer := range timers {
+		t.pending[timer.ID] = timer
+	}
+	return nil
+}
+
+// ScheduleAt adds a timer for a registered standard queue.
+func (t *timerStore) ScheduleAt(ctx context.Context, queue string, at time.Time, payload []byte) (string, error) {
+	if _, ok := t.app.QueueHandler(queue); !ok {
+		return "", fmt.Errorf("queue %q not registered", queue)
+	}
+	if t.app.QueueConfig(queue).FIFO {
+		return "", fmt.Errorf("transire: timers cannot target FIFO queue %s", queue)
+	}
+	timer := transire.Timer{ID: newTimerID(), Queue: queue, At: at, Payload: payload}
+	t.mu.Lock()
+	t.pending[timer.ID] = timer
+	err := t.save()
+	if err != nil {
+		delete(t.pending, timer.ID)
+	}
+	t.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	t.notify()
+	return timer.ID, nil
+}
+
+// Cancel removes a pending timer.
+func (t *timerStore) Cancel(ctx context.Context, id string) error {
+	t.mu.Lock()
+	timer, ok := t.pending[id]
+	if !ok {
+		t.mu.Unlock()
+		return transire.ErrTimerNotFound
+	}
+	delete(t.pending, id)
+	err := t.save()
+	if err != nil {
+		t.pending[id] = timer
+	}
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	t.notify()
+	return nil
+}
+
+// Now returns the timers' time.
+func (t *timerStore) Now() time.Time {
+	t.mu.Lock()
+	now := t.now
+	t.mu.Unlock()
+	return now()
+}
+
+// List returns the pending timers in delivery order.
+func (t *timerStore) List() []transire.Timer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timers := make([]transire.Timer, 0, len(t.pending))
+	for _, timer := range t.pending {
+		timers = append(timers, timer)
+	}
+	sort.Slice(timers, func(i, j int) bool {
+		if !timers[i].At.Equal(timers[j].At) {
+			return timers[i].At.Before(timers[j].At)
+		}
+		return timers[i].ID < timers[j].ID
+	})
+	return timers
+}
+
+// next returns the earliest delivery time of the pending timers.
+func (t *timerStore) next() (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timer, ok := t.earliest()
+	return timer.At, ok
+}
+
+// wait returns a channel that receives once the earliest pending timer comes due, or nil
+// when none is pending, and a func that stops the wait.
+func (t *timerStore) wait() (<-chan time.Time, func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timer, ok := t.earliest()
+	if !ok {
+		return nil, func() {}
+	}
+	return t.after(timer.At.Sub(t.now()))
+}
+
+// earliest returns the pending timer due first. t.mu must be held.
+func (t *timerStore) earliest() (transire.Timer, bool) {
+	var first transire.Timer
+	found := false
+	for _, timer := range t.pending {
+		if !found || timer.At.Before(first.At) || (timer.At.Equal(first.At) && timer.ID < first.ID) {
+			first, found = timer, true
+		}
+	}
+	return first, found
+}
+
+// loop sends the timers as they come due until ctx is done. Timers that were due while
+// the dispatcher was stopped are sent at once.
+func (t *timerStore) loop(ctx context.Context) {
+	for {
+		wait, stop := t.wait()
+		select {
+		case <-wait:
+			t.fire(ctx, t.Now())
+		case <-t.wake:
+		case <-ctx.Done():
+			stop()
+			return
+		}
+		stop()
+	}
+}
+
+// fire sends every timer due at or before until, in delivery order. Each timer is
+// removed before it is sent, so a concurrent Cancel either stops it or reports it sent. A
+// timer whose send is refused because the dispatcher is shutting down is put back for the
+// next run; one that fails otherwise is logged and dropped.
+func (t *timerStore) fire(ctx context.Context, until time.Time) {
+	logger := t.app.NewContext(ctx).Log()
+	for {
+		t.mu.Lock()
+		timer, ok := t.earliest()
+		if !ok || timer.At.After(until) {
+			t.mu.Unlock()
+			return
+		}
+		delete(t.pending, timer.ID)
+		if err := t.save(); err != nil {
+			logger.Warn("save timers", "error", err)
+		}
+		t.mu.Unlock()
+
+		err := t.app.QueueSender().Send(ctx, timer.Queue, timer.Payload)
+		if errors.Is(err, transire.ErrShuttingDown) {
+			t.mu.Lock()
+			t.pending[timer.ID] = timer
+			if err := t.save(); err != nil {
+				logger.Warn("save timers", "error", err)
+			}
+			t.mu.Unlock()
+			return
+		}
+		if err != nil {
+			logger.Error("timer send failed; dropping", "timer", timer.ID, "queue", timer.Queue, "error", err)
+		}
+	}
+}
+
+// notify wakes the loop.
+func (t *timerStore) notify() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// save writes the pending timers to the store's file, removing it when none are left.
+// t.mu must be held.
+func (t *timerStore) save() error {
+	if t.path == "" {
+		return nil
+	}
+	if len(t.pending) == 0 {
+		if err := os.Remove(t.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("save timers: %w", err)
+		}
+		return nil
+	}
+	timers := make([]transire.Timer, 0, len(t.pending))
+	for _, timer := range t.pending {
+		timers = append(timers, timer)
+	}
+	sort.Slice(timers, func(i, j int) bool { return timers[i].ID < timers[j].ID })
+	raw, err := json.MarshalIndent(timers, "", "  ")
+	if err != nil {
+		return fmt.Errorf("save timers: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return fmt.Errorf("save timers: %w", err)
+	}
+	// Write then rename, so a crash never leaves a truncated file behind.
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return fmt.Errorf("save timers: %w", err)
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		return fmt.Errorf("save timers: %w", err)
+	}
+	return nil
+}
+
+// newTimerID returns a random timer ID.
+func newTimerID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,37 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	transire "github.com/transire/transire"
+)
+
+// topicPublisher fans messages out in-process to each subscription queue of a topic.
+type topicPublisher struct {
+	app *transire.App
+}
+
+// Publish sends payload to every subscription queue of topic through the app's queue sender.
+func (p *topicPublisher) Publish(ctx context.Context, topic string, payload []byte) error {
+	subscriptions := p.app.Topics()[topic]
+	if len(subscriptions) == 0 {
+		return fmt.Errorf("topic %q has no subscribers", topic)
+	}
+	sender := p.app.QueueSender()
+	if sender == nil {
+		return fmt.Errorf("topic %q: no queue sender configured", topic)
+	}
+	var errs []error
+	for _, sub := range subscriptions {
+		if err := sender.Send(ctx, transire.SubscriptionQueueName(topic, sub), payload); err != nil {
+			errs = append(errs, fmt.Errorf("subscription %s: %w", sub, err))
+		}
+	}
+	return errors.Join(errs...)
+}
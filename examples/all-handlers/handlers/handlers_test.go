@@ -20,57 +20,32 @@
 package handlers
 
 import (
-	"context"
-	"encoding/json"
 	"net/http"
-	"net/http/httptest"
 	"testing"
 	"time"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/transire/transire"
+	"github.com/transire/transire/transiretest"
 )
 
-type queuedMessage struct {
-	queue   string
-	payload []byte
-}
-
-type recordingSender struct {
-	messages []queuedMessage
-}
-
-func (r *recordingSender) Send(ctx context.Context, queue string, payload []byte) error {
-	r.messages = append(r.messages, queuedMessage{queue: queue, payload: payload})
-	return nil
-}
-
-func TestHTTPHandlerSendsWorkQueue(t *testing.T) {
-	sender := &recordingSender{}
+func newHarness(t *testing.T) *transiretest.Harness {
 	app := transire.New()
-	app.SetQueueSender(sender)
-
 	RegisterHTTP(app)
+	RegisterQueues(app)
+	RegisterSchedules(app)
+	return transiretest.New(t, app)
+}
 
-	root := chi.NewRouter()
-	root.Use(transire.InjectContext(sender))
-	root.Mount("/", app.Router())
-
-	req := httptest.NewRequest(http.MethodGet, "/?msg=demo", nil)
-	rr := httptest.NewRecorder()
-	root.ServeHTTP(rr, req)
+func TestHTTPHandlerSendsWorkQueue(t *testing.T) {
+	h := newHarness(t)
 
+	rr := h.Request(http.MethodGet, "/?msg=demo", nil)
 	if rr.Code != http.StatusAccepted {
 		t.Fatalf("unexpected status: %d", rr.Code)
 	}
-	if len(sender.messages) != 1 {
-		t.Fatalf("expected 1 queued message, got %d", len(sender.messages))
-	}
 
 	var payload WorkPayload
-	if err := json.Unmarshal(sender.messages[0].payload, &payload); err != nil {
-		t.Fatalf("failed to decode payload: %v", err)
-	}
+	h.AssertSentJSON(WorkQueue, &payload)
 	if payload.Source != "http" {
 		t.Fatalf("expected source http, got %s", payload.Source)
 	}
@@ -80,97 +55,46 @@ func TestHTTPHandlerSendsWorkQueue(t *testing.T) {
 }
 
 func TestQueueHandlerForwardsToNotification(t *testing.T) {
-	sender := &recordingSender{}
-	app := transire.New()
-	app.SetQueueSender(sender)
-	RegisterQueues(app)
-
-	work := WorkPayload{Source: "schedule", Detail: "beat"}
-	body, err := json.Marshal(work)
-	if err != nil {
-		t.Fatalf("marshal work: %v", err)
-	}
+	h := newHarness(t)
 
-	handler := app.QueueHandlers()[WorkQueue]
-	if handler == nil {
-		t.Fatalf("work queue handler missing")
-	}
-	err = handler(transire.Context{Context: context.Background(), Queues: sender}, transire.Message{
-		Queue: WorkQueue,
-		Body:  body,
-	})
-	if err != nil {
-		t.Fatalf("handler returned error: %v", err)
-	}
-
-	if len(sender.messages) != 1 {
-		t.Fatalf("expected notification to be queued, got %d messages", len(sender.messages))
-	}
-	if sender.messages[0].queue != NotificationsQueue {
-		t.Fatalf("expected send to %s, got %s", NotificationsQueue, sender.messages[0].queue)
-	}
+	h.SendJSON(WorkQueue, WorkPayload{Source: "schedule", Detail: "beat"})
+	h.Drain()
+	h.AssertNoErrors()
 
 	var note NotificationPayload
-	if err := json.Unmarshal(sender.messages[0].payload, &note); err != nil {
-		t.Fatalf("decode notification: %v", err)
-	}
+	h.AssertSentJSON(NotificationsQueue, &note)
 	if note.Stage != "work-processed" {
 		t.Fatalf("unexpected stage: %s", note.Stage)
 	}
+	if note.Detail != "beat via schedule" {
+		t.Fatalf("unexpected detail: %s", note.Detail)
+	}
 }
 
 func TestNotificationHandlerForwardsToLogQueue(t *testing.T) {
-	sender := &recordingSender{}
-	app := transire.New()
-	app.SetQueueSender(sender)
-	RegisterQueues(app)
+	h := newHarness(t)
 
-	note := NotificationPayload{Stage: "work-processed", Detail: "done"}
-	body, err := json.Marshal(note)
-	if err != nil {
-		t.Fatalf("marshal note: %v", err)
-	}
-
-	handler := app.QueueHandlers()[NotificationsQueue]
-	if handler == nil {
-		t.Fatalf("notification queue handler missing")
-	}
-	err = handler(transire.Context{Context: context.Background(), Queues: sender}, transire.Message{
-		Queue: NotificationsQueue,
-		Body:  body,
-	})
-	if err != nil {
-		t.Fatalf("handler returned error: %v", err)
-	}
+	h.SendJSON(NotificationsQueue, NotificationPayload{Stage: "work-processed", Detail: "done"})
+	h.Drain()
+	h.AssertNoErrors()
 
-	if len(sender.messages) != 1 {
-		t.Fatalf("expected 1 message forwarded, got %d", len(sender.messages))
-	}
-	if sender.messages[0].queue != NotificationLog {
-		t.Fatalf("expected send to %s, got %s", NotificationLog, sender.messages[0].queue)
+	var note NotificationPayload
+	h.AssertSentJSON(NotificationLog, &note)
+	if note.Detail != "done" {
+		t.Fatalf("unexpected forwarded detail: %s", note.Detail)
 	}
 }
 
 func TestScheduleHandlerEnqueuesWork(t *testing.T) {
-	sender := &recordingSender{}
-	app := transire.New()
-	app.SetQueueSender(sender)
-	RegisterSchedules(app)
+	h := newHarness(t)
 
-	sched, ok := app.Schedules()["heartbeat"]
-	if !ok {
-		t.Fatalf("schedule missing")
-	}
-
-	err := sched.Handler(transire.Context{Context: context.Background(), Queues: sender}, time.Now())
-	if err != nil {
-		t.Fatalf("schedule handler error: %v", err)
-	}
+	h.Advance(time.Minute)
+	h.AssertNoErrors()
 
-	if len(sender.messages) != 1 {
-		t.Fatalf("expected work message queued, got %d", len(sender.messages))
-	}
-	if sender.messages[0].queue != WorkQueue {
-		t.Fatalf("expected send to %s, got %s", WorkQueue, sender.messages[0].queue)
+	var payload WorkPayload
+	h.AssertSentJSON(WorkQueue, &payload)
+	if payload.Source != "schedule" {
+		t.Fatalf("expected schedule source, got %s", payload.Source)
 	}
+	h.AssertSent(NotificationLog, 1)
 }
@@ -1,56 +1,32 @@
 package handlers
 
 import (
-	"context"
-	"encoding/json"
 	"net/http"
-	"net/http/httptest"
 	"testing"
 	"time"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/transire/transire"
+	"github.com/transire/transire/transiretest"
 )
 
-type queuedMessage struct {
-	queue string
-	body  []byte
-}
-
-type recordingSender struct {
-	messages []queuedMessage
-}
-
-func (r *recordingSender) Send(ctx context.Context, queue string, payload []byte) error {
-	r.messages = append(r.messages, queuedMessage{queue: queue, body: payload})
-	return nil
-}
-
-func TestHTTPHandlerEnqueuesWork(t *testing.T) {
-	sender := &recordingSender{}
+func newHarness(t *testing.T) *transiretest.Harness {
 	app := transire.New()
-	app.SetQueueSender(sender)
 	RegisterHTTP(app)
+	RegisterQueues(app)
+	RegisterSchedules(app)
+	return transiretest.New(t, app)
+}
 
-	root := chi.NewRouter()
-	root.Use(transire.InjectContext(sender))
-	root.Mount("/", app.Router())
-
-	req := httptest.NewRequest(http.MethodGet, "/?msg=demo", nil)
-	rr := httptest.NewRecorder()
-	root.ServeHTTP(rr, req)
+func TestHTTPHandlerEnqueuesWork(t *testing.T) {
+	h := newHarness(t)
 
+	rr := h.Request(http.MethodGet, "/?msg=demo", nil)
 	if rr.Code != http.StatusAccepted {
 		t.Fatalf("unexpected status %d", rr.Code)
 	}
-	if len(sender.messages) != 1 {
-		t.Fatalf("expected one queued message, got %d", len(sender.messages))
-	}
 
 	var payload WorkPayload
-	if err := json.Unmarshal(sender.messages[0].body, &payload); err != nil {
-		t.Fatalf("decode payload: %v", err)
-	}
+	h.AssertSentJSON(WorkQueue, &payload)
 	if payload.Source != "http" {
 		t.Fatalf("expected source http, got %s", payload.Source)
 	}
@@ -60,40 +36,14 @@ func TestHTTPHandlerEnqueuesWork(t *testing.T) {
 }
 
 func TestWorkHandlerForwardsSummary(t *testing.T) {
-	sender := &recordingSender{}
-	app := transire.New()
-	app.SetQueueSender(sender)
-	RegisterQueues(app)
-
-	work := WorkPayload{Source: "schedule", Detail: "beat"}
-	body, err := json.Marshal(work)
-	if err != nil {
-		t.Fatalf("marshal work payload: %v", err)
-	}
+	h := newHarness(t)
 
-	handler := app.QueueHandlers()[WorkQueue]
-	if handler == nil {
-		t.Fatalf("work handler missing")
-	}
-	err = handler(transire.Context{Context: context.Background(), Queues: sender}, transire.Message{
-		Queue: WorkQueue,
-		Body:  body,
-	})
-	if err != nil {
-		t.Fatalf("work handler error: %v", err)
-	}
-
-	if len(sender.messages) != 1 {
-		t.Fatalf("expected summary enqueue, got %d messages", len(sender.messages))
-	}
-	if sender.messages[0].queue != SummaryQueue {
-		t.Fatalf("expected send to %s, got %s", SummaryQueue, sender.messages[0].queue)
-	}
+	h.SendJSON(WorkQueue, WorkPayload{Source: "schedule", Detail: "beat"})
+	h.Drain()
+	h.AssertNoErrors()
 
 	var summary SummaryPayload
-	if err := json.Unmarshal(sender.messages[0].body, &summary); err != nil {
-		t.Fatalf("decode summary: %v", err)
-	}
+	h.AssertSentJSON(SummaryQueue, &summary)
 	if summary.Source != "schedule" {
 		t.Fatalf("expected summary source schedule, got %s", summary.Source)
 	}
@@ -106,99 +56,57 @@ func TestWorkHandlerForwardsSummary(t *testing.T) {
 }
 
 func TestSummaryHandlerForwardsLog(t *testing.T) {
-	sender := &recordingSender{}
-	app := transire.New()
-	app.SetQueueSender(sender)
-	RegisterQueues(app)
+	h := newHarness(t)
 
-	summary := SummaryPayload{Source: "http", Steps: []string{"work accepted: demo"}}
-	body, err := json.Marshal(summary)
-	if err != nil {
-		t.Fatalf("marshal summary: %v", err)
-	}
-
-	handler := app.QueueHandlers()[SummaryQueue]
-	if handler == nil {
-		t.Fatalf("summary handler missing")
-	}
-	err = handler(transire.Context{Context: context.Background(), Queues: sender}, transire.Message{
-		Queue: SummaryQueue,
-		Body:  body,
-	})
-	if err != nil {
-		t.Fatalf("summary handler error: %v", err)
-	}
-
-	if len(sender.messages) != 1 {
-		t.Fatalf("expected log enqueue, got %d messages", len(sender.messages))
-	}
-	if sender.messages[0].queue != LogQueue {
-		t.Fatalf("expected send to %s, got %s", LogQueue, sender.messages[0].queue)
-	}
+	h.SendJSON(SummaryQueue, SummaryPayload{Source: "http", Steps: []string{"work accepted: demo"}})
+	h.Drain()
+	h.AssertNoErrors()
 
 	var logPayload LogPayload
-	if err := json.Unmarshal(sender.messages[0].body, &logPayload); err != nil {
-		t.Fatalf("decode log payload: %v", err)
-	}
+	h.AssertSentJSON(LogQueue, &logPayload)
 	if logPayload.Message != "work accepted: demo -> forwarded to log" {
 		t.Fatalf("unexpected log message: %s", logPayload.Message)
 	}
 }
 
 func TestLogHandlerNoop(t *testing.T) {
-	sender := &recordingSender{}
-	app := transire.New()
-	app.SetQueueSender(sender)
-	RegisterQueues(app)
+	h := newHarness(t)
 
-	handler := app.QueueHandlers()[LogQueue]
-	if handler == nil {
-		t.Fatalf("log handler missing")
-	}
-	err := handler(transire.Context{Context: context.Background(), Queues: sender}, transire.Message{
-		Queue: LogQueue,
-		Body:  []byte(`{"message":"hi"}`),
-	})
-	if err != nil {
-		t.Fatalf("log handler returned error: %v", err)
-	}
-	if len(sender.messages) != 0 {
-		t.Fatalf("log handler should not enqueue more messages, got %d", len(sender.messages))
-	}
+	h.Send(LogQueue, []byte(`{"message":"hi"}`))
+	h.Drain()
+	h.AssertNoErrors()
+
+	h.AssertSent(LogQueue, 1)
+	h.AssertSent(WorkQueue, 0)
+	h.AssertSent(SummaryQueue, 0)
 }
 
 func TestScheduleHandlerEnqueuesWork(t *testing.T) {
-	sender := &recordingSender{}
-	app := transire.New()
-	app.SetQueueSender(sender)
-	RegisterSchedules(app)
-
-	sched, ok := app.Schedules()["heartbeat"]
-	if !ok {
-		t.Fatalf("schedule heartbeat missing")
-	}
-	now := time.Now()
+	h := newHarness(t)
 
-	err := sched.Handler(transire.Context{Context: context.Background(), Queues: sender}, now)
-	if err != nil {
-		t.Fatalf("schedule handler error: %v", err)
-	}
-
-	if len(sender.messages) != 1 {
-		t.Fatalf("expected one queued message, got %d", len(sender.messages))
-	}
-	if sender.messages[0].queue != WorkQueue {
-		t.Fatalf("expected send to %s, got %s", WorkQueue, sender.messages[0].queue)
-	}
+	h.Advance(time.Minute)
+	h.AssertNoErrors()
 
 	var payload WorkPayload
-	if err := json.Unmarshal(sender.messages[0].body, &payload); err != nil {
-		t.Fatalf("decode work payload: %v", err)
-	}
+	h.AssertSentJSON(WorkQueue, &payload)
 	if payload.Source != "schedule" {
 		t.Fatalf("expected schedule source, got %s", payload.Source)
 	}
-	if payload.Detail == "" {
-		t.Fatalf("expected detail to be populated")
+	if payload.Detail != "heartbeat at "+h.Now().Format(time.RFC3339) {
+		t.Fatalf("unexpected detail %q", payload.Detail)
+	}
+}
+
+func TestHTTPRequestRunsWholeChain(t *testing.T) {
+	h := newHarness(t)
+
+	h.Request(http.MethodGet, "/?msg=demo", nil)
+	h.Drain()
+	h.AssertNoErrors()
+
+	var logPayload LogPayload
+	h.AssertSentJSON(LogQueue, &logPayload)
+	if logPayload.Message != "work accepted: demo -> forwarded to log" {
+		t.Fatalf("unexpected log message: %s", logPayload.Message)
 	}
 }
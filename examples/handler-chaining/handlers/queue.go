@@ -3,7 +3,6 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/transire/transire"
@@ -19,7 +18,7 @@ func RegisterQueues(app *transire.App) {
 			}
 		}
 
-		log.Printf("work queue received from %s: %s", payload.Source, payload.Detail)
+		ctx.Log().Info("work queue received", "source", payload.Source, "detail", payload.Detail)
 
 		summary := SummaryPayload{
 			Source: payload.Source,
@@ -43,7 +42,7 @@ func RegisterQueues(app *transire.App) {
 		}
 
 		payload.Steps = append(payload.Steps, "forwarded to log")
-		log.Printf("summary received for %s: %s", payload.Source, strings.Join(payload.Steps, " -> "))
+		ctx.Log().Info("summary received", "source", payload.Source, "steps", strings.Join(payload.Steps, " -> "))
 
 		logPayload := LogPayload{
 			Message: strings.Join(payload.Steps, " -> "),
@@ -61,7 +60,7 @@ func RegisterQueues(app *transire.App) {
 		if err := json.Unmarshal(msg.Body, &payload); err != nil {
 			payload = LogPayload{Message: string(msg.Body)}
 		}
-		log.Printf("log queue received: %s", payload.Message)
+		ctx.Log().Info("log queue received", "message", payload.Message)
 		return nil
 	})
 }
@@ -3,7 +3,6 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/transire/transire"
@@ -11,7 +10,7 @@ import (
 
 func RegisterSchedules(app *transire.App) {
 	app.RegisterScheduleHandler("heartbeat", time.Minute, func(ctx transire.Context, at time.Time) error {
-		log.Printf("heartbeat at %s", at.UTC())
+		ctx.Log().Info("heartbeat", "at", at.UTC())
 		payload := WorkPayload{
 			Source: "schedule",
 			Detail: fmt.Sprintf("heartbeat at %s", at.UTC().Format(time.RFC3339)),
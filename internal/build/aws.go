@@ -7,23 +7,43 @@ package build
 import (
 	"archive/zip"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/transire/transire"
 	"github.com/transire/transire/internal/config"
 	"github.com/transire/transire/internal/discover"
 )
 
 const queueEnvPrefix = "TRANSIRE_QUEUE_"
 const queueNameEnvSuffix = "_NAME"
+const deadLetterURLEnvSuffix = "_DLQ_URL"
 const scheduleEnvPrefix = "TRANSIRE_SCHEDULE_"
+const topicEnvPrefix = "TRANSIRE_TOPIC_"
+const scheduleLockTableEnv = "TRANSIRE_SCHEDULE_LOCK_TABLE"
+const timerGroupEnv = "TRANSIRE_TIMER_GROUP"
+const timerRoleEnv = "TRANSIRE_TIMER_ROLE_ARN"
 
 // BuildAWS builds the Lambda bootstrap binary and generates CDK app files.
 func BuildAWS(ctx context.Context, projectRoot string, manifest config.Manifest, layout discover.Layout) error {
+	hasExtend := fileExists(filepath.Join(projectRoot, "infra", "extend.ts"))
+	if err := validateQueues(layout, hasExtend); err != nil {
+		return err
+	}
+	if err := validateSchedules(layout); err != nil {
+		return err
+	}
+	if err := validateScheduleOverrides(manifest, layout); err != nil {
+		return err
+	}
+
 	distRoot := filepath.Join(projectRoot, "dist", "aws")
 	lambdaDir := filepath.Join(distRoot, "lambda")
 	cdkDir := filepath.Join(distRoot, "cdk")
@@ -58,7 +78,6 @@ func BuildAWS(ctx context.Context, projectRoot string, manifest config.Manifest,
 		return err
 	}
 
-	hasExtend := fileExists(filepath.Join(projectRoot, "infra", "extend.ts"))
 	if err := writeCDK(cdkDir, manifest, layout, hasExtend); err != nil {
 		return err
 	}
@@ -227,25 +246,104 @@ func libStackTS(appName string, manifest config.Manifest, layout discover.Layout
 		upper := strings.ToUpper(strings.ReplaceAll(q.Name, "-", "_"))
 		id := safeID(q.Name)
 		envVars = append(envVars, fmt.Sprintf("      \"%s%s_URL\": %s.queueUrl", queueEnvPrefix, upper, id))
-		envVars = append(envVars, fmt.Sprintf("      \"%s%s%s\": appName + \"-%s-\" + env", queueEnvPrefix, upper, queueNameEnvSuffix, q.Name))
+		nameSuffix, fifoProps := "", ""
+		if q.FIFO {
+			nameSuffix = fmt.Sprintf(" + %q", transire.FIFOSuffix)
+			fifoProps = "\n      fifo: true,"
+			if q.ContentBasedDeduplication {
+				fifoProps += "\n      contentBasedDeduplication: true,"
+			}
+		}
+		envVars = append(envVars, fmt.Sprintf("      \"%s%s%s\": appName + \"-%s-\" + env%s", queueEnvPrefix, upper, queueNameEnvSuffix, q.Name, nameSuffix))
 		// AWS requires SQS visibility timeout >= Lambda timeout for event source mappings
 		// We use 6x the Lambda timeout as recommended by AWS
 		visibilityTimeout := queueVisibilityTimeout(hasExtend)
-		queueDecls = append(queueDecls, fmt.Sprintf("    const %s = new sqs.Queue(this, \"%sQueue\", {\n      queueName: appName + \"-%s-\" + env,\n      visibilityTimeout: %s,\n    });", id, id, q.Name, visibilityTimeout))
-		queueSources = append(queueSources, fmt.Sprintf("    fn.addEventSource(new lambdaEventSources.SqsEventSource(%s));\n    %s.grantSendMessages(fn);", id, id))
+		if q.VisibilityTimeout > 0 {
+			visibilityTimeout = fmt.Sprintf("cdk.Duration.seconds(%d)", ceilSeconds(q.VisibilityTimeout))
+			if hasExtend {
+				// infra/extend.ts may set the Lambda timeout, so check it when the app is synthesized.
+				queueDecls = append(queueDecls, fmt.Sprintf("    if ((config.timeout?.toSeconds() ?? %d) > %d) {\n      throw new Error(\"queue %s: visibility timeout %ds is shorter than the Lambda timeout\");\n    }", defaultLambdaTimeoutSeconds, ceilSeconds(q.VisibilityTimeout), q.Name, ceilSeconds(q.VisibilityTimeout)))
+			}
+		}
+		redrive := ""
+		if q.MaxReceiveCount > 0 {
+			dlqName := q.Name + transire.DeadLetterSuffix
+			// A FIFO queue's dead-letter queue must be FIFO too.
+			dlqFIFO := ""
+			if q.FIFO {
+				dlqFIFO = "\n      fifo: true,"
+			}
+			queueDecls = append(queueDecls, fmt.Sprintf("    const %sDlq = new sqs.Queue(this, \"%sDeadLetterQueue\", {\n      queueName: appName + \"-%s-\" + env%s,%s\n      retentionPeriod: cdk.Duration.days(14),\n    });", id, id, dlqName, nameSuffix, dlqFIFO))
+			redrive = fmt.Sprintf("\n      deadLetterQueue: { queue: %sDlq, maxReceiveCount: %d },", id, q.MaxReceiveCount)
+			queueOutputs = append(queueOutputs, fmt.Sprintf("    new cdk.CfnOutput(this, \"%sDeadLetterQueueUrl\", { value: %sDlq.queueUrl });", id, id))
+			// Permanent failures are forwarded to the dead-letter queue by the handler.
+			envVars = append(envVars, fmt.Sprintf("      \"%s%s%s\": %sDlq.queueUrl", queueEnvPrefix, upper, deadLetterURLEnvSuffix, id))
+			queueSources = append(queueSources, fmt.Sprintf("    %sDlq.grantSendMessages(fn);", id))
+		}
+		queueDecls = append(queueDecls, fmt.Sprintf("    const %s = new sqs.Queue(this, \"%sQueue\", {\n      queueName: appName + \"-%s-\" + env%s,%s\n      visibilityTimeout: %s,%s\n    });", id, id, q.Name, nameSuffix, fifoProps, visibilityTimeout, redrive))
+		queueSources = append(queueSources, fmt.Sprintf("    fn.addEventSource(new lambdaEventSources.SqsEventSource(%s, { %s }));\n    %s.grantSendMessages(fn);", id, sqsEventSourceProps(q), id))
 		queueOutputs = append(queueOutputs, fmt.Sprintf("    new cdk.CfnOutput(this, \"%sQueueUrl\", { value: %s.queueUrl });", id, id))
 	}
 
+	if layout.Timers {
+		decls, grants, vars := timersTS(layout)
+		queueDecls = append(queueDecls, decls)
+		queueSources = append(queueSources, grants)
+		envVars = append(envVars, vars...)
+	}
+
+	for _, t := range layout.Topics {
+		upper := strings.ToUpper(strings.ReplaceAll(t.Name, "-", "_"))
+		id := safeID(t.Name) + "Topic"
+		queueDecls = append(queueDecls, fmt.Sprintf("    const %s = new sns.Topic(this, \"%s\", {\n      topicName: appName + \"-%s-\" + env,\n    });", id, id, t.Name))
+		for _, sub := range t.Subscriptions {
+			// Raw delivery hands subscribers the published payload rather than an SNS envelope.
+			queueDecls = append(queueDecls, fmt.Sprintf("    %s.addSubscription(new snsSubscriptions.SqsSubscription(%s, { rawMessageDelivery: true }));", id, safeID(transire.SubscriptionQueueName(t.Name, sub))))
+		}
+		envVars = append(envVars, fmt.Sprintf("      \"%s%s_ARN\": %s.topicArn", topicEnvPrefix, upper, id))
+		queueSources = append(queueSources, fmt.Sprintf("    %s.grantPublish(fn);", id))
+		queueOutputs = append(queueOutputs, fmt.Sprintf("    new cdk.CfnOutput(this, \"%sArn\", { value: %s.topicArn });", id, id))
+	}
+
 	var scheduleDecls []string
 	var scheduleOutputs []string
+	needsSchedulerRole := false
 	for _, s := range layout.Schedules {
-		dur := toCDKDuration(s.Every)
-		scheduleDecls = append(scheduleDecls, fmt.Sprintf("    new events.Rule(this, \"%sRule\", {\n      schedule: %s,\n      ruleName: appName + \"-%s-\" + env,\n      targets: [new targets.LambdaFunction(fn)],\n    });", safeID(s.Name), dur, s.Name))
+		variants := scheduleVariants(manifest, s)
+		if usesScheduler(s) {
+			needsSchedulerRole = true
+			scheduleDecls = append(scheduleDecls, schedulerScheduleTS(s, variants))
+		} else {
+			scheduleDecls = append(scheduleDecls, ruleTS(s, variants))
+		}
+		if s.RunOnStart {
+			scheduleDecls = append(scheduleDecls, runOnDeployTS(s, variants))
+		}
 		upper := strings.ToUpper(strings.ReplaceAll(s.Name, "-", "_"))
 		envVars = append(envVars, fmt.Sprintf("      \"%s%s%s\": appName + \"-%s-\" + env", scheduleEnvPrefix, upper, queueNameEnvSuffix, s.Name))
 		scheduleOutputs = append(scheduleOutputs, fmt.Sprintf("    new cdk.CfnOutput(this, \"%sScheduleName\", { value: appName + \"-%s-\" + env });", safeID(s.Name), s.Name))
 	}
 
+	if scheduleLocks(layout) {
+		// Exclusive schedules hold a lease item, keyed by schedule name, for each run.
+		scheduleDecls = append([]string{`    const scheduleLocks = new dynamodb.Table(this, "ScheduleLocks", {
+      tableName: appName + "-schedule-locks-" + env,
+      partitionKey: { name: "schedule", type: dynamodb.AttributeType.STRING },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+      timeToLiveAttribute: "expiresAt",
+      removalPolicy: cdk.RemovalPolicy.DESTROY,
+    });
+    scheduleLocks.grantReadWriteData(fn);`}, scheduleDecls...)
+		envVars = append(envVars, fmt.Sprintf("      \"%s\": appName + \"-schedule-locks-\" + env", scheduleLockTableEnv))
+	}
+
+	if needsSchedulerRole {
+		scheduleDecls = append([]string{`    const schedulerRole = new iam.Role(this, "SchedulerRole", {
+      assumedBy: new iam.ServicePrincipal("scheduler.amazonaws.com"),
+    });
+    fn.grantInvoke(schedulerRole);`}, scheduleDecls...)
+	}
+
 	// Add config.environment spread when extending
 	if hasExtend {
 		envVars = append(envVars, "      ...config.environment")
@@ -278,9 +376,15 @@ import * as lambda from "aws-cdk-lib/aws-lambda";
 import * as apigwv2 from "aws-cdk-lib/aws-apigatewayv2";
 import * as integrations from "aws-cdk-lib/aws-apigatewayv2-integrations";
 import * as sqs from "aws-cdk-lib/aws-sqs";
+import * as sns from "aws-cdk-lib/aws-sns";
+import * as snsSubscriptions from "aws-cdk-lib/aws-sns-subscriptions";
 import * as lambdaEventSources from "aws-cdk-lib/aws-lambda-event-sources";
 import * as events from "aws-cdk-lib/aws-events";
 import * as targets from "aws-cdk-lib/aws-events-targets";
+import * as scheduler from "aws-cdk-lib/aws-scheduler";
+import * as iam from "aws-cdk-lib/aws-iam";
+import * as dynamodb from "aws-cdk-lib/aws-dynamodb";
+import * as cr from "aws-cdk-lib/custom-resources";
 %s
 export class TransireStack extends cdk.Stack {
   constructor(scope: Construct, id: string, props?: cdk.StackProps) {
@@ -338,11 +442,14 @@ func lambdaMemory(hasExtend bool) string {
 	return "512"
 }
 
+// defaultLambdaTimeoutSeconds is the Lambda timeout unless infra/extend.ts sets one.
+const defaultLambdaTimeoutSeconds = 30
+
 func lambdaTimeout(hasExtend bool) string {
 	if hasExtend {
-		return "config.timeout ?? cdk.Duration.seconds(30)"
+		return fmt.Sprintf("config.timeout ?? cdk.Duration.seconds(%d)", defaultLambdaTimeoutSeconds)
 	}
-	return "cdk.Duration.seconds(30)"
+	return fmt.Sprintf("cdk.Duration.seconds(%d)", defaultLambdaTimeoutSeconds)
 }
 
 func lambdaConfigSpread(hasExtend bool) string {
@@ -375,6 +482,462 @@ func safeID(name string) string {
 	return name
 }
 
+func toCDKSchedule(s discover.Schedule) string {
+	if s.Cron == "" {
+		return toCDKDuration(s.Every)
+	}
+	minute, hour, day, month, weekDay, err := eventBridgeCronFields(s.Cron)
+	if err != nil {
+		// validateSchedules rejects these before any CDK is written.
+		return toCDKDuration(s.Every)
+	}
+	opts := []string{
+		fmt.Sprintf("minute: %q", minute),
+		fmt.Sprintf("hour: %q", hour),
+	}
+	if day != "?" {
+		opts = append(opts, fmt.Sprintf("day: %q", day))
+	}
+	opts = append(opts, fmt.Sprintf("month: %q", month))
+	if weekDay != "?" {
+		opts = append(opts, fmt.Sprintf("weekDay: %q", weekDay))
+	}
+	return fmt.Sprintf("events.Schedule.cron({ %s })", strings.Join(opts, ", "))
+}
+
+// envSchedule is a schedule as overridden in one environment of transire.yaml.
+type envSchedule struct {
+	env      string
+	sched    discover.Schedule
+	disabled bool
+	// rescheduled is set when the override changes the rate or cron expression.
+	rescheduled bool
+}
+
+// scheduleVariants returns the environments that override s, sorted by name. The local
+// env only applies to "transire run".
+func scheduleVariants(manifest config.Manifest, s discover.Schedule) []envSchedule {
+	var envs []string
+	for env, cfg := range manifest.Environments {
+		if _, ok := cfg.Schedules[s.Name]; ok && env != "local" {
+			envs = append(envs, env)
+		}
+	}
+	sort.Strings(envs)
+	variants := make([]envSchedule, 0, len(envs))
+	for _, env := range envs {
+		o := manifest.Environments[env].Schedules[s.Name]
+		v := envSchedule{env: env, sched: s, disabled: o.Disabled()}
+		if o.Cron != "" {
+			v.sched.Cron, v.sched.Every, v.rescheduled = o.Cron, 0, true
+		} else if o.Rate != "" {
+			every, _ := config.ParseDuration(o.Rate)
+			v.sched.Cron, v.sched.Every, v.rescheduled = "", every, true
+		}
+		variants = append(variants, v)
+	}
+	return variants
+}
+
+// envSwitch renders an expression that picks the value of the deployed env among the
+// rescheduled variants, falling back to the value of the registered schedule.
+func envSwitch(s discover.Schedule, variants []envSchedule, render func(discover.Schedule) string) string {
+	expr := render(s)
+	for i := len(variants) - 1; i >= 0; i-- {
+		if variants[i].rescheduled {
+			expr = fmt.Sprintf("env === %q ? %s : %s", variants[i].env, render(variants[i].sched), expr)
+		}
+	}
+	return expr
+}
+
+// disabledEnvs renders a TypeScript array of the envs that disable the schedule, or ""
+// when none do.
+func disabledEnvs(variants []envSchedule) string {
+	var envs []string
+	for _, v := range variants {
+		if v.disabled {
+			envs = append(envs, strconv.Quote(v.env))
+		}
+	}
+	if len(envs) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(envs, ", ") + "]"
+}
+
+// timersTS renders the resources behind ScheduleAt: a schedule group that the Lambda
+// creates one-time schedules in, and the role those schedules assume to send to the
+// standard queues. It returns the declarations, the Lambda's grants, and its environment.
+func timersTS(layout discover.Layout) (string, string, []string) {
+	decls := []string{`    const timerGroup = new scheduler.CfnScheduleGroup(this, "TimerGroup", {
+      name: appName + "-timers-" + env,
+    });
+    const timerRole = new iam.Role(this, "TimerRole", {
+      assumedBy: new iam.ServicePrincipal("scheduler.amazonaws.com"),
+    });`}
+	vars := []string{
+		fmt.Sprintf("      \"%s\": timerGroup.ref", timerGroupEnv),
+		fmt.Sprintf("      \"%s\": timerRole.roleArn", timerRoleEnv),
+	}
+	for _, q := range layout.Queues {
+		if q.FIFO {
+			continue
+		}
+		upper := strings.ToUpper(strings.ReplaceAll(q.Name, "-", "_"))
+		decls = append(decls, fmt.Sprintf("    %s.grantSendMessages(timerRole);", safeID(q.Name)))
+		vars = append(vars, fmt.Sprintf("      \"%s%s_ARN\": %s.queueArn", queueEnvPrefix, upper, safeID(q.Name)))
+	}
+	grants := `    fn.addToRolePolicy(new iam.PolicyStatement({
+      actions: ["scheduler:CreateSchedule", "scheduler:DeleteSchedule"],
+      resources: [this.formatArn({ service: "scheduler", resource: "schedule", resourceName: appName + "-timers-" + env + "/*" })],
+    }));
+    timerRole.grantPassRole(fn.grantPrincipal);`
+	return strings.Join(decls, "\n"), grants, vars
+}
+
+// usesScheduler reports whether s needs EventBridge Scheduler: EventBridge rules only
+// evaluate cron in UTC and have no flexible time window for jitter.
+func usesScheduler(s discover.Schedule) bool {
+	return (s.Cron != "" && !isUTC(s.Timezone)) || s.Jitter > 0
+}
+
+// ruleTS renders an EventBridge rule for s.
+func ruleTS(s discover.Schedule, variants []envSchedule) string {
+	enabled := ""
+	if envs := disabledEnvs(variants); envs != "" {
+		enabled = fmt.Sprintf("\n      enabled: !%s.includes(env),", envs)
+	}
+	target := "new targets.LambdaFunction(fn)"
+	if len(s.Metadata) > 0 {
+		// A custom input replaces the scheduled event, so it carries the fields the AWS
+		// dispatcher resolves the schedule from.
+		target = fmt.Sprintf(`new targets.LambdaFunction(fn, {
+          event: events.RuleTargetInput.fromObject({
+            source: "transire.schedule",
+            resources: [appName + "-%s-" + env],
+            time: events.EventField.time,
+            detail: { metadata: %s },
+          }),
+        })`, s.Name, metadataTS(s))
+	}
+	return fmt.Sprintf("    new events.Rule(this, \"%sRule\", {\n      schedule: %s,\n      ruleName: appName + \"-%s-\" + env,%s\n      targets: [%s],\n    });", safeID(s.Name), envSwitch(s, variants, toCDKSchedule), s.Name, enabled, target)
+}
+
+// metadataTS renders the metadata of s as a TypeScript object literal.
+func metadataTS(s discover.Schedule) string {
+	raw, _ := json.Marshal(s.Metadata)
+	return string(raw)
+}
+
+// scheduleDetailTS renders the detail field of the inputs sent for s, or "" when s has
+// no metadata.
+func scheduleDetailTS(s discover.Schedule, indent string) string {
+	if len(s.Metadata) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n%sdetail: { metadata: %s },", indent, metadataTS(s))
+}
+
+// schedulerScheduleTS renders an EventBridge Scheduler schedule for a zoned cron or a
+// jittered schedule. The input mimics a scheduled event so the AWS dispatcher resolves it
+// like a rule.
+func schedulerScheduleTS(s discover.Schedule, variants []envSchedule) string {
+	tz := s.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	window := `{ mode: "OFF" }`
+	if s.Jitter > 0 {
+		window = fmt.Sprintf(`{ mode: "FLEXIBLE", maximumWindowInMinutes: %d }`, jitterMinutes(s.Jitter))
+	}
+	state := ""
+	if envs := disabledEnvs(variants); envs != "" {
+		state = fmt.Sprintf("\n      state: %s.includes(env) ? \"DISABLED\" : \"ENABLED\",", envs)
+	}
+	detail := ""
+	if len(s.Metadata) > 0 {
+		detail = fmt.Sprintf("\n          detail: { metadata: %s },", metadataTS(s))
+	}
+	return fmt.Sprintf(`    new scheduler.CfnSchedule(this, "%sSchedule", {
+      name: appName + "-%s-" + env,
+      scheduleExpression: %s,
+      scheduleExpressionTimezone: %q,
+      flexibleTimeWindow: %s,%s
+      target: {
+        arn: fn.functionArn,
+        roleArn: schedulerRole.roleArn,
+        input: JSON.stringify({
+          source: "transire.scheduler",
+          resources: ["<aws.scheduler.schedule-arn>"],
+          time: "<aws.scheduler.scheduled-time>",%s
+        }),
+      },
+    });`, safeID(s.Name), s.Name, envSwitch(s, variants, schedulerExpression), tz, window, state, detail)
+}
+
+// runOnDeployTS renders a custom resource that invokes the schedule once after every
+// deploy to an env that has it enabled.
+func runOnDeployTS(s discover.Schedule, variants []envSchedule) string {
+	detail := ""
+	if len(s.Metadata) > 0 {
+		detail = fmt.Sprintf(", detail: { metadata: %s }", metadataTS(s))
+	}
+	decl := fmt.Sprintf(`new cr.AwsCustomResource(this, "%sRunOnDeploy", {
+      onUpdate: {
+        service: "Lambda",
+        action: "invoke",
+        parameters: {
+          FunctionName: fn.functionName,
+          InvocationType: "Event",
+          Payload: JSON.stringify({ source: "transire.deploy", resources: [appName + "-%s-" + env]%s }),
+        },
+        // A new ID on every synth makes each deploy invoke it again.
+        physicalResourceId: cr.PhysicalResourceId.of(Date.now().toString()),
+      },
+      policy: cr.AwsCustomResourcePolicy.fromStatements([
+        new iam.PolicyStatement({ actions: ["lambda:InvokeFunction"], resources: [fn.functionArn] }),
+      ]),
+    });`, safeID(s.Name), s.Name, detail)
+	envs := disabledEnvs(variants)
+	if envs == "" {
+		return "    " + decl
+	}
+	decl = strings.ReplaceAll(decl, "\n", "\n  ")
+	return fmt.Sprintf("    if (!%s.includes(env)) {\n      %s\n    }", envs, decl)
+}
+
+// schedulerExpression renders s as an EventBridge Scheduler expression string.
+func schedulerExpression(s discover.Schedule) string {
+	if s.Cron == "" {
+		return strconv.Quote(schedulerRate(s.Every))
+	}
+	minute, hour, day, month, weekDay, _ := eventBridgeCronFields(s.Cron)
+	return strconv.Quote(fmt.Sprintf("cron(%s %s %s %s %s *)", minute, hour, day, month, weekDay))
+}
+
+// schedulerRate renders a rate expression in the largest whole unit; validateSchedules
+// rejects rates that are not whole minutes.
+func schedulerRate(every time.Duration) string {
+	value, unit := int64(every/time.Minute), "minute"
+	switch {
+	case every <= 0:
+		value = 1
+	case every%(24*time.Hour) == 0:
+		value, unit = int64(every/(24*time.Hour)), "day"
+	case every%time.Hour == 0:
+		value, unit = int64(every/time.Hour), "hour"
+	}
+	if value != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("rate(%d %s)", value, unit)
+}
+
+// jitterMinutes rounds a jitter up to the minutes of a flexible time window.
+func jitterMinutes(jitter time.Duration) int64 {
+	return int64((jitter + time.Minute - 1) / time.Minute)
+}
+
+// scheduleLocks reports whether any schedule needs the lease table to keep its runs
+// from overlapping.
+func scheduleLocks(layout discover.Layout) bool {
+	for _, s := range layout.Schedules {
+		if transire.Concurrency(s.Concurrency).Exclusive() {
+			return true
+		}
+	}
+	return false
+}
+
+func isUTC(timezone string) bool {
+	switch timezone {
+	case "", "UTC", "Etc/UTC":
+		return true
+	default:
+		return false
+	}
+}
+
+// eventBridgeCronFields converts a five-field cron expression into EventBridge cron fields.
+// EventBridge requires exactly one of day-of-month and day-of-week to be "?", and numbers
+// days of the week from 1 (Sunday), so numeric weekdays are rewritten as names.
+func eventBridgeCronFields(expr string) (minute, hour, day, month, weekDay string, err error) {
+	if err := transire.ValidateCron(expr); err != nil {
+		return "", "", "", "", "", err
+	}
+	fields := strings.Fields(expr)
+	minute, hour, day, month, weekDay = fields[0], fields[1], fields[2], fields[3], fields[4]
+	if weekDay == "*" || weekDay == "?" {
+		weekDay = "?"
+		if day == "?" {
+			day = "*"
+		}
+		return minute, hour, day, month, weekDay, nil
+	}
+	day = "?"
+	weekDay, err = eventBridgeWeekDays(weekDay)
+	return minute, hour, day, month, weekDay, err
+}
+
+var weekDayNames = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT", "SUN"}
+
+func eventBridgeWeekDays(field string) (string, error) {
+	var parts []string
+	for _, part := range strings.Split(field, ",") {
+		if strings.Contains(part, "/") {
+			return "", fmt.Errorf("cron day-of-week step %q is not supported on AWS", part)
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		lo := weekDayName(bounds[0])
+		if len(bounds) == 1 {
+			parts = append(parts, lo)
+			continue
+		}
+		hi := weekDayName(bounds[1])
+		if bounds[1] == "7" {
+			// 7 is Sunday at the end of the week; EventBridge ranges stop at SAT.
+			if lo == "SUN" {
+				parts = append(parts, "SUN-SAT")
+			} else {
+				parts = append(parts, lo+"-SAT", "SUN")
+			}
+			continue
+		}
+		parts = append(parts, lo+"-"+hi)
+	}
+	return strings.Join(parts, ","), nil
+}
+
+func weekDayName(raw string) string {
+	if n, err := strconv.Atoi(raw); err == nil && n >= 0 && n < len(weekDayNames) {
+		return weekDayNames[n]
+	}
+	return strings.ToUpper(raw)
+}
+
+// sqsEventSourceProps renders the event source mapping settings for a queue.
+func sqsEventSourceProps(q discover.Queue) string {
+	props := []string{"reportBatchItemFailures: true"}
+	if q.BatchSize > 0 {
+		props = append(props, fmt.Sprintf("batchSize: %d", q.BatchSize))
+	}
+	if q.BatchWindow > 0 {
+		props = append(props, fmt.Sprintf("maxBatchingWindow: cdk.Duration.seconds(%d)", ceilSeconds(q.BatchWindow)))
+	}
+	if q.MaxConcurrency > 0 {
+		props = append(props, fmt.Sprintf("maxConcurrency: %d", q.MaxConcurrency))
+	}
+	return strings.Join(props, ", ")
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// validateQueues rejects consumer settings outside the SQS event source limits, and
+// visibility timeouts shorter than the Lambda timeout. hasExtend reports whether
+// infra/extend.ts may set that timeout.
+func validateQueues(layout discover.Layout, hasExtend bool) error {
+	for _, q := range layout.Queues {
+		if q.BatchSize < 0 || q.BatchSize > 10000 {
+			return fmt.Errorf("queue %s: batch size %d outside [1, 10000]", q.Name, q.BatchSize)
+		}
+		if q.BatchSize > 10 && q.BatchWindow <= 0 {
+			return fmt.Errorf("queue %s: batch size %d requires a batch window", q.Name, q.BatchSize)
+		}
+		if q.BatchWindow < 0 || q.BatchWindow > 5*time.Minute {
+			return fmt.Errorf("queue %s: batch window %s outside [0, 5m]", q.Name, q.BatchWindow)
+		}
+		if q.MaxConcurrency != 0 && (q.MaxConcurrency < 2 || q.MaxConcurrency > 1000) {
+			return fmt.Errorf("queue %s: max concurrency %d outside [2, 1000]", q.Name, q.MaxConcurrency)
+		}
+		if q.FIFO && q.BatchWindow > 0 {
+			return fmt.Errorf("queue %s: FIFO queues do not support a batch window", q.Name)
+		}
+		if q.FIFO && q.BatchSize > 10 {
+			return fmt.Errorf("queue %s: FIFO queues accept a batch size of at most 10", q.Name)
+		}
+		if q.Topic != "" && q.FIFO {
+			return fmt.Errorf("queue %s: topic subscriptions cannot be FIFO queues", q.Name)
+		}
+		if q.VisibilityTimeout < 0 || q.VisibilityTimeout > 12*time.Hour {
+			return fmt.Errorf("queue %s: visibility timeout %s outside [0, 12h]", q.Name, q.VisibilityTimeout)
+		}
+		// With infra/extend.ts the Lambda timeout is only known when the CDK app runs,
+		// which checks it instead.
+		if !hasExtend && q.VisibilityTimeout > 0 && ceilSeconds(q.VisibilityTimeout) < defaultLambdaTimeoutSeconds {
+			return fmt.Errorf("queue %s: visibility timeout %s is shorter than the %ds Lambda timeout", q.Name, q.VisibilityTimeout, defaultLambdaTimeoutSeconds)
+		}
+	}
+	return nil
+}
+
+// maxScheduleMetadata bounds the encoded metadata of a schedule so that its EventBridge
+// target input, with the fields around it, stays under the 8KB limit.
+const maxScheduleMetadata = 7 * 1024
+
+// validateSchedules rejects schedules that cannot be rendered for AWS.
+func validateSchedules(layout discover.Layout) error {
+	for _, s := range layout.Schedules {
+		if s.Jitter < 0 || jitterMinutes(s.Jitter) > 1440 {
+			return fmt.Errorf("schedule %s: jitter %s outside [0, 24h]", s.Name, s.Jitter)
+		}
+		if usesScheduler(s) && s.Cron == "" && s.Every%time.Minute != 0 {
+			return fmt.Errorf("schedule %s: rate %s must be whole minutes on EventBridge Scheduler", s.Name, s.Every)
+		}
+		switch transire.Concurrency(s.Concurrency) {
+		case "", transire.ConcurrencyAllow, transire.ConcurrencySkip, transire.ConcurrencyQueue:
+		default:
+			return fmt.Errorf("schedule %s: unknown concurrency policy %q", s.Name, s.Concurrency)
+		}
+		if n := len(metadataTS(s)); n > maxScheduleMetadata {
+			return fmt.Errorf("schedule %s: metadata of %d bytes exceeds the %d byte target input limit", s.Name, n, maxScheduleMetadata)
+		}
+		if s.Cron == "" {
+			continue
+		}
+		if _, _, _, _, _, err := eventBridgeCronFields(s.Cron); err != nil {
+			return fmt.Errorf("schedule %s: %w", s.Name, err)
+		}
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("schedule %s: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateScheduleOverrides rejects transire.yaml overrides of unknown schedules and
+// overridden schedules that cannot be rendered for AWS.
+func validateScheduleOverrides(manifest config.Manifest, layout discover.Layout) error {
+	known := map[string]bool{}
+	for _, s := range layout.Schedules {
+		known[s.Name] = true
+	}
+	for env, cfg := range manifest.Environments {
+		for name, o := range cfg.Schedules {
+			if !known[name] {
+				return fmt.Errorf("envs.%s.schedules.%s: schedule not discovered in project", env, name)
+			}
+			if err := o.Validate(); err != nil {
+				return fmt.Errorf("envs.%s.schedules.%s: %w", env, name, err)
+			}
+		}
+	}
+	for _, s := range layout.Schedules {
+		for _, v := range scheduleVariants(manifest, s) {
+			if err := validateSchedules(discover.Layout{Schedules: []discover.Schedule{v.sched}}); err != nil {
+				return fmt.Errorf("envs.%s: %w", v.env, err)
+			}
+			// The registered schedule decides whether the variant renders through Scheduler.
+			if usesScheduler(s) && v.sched.Cron == "" && v.sched.Every%time.Minute != 0 {
+				return fmt.Errorf("envs.%s: schedule %s: rate %s must be whole minutes on EventBridge Scheduler", v.env, s.Name, v.sched.Every)
+			}
+		}
+	}
+	return nil
+}
+
 func toCDKDuration(dur time.Duration) string {
 	if dur <= 0 {
 		return `events.Schedule.rate(cdk.Duration.minutes(1))`
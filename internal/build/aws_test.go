@@ -30,6 +30,111 @@ func TestToCDKDuration(t *testing.T) {
 	}
 }
 
+func TestToCDKScheduleCron(t *testing.T) {
+	got := toCDKSchedule(discover.Schedule{Name: "nightly", Cron: "0 2 * * 1-5"})
+	want := `events.Schedule.cron({ minute: "0", hour: "2", month: "*", weekDay: "MON-FRI" })`
+	if got != want {
+		t.Fatalf("unexpected cron schedule:\n got %s\nwant %s", got, want)
+	}
+	got = toCDKSchedule(discover.Schedule{Name: "monthly", Cron: "30 6 1 * *"})
+	want = `events.Schedule.cron({ minute: "30", hour: "6", day: "1", month: "*" })`
+	if got != want {
+		t.Fatalf("unexpected cron schedule:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestEventBridgeWeekDays(t *testing.T) {
+	cases := map[string]string{
+		"0":       "SUN",
+		"1-5":     "MON-FRI",
+		"mon,fri": "MON,FRI",
+		"5-7":     "FRI-SAT,SUN",
+		"0-7":     "SUN-SAT",
+	}
+	for in, want := range cases {
+		got, err := eventBridgeWeekDays(in)
+		if err != nil || got != want {
+			t.Errorf("%s: expected %s, got %s (err %v)", in, want, got, err)
+		}
+	}
+	if _, err := eventBridgeWeekDays("*/2"); err == nil {
+		t.Errorf("expected error for day-of-week step")
+	}
+}
+
+func TestLibStackTSZonedCronUsesScheduler(t *testing.T) {
+	var m config.Manifest
+	layout := discover.Layout{
+		Schedules: []discover.Schedule{
+			{Name: "nightly", Cron: "0 2 * * MON-FRI", Timezone: "Europe/London"},
+			{Name: "utc-nightly", Cron: "0 2 * * *", Timezone: "UTC"},
+		},
+	}
+
+	content := libStackTS("testapp", m, layout, false)
+
+	if !strings.Contains(content, `scheduleExpression: "cron(0 2 ? * MON-FRI *)"`) {
+		t.Error("zoned cron should render an EventBridge Scheduler expression")
+	}
+	if !strings.Contains(content, `scheduleExpressionTimezone: "Europe/London"`) {
+		t.Error("zoned cron should carry its timezone")
+	}
+	if !strings.Contains(content, "fn.grantInvoke(schedulerRole)") {
+		t.Error("scheduler role should be allowed to invoke the lambda")
+	}
+	if !strings.Contains(content, `events.Schedule.cron({ minute: "0", hour: "2", day: "*", month: "*" })`) {
+		t.Error("UTC cron should render an EventBridge rule")
+	}
+}
+
+func TestValidateSchedules(t *testing.T) {
+	ok := discover.Layout{Schedules: []discover.Schedule{{Name: "a", Cron: "0 2 * * *", Timezone: "Asia/Tokyo"}, {Name: "b", Every: time.Minute}}}
+	if err := validateSchedules(ok); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	badTZ := discover.Layout{Schedules: []discover.Schedule{{Name: "a", Cron: "0 2 * * *", Timezone: "Mars/Olympus"}}}
+	if err := validateSchedules(badTZ); err == nil {
+		t.Fatalf("expected error for unknown timezone")
+	}
+	badCron := discover.Layout{Schedules: []discover.Schedule{{Name: "a", Cron: "0 2 * * */2"}}}
+	if err := validateSchedules(badCron); err == nil {
+		t.Fatalf("expected error for unsupported cron")
+	}
+	badPolicy := discover.Layout{Schedules: []discover.Schedule{{Name: "a", Every: time.Minute, Concurrency: "serial"}}}
+	if err := validateSchedules(badPolicy); err == nil {
+		t.Fatalf("expected error for unknown concurrency policy")
+	}
+	bigMetadata := discover.Layout{Schedules: []discover.Schedule{{Name: "a", Every: time.Minute, Metadata: map[string]string{"k": strings.Repeat("x", 8*1024)}}}}
+	if err := validateSchedules(bigMetadata); err == nil {
+		t.Fatalf("expected error for metadata over the input limit")
+	}
+}
+
+func TestLibStackTSScheduleLocks(t *testing.T) {
+	var m config.Manifest
+	allow := discover.Layout{Schedules: []discover.Schedule{{Name: "tick", Every: time.Minute, Concurrency: "allow"}}}
+	if content := libStackTS("testapp", m, allow, false); strings.Contains(content, "new dynamodb.Table") {
+		t.Error("overlapping schedules should not provision a lock table")
+	}
+
+	exclusive := discover.Layout{Schedules: []discover.Schedule{
+		{Name: "tick", Every: time.Minute},
+		{Name: "reconcile", Every: time.Hour, Concurrency: "skip"},
+	}}
+	content := libStackTS("testapp", m, exclusive, false)
+	for _, want := range []string{
+		`new dynamodb.Table(this, "ScheduleLocks"`,
+		`partitionKey: { name: "schedule", type: dynamodb.AttributeType.STRING }`,
+		`timeToLiveAttribute: "expiresAt"`,
+		"scheduleLocks.grantReadWriteData(fn);",
+		`"TRANSIRE_SCHEDULE_LOCK_TABLE": appName + "-schedule-locks-" + env`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+}
+
 func TestLibStackTSWithoutExtend(t *testing.T) {
 	var m config.Manifest
 	m.App.Name = "testapp"
@@ -75,6 +180,127 @@ func TestQueueVisibilityTimeout(t *testing.T) {
 	}
 }
 
+func TestQueueEventSourceReportsBatchItemFailures(t *testing.T) {
+	var m config.Manifest
+	layout := discover.Layout{Queues: []discover.Queue{{Name: "work"}}}
+
+	content := libStackTS("testapp", m, layout, false)
+	if !strings.Contains(content, "new lambdaEventSources.SqsEventSource(work, { reportBatchItemFailures: true })") {
+		t.Error("SQS event source should enable reportBatchItemFailures")
+	}
+}
+
+func TestLibStackTSDeadLetterQueue(t *testing.T) {
+	var m config.Manifest
+	layout := discover.Layout{Queues: []discover.Queue{{Name: "work", MaxReceiveCount: 5}, {Name: "plain"}}}
+
+	content := libStackTS("testapp", m, layout, false)
+	if !strings.Contains(content, `queueName: appName + "-work-dlq-" + env`) {
+		t.Error("dead-letter queue should be declared for work")
+	}
+	if !strings.Contains(content, "deadLetterQueue: { queue: workDlq, maxReceiveCount: 5 }") {
+		t.Error("work queue should carry a redrive policy")
+	}
+	if !strings.Contains(content, `"workDeadLetterQueueUrl"`) {
+		t.Error("dead-letter queue URL should be an output")
+	}
+	if !strings.Contains(content, `"TRANSIRE_QUEUE_WORK_DLQ_URL": workDlq.queueUrl`) || !strings.Contains(content, "workDlq.grantSendMessages(fn);") {
+		t.Error("function should be able to forward permanent failures to the dead-letter queue")
+	}
+	if strings.Contains(content, "plainDlq") {
+		t.Error("queues without a dead-letter option should not get one")
+	}
+}
+
+func TestLibStackTSConsumerSettings(t *testing.T) {
+	var m config.Manifest
+	layout := discover.Layout{Queues: []discover.Queue{{Name: "bulk", BatchSize: 50, BatchWindow: 1500 * time.Millisecond, MaxConcurrency: 4, VisibilityTimeout: 10 * time.Minute}}}
+
+	content := libStackTS("testapp", m, layout, false)
+	if !strings.Contains(content, "SqsEventSource(bulk, { reportBatchItemFailures: true, batchSize: 50, maxBatchingWindow: cdk.Duration.seconds(2), maxConcurrency: 4 })") {
+		t.Error("event source should carry the consumer settings")
+	}
+	if !strings.Contains(content, "visibilityTimeout: cdk.Duration.seconds(600)") {
+		t.Error("visibility timeout option should override the default")
+	}
+}
+
+func TestLibStackTSFIFOQueue(t *testing.T) {
+	var m config.Manifest
+	layout := discover.Layout{Queues: []discover.Queue{{Name: "billing", FIFO: true, ContentBasedDeduplication: true, MaxReceiveCount: 3}}}
+
+	content := libStackTS("testapp", m, layout, false)
+	if !strings.Contains(content, "queueName: appName + \"-billing-\" + env + \".fifo\",\n      fifo: true,\n      contentBasedDeduplication: true,") {
+		t.Error("FIFO queue should carry the .fifo suffix and FIFO props")
+	}
+	if !strings.Contains(content, "queueName: appName + \"-billing-dlq-\" + env + \".fifo\",\n      fifo: true,") {
+		t.Error("FIFO queue's dead-letter queue should be FIFO")
+	}
+	if !strings.Contains(content, `"TRANSIRE_QUEUE_BILLING_NAME": appName + "-billing-" + env + ".fifo"`) {
+		t.Error("queue name env var should include the .fifo suffix")
+	}
+}
+
+func TestLibStackTSTopics(t *testing.T) {
+	var m config.Manifest
+	layout := discover.Layout{
+		Queues: []discover.Queue{
+			{Name: "order-created-billing", Topic: "order-created", Subscription: "billing"},
+			{Name: "order-created-email", Topic: "order-created", Subscription: "email"},
+		},
+		Topics: []discover.Topic{{Name: "order-created", Subscriptions: []string{"billing", "email"}}},
+	}
+
+	content := libStackTS("testapp", m, layout, false)
+	for _, want := range []string{
+		`const ordercreatedTopic = new sns.Topic(this, "ordercreatedTopic", {`,
+		`topicName: appName + "-order-created-" + env,`,
+		"ordercreatedTopic.addSubscription(new snsSubscriptions.SqsSubscription(ordercreatedbilling, { rawMessageDelivery: true }));",
+		"ordercreatedTopic.addSubscription(new snsSubscriptions.SqsSubscription(ordercreatedemail, { rawMessageDelivery: true }));",
+		`"TRANSIRE_TOPIC_ORDER_CREATED_ARN": ordercreatedTopic.topicArn`,
+		"ordercreatedTopic.grantPublish(fn);",
+		`new cdk.CfnOutput(this, "ordercreatedTopicArn"`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+	if strings.Index(content, "const ordercreatedbilling = new sqs.Queue") > strings.Index(content, "ordercreatedTopic.addSubscription") {
+		t.Error("subscription queues must be declared before the topic subscribes them")
+	}
+}
+
+func TestValidateQueues(t *testing.T) {
+	ok := discover.Layout{Queues: []discover.Queue{{Name: "a"}, {Name: "b", BatchSize: 100, BatchWindow: time.Second, MaxConcurrency: 2}}}
+	if err := validateQueues(ok, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, q := range []discover.Queue{
+		{Name: "no-window", BatchSize: 11},
+		{Name: "too-big", BatchSize: 10001, BatchWindow: time.Second},
+		{Name: "long-window", BatchWindow: 6 * time.Minute},
+		{Name: "one-worker", MaxConcurrency: 1},
+		{Name: "long-visibility", VisibilityTimeout: 13 * time.Hour},
+		{Name: "short-visibility", VisibilityTimeout: 10 * time.Second},
+		{Name: "fifo-window", FIFO: true, BatchWindow: time.Second},
+		{Name: "fifo-subscription", FIFO: true, Topic: "events", Subscription: "audit"},
+		{Name: "fifo-batch", FIFO: true, BatchSize: 20, BatchWindow: time.Second},
+	} {
+		if err := validateQueues(discover.Layout{Queues: []discover.Queue{q}}, false); err == nil {
+			t.Errorf("expected error for %s", q.Name)
+		}
+	}
+	// infra/extend.ts may shorten the Lambda timeout, so the CDK app checks it instead.
+	short := discover.Layout{Queues: []discover.Queue{{Name: "short", VisibilityTimeout: 10 * time.Second}}}
+	if err := validateQueues(short, true); err != nil {
+		t.Fatalf("unexpected error with extend: %v", err)
+	}
+	content := libStackTS("demo", config.Manifest{}, short, true)
+	if !strings.Contains(content, `if ((config.timeout?.toSeconds() ?? 30) > 10) {`) || !strings.Contains(content, `queue short: visibility timeout 10s is shorter than the Lambda timeout`) {
+		t.Fatalf("expected a synth-time visibility check:\n%s", content)
+	}
+}
+
 func TestQueueVisibilityTimeoutFunction(t *testing.T) {
 	// Test the queueVisibilityTimeout helper directly
 	noExtend := queueVisibilityTimeout(false)
@@ -284,3 +510,136 @@ func main() {
 		t.Fatalf("stack outputs missing expected entries")
 	}
 }
+
+func TestLibStackTSScheduleOverrides(t *testing.T) {
+	off := false
+	var m config.Manifest
+	m.Environments = map[string]config.Environment{
+		"local":   {Schedules: map[string]config.ScheduleOverride{"heartbeat": {Enabled: &off}}},
+		"staging": {Schedules: map[string]config.ScheduleOverride{"heartbeat": {Enabled: &off}, "nightly": {Enabled: &off}}},
+		"prod":    {Schedules: map[string]config.ScheduleOverride{"heartbeat": {Rate: "1h"}, "nightly": {Cron: "0 3 * * *"}}},
+	}
+	layout := discover.Layout{Schedules: []discover.Schedule{
+		{Name: "heartbeat", Every: time.Minute},
+		{Name: "nightly", Cron: "0 2 * * *", Timezone: "Europe/London"},
+	}}
+	if err := validateScheduleOverrides(m, layout); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content := libStackTS("testapp", m, layout, false)
+	for _, want := range []string{
+		`schedule: env === "prod" ? events.Schedule.rate(cdk.Duration.hours(1)) : events.Schedule.rate(cdk.Duration.minutes(1)),`,
+		`enabled: !["staging"].includes(env),`,
+		`scheduleExpression: env === "prod" ? "cron(0 3 * * ? *)" : "cron(0 2 * * ? *)",`,
+		`state: ["staging"].includes(env) ? "DISABLED" : "ENABLED",`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+	if strings.Contains(content, `"local"`) {
+		t.Error("the local env should not be rendered")
+	}
+}
+
+func TestLibStackTSRunOnStartAndJitter(t *testing.T) {
+	off := false
+	var m config.Manifest
+	m.Environments = map[string]config.Environment{
+		"staging": {Schedules: map[string]config.ScheduleOverride{"warm": {Enabled: &off}}},
+	}
+	layout := discover.Layout{Schedules: []discover.Schedule{
+		{Name: "warm", Every: 90 * time.Minute, RunOnStart: true, Jitter: 90 * time.Second},
+	}}
+
+	content := libStackTS("testapp", m, layout, false)
+	for _, want := range []string{
+		`new scheduler.CfnSchedule(this, "warmSchedule"`,
+		`scheduleExpression: "rate(90 minutes)",`,
+		`flexibleTimeWindow: { mode: "FLEXIBLE", maximumWindowInMinutes: 2 },`,
+		"fn.grantInvoke(schedulerRole)",
+		`if (!["staging"].includes(env)) {`,
+		`new cr.AwsCustomResource(this, "warmRunOnDeploy"`,
+		`Payload: JSON.stringify({ source: "transire.deploy", resources: [appName + "-warm-" + env] }),`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+}
+
+func TestLibStackTSScheduleMetadata(t *testing.T) {
+	var m config.Manifest
+	meta := map[string]string{"team": "billing"}
+	layout := discover.Layout{Schedules: []discover.Schedule{
+		{Name: "tick", Every: time.Minute},
+		{Name: "report", Every: time.Hour, Metadata: meta},
+		{Name: "warm", Every: time.Hour, Jitter: time.Minute, RunOnStart: true, Metadata: meta},
+	}}
+
+	content := libStackTS("testapp", m, layout, false)
+	for _, want := range []string{
+		`targets: [new targets.LambdaFunction(fn)],`,
+		`event: events.RuleTargetInput.fromObject({`,
+		`resources: [appName + "-report-" + env],`,
+		`time: events.EventField.time,`,
+		`detail: { metadata: {"team":"billing"} },`,
+		`Payload: JSON.stringify({ source: "transire.deploy", resources: [appName + "-warm-" + env], detail: { metadata: {"team":"billing"} } }),`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+	if n := strings.Count(content, `detail: { metadata: {"team":"billing"} }`); n != 3 {
+		t.Errorf("expected the rule, scheduler and deploy inputs to carry metadata, got %d", n)
+	}
+}
+
+func TestValidateScheduleOverrides(t *testing.T) {
+	layout := discover.Layout{Schedules: []discover.Schedule{
+		{Name: "heartbeat", Every: time.Minute},
+		{Name: "warm", Every: time.Hour, Jitter: time.Minute},
+	}}
+	for name, o := range map[string]map[string]config.ScheduleOverride{
+		"unknown schedule": {"missing": {Rate: "1m"}},
+		"rate and cron":    {"heartbeat": {Rate: "1m", Cron: "* * * * *"}},
+		"invalid cron":     {"heartbeat": {Cron: "0 2 * * */2"}},
+		"sub-minute rate":  {"warm": {Rate: "90s"}},
+	} {
+		m := config.Manifest{Environments: map[string]config.Environment{"prod": {Schedules: o}}}
+		if err := validateScheduleOverrides(m, layout); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLibStackTSTimers(t *testing.T) {
+	var m config.Manifest
+	layout := discover.Layout{Queues: []discover.Queue{{Name: "reminders"}, {Name: "ordered", FIFO: true}}}
+	if content := libStackTS("testapp", m, layout, false); strings.Contains(content, "TimerGroup") {
+		t.Error("apps without timers should not provision a schedule group")
+	}
+
+	layout.Timers = true
+	content := libStackTS("testapp", m, layout, false)
+	for _, want := range []string{
+		`const timerGroup = new scheduler.CfnScheduleGroup(this, "TimerGroup", {`,
+		`name: appName + "-timers-" + env,`,
+		`assumedBy: new iam.ServicePrincipal("scheduler.amazonaws.com"),`,
+		"reminders.grantSendMessages(timerRole);",
+		`"TRANSIRE_QUEUE_REMINDERS_ARN": reminders.queueArn`,
+		`"TRANSIRE_TIMER_GROUP": timerGroup.ref`,
+		`"TRANSIRE_TIMER_ROLE_ARN": timerRole.roleArn`,
+		`actions: ["scheduler:CreateSchedule", "scheduler:DeleteSchedule"],`,
+		`resourceName: appName + "-timers-" + env + "/*"`,
+		"timerRole.grantPassRole(fn.grantPrincipal);",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+	if strings.Contains(content, "ordered.grantSendMessages(timerRole)") || strings.Contains(content, "TRANSIRE_QUEUE_ORDERED_ARN") {
+		t.Error("timers should not target FIFO queues")
+	}
+}
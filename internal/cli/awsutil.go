@@ -18,6 +18,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/transire/transire"
 )
 
 type cfnAPI interface {
@@ -83,19 +84,28 @@ func fetchOutputs(ctx context.Context, cfn cfnAPI, stack string) (map[string]str
 	return out, nil
 }
 
-func sendAWSQueue(ctx context.Context, sqsClient sqsAPI, outputs map[string]string, queue string, payload []byte) error {
+func sendAWSQueue(ctx context.Context, sqsClient sqsAPI, outputs map[string]string, queue string, payload []byte, opts transire.SendOptions) error {
 	url := outputs[queueOutputKey(queue)]
 	if url == "" {
 		return fmt.Errorf("queue %s URL not found in stack outputs", queue)
 	}
-	_, err := sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+	in := &sqs.SendMessageInput{
 		QueueUrl:    aws.String(url),
 		MessageBody: aws.String(string(payload)),
-	})
+	}
+	if opts.GroupID != "" {
+		in.MessageGroupId = aws.String(opts.GroupID)
+	}
+	if opts.DeduplicationID != "" {
+		in.MessageDeduplicationId = aws.String(opts.DeduplicationID)
+	}
+	_, err := sqsClient.SendMessage(ctx, in)
 	return err
 }
 
-func triggerScheduleLambda(ctx context.Context, lambdaClient lambdaAPI, outputs map[string]string, schedule string) error {
+// triggerScheduleLambda invokes the stack's Lambda with a scheduled event for schedule,
+// carrying metadata as the run's data.
+func triggerScheduleLambda(ctx context.Context, lambdaClient lambdaAPI, outputs map[string]string, schedule string, metadata map[string]string) error {
 	name := outputs[scheduleOutputKey(schedule)]
 	if name == "" {
 		return fmt.Errorf("schedule %s name not found in stack outputs", schedule)
@@ -104,6 +114,9 @@ func triggerScheduleLambda(ctx context.Context, lambdaClient lambdaAPI, outputs
 		"resources": []string{name},
 		"time":      time.Now().UTC().Format(time.RFC3339),
 	}
+	if len(metadata) > 0 {
+		payload["detail"] = map[string]any{"metadata": metadata}
+	}
 	data, err := json.Marshal(payload)
 	if err != nil {
 		return err
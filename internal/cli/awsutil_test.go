@@ -9,6 +9,8 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+
+	"github.com/transire/transire"
 )
 
 func TestQueueOutputKey(t *testing.T) {
@@ -37,14 +39,14 @@ func TestFetchOutputs(t *testing.T) {
 func TestSendAWSQueue(t *testing.T) {
 	sqs := &mockSQS{}
 	out := map[string]string{queueOutputKey("q"): "url"}
-	err := sendAWSQueue(context.Background(), sqs, out, "q", []byte("hi"))
+	err := sendAWSQueue(context.Background(), sqs, out, "q", []byte("hi"), transire.SendOptions{})
 	if err != nil {
 		t.Fatalf("send err: %v", err)
 	}
 	if sqs.LastURL != "url" || sqs.LastMessage != "hi" {
 		t.Fatalf("unexpected send inputs: %+v", sqs)
 	}
-	if err := sendAWSQueue(context.Background(), sqs, map[string]string{}, "missing", []byte("x")); err == nil {
+	if err := sendAWSQueue(context.Background(), sqs, map[string]string{}, "missing", []byte("x"), transire.SendOptions{}); err == nil {
 		t.Fatalf("expected error for missing queue url")
 	}
 }
@@ -55,7 +57,7 @@ func TestTriggerScheduleLambda(t *testing.T) {
 		scheduleOutputKey("heartbeat"): "rule-name",
 		"LambdaName":                   "my-lambda",
 	}
-	if err := triggerScheduleLambda(context.Background(), lm, out, "heartbeat"); err != nil {
+	if err := triggerScheduleLambda(context.Background(), lm, out, "heartbeat", nil); err != nil {
 		t.Fatalf("trigger err: %v", err)
 	}
 	var payload map[string]any
@@ -68,6 +70,24 @@ func TestTriggerScheduleLambda(t *testing.T) {
 	}
 }
 
+func TestTriggerScheduleLambdaSendsMetadata(t *testing.T) {
+	lm := &mockLambda{}
+	out := map[string]string{
+		scheduleOutputKey("heartbeat"): "rule-name",
+		"LambdaName":                   "my-lambda",
+	}
+	if err := triggerScheduleLambda(context.Background(), lm, out, "heartbeat", map[string]string{"reason": "backfill"}); err != nil {
+		t.Fatalf("trigger err: %v", err)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(lm.InvokedWith, &payload); err != nil {
+		t.Fatalf("payload not json: %v", err)
+	}
+	if meta := payload["detail"].(map[string]any)["metadata"].(map[string]any); meta["reason"] != "backfill" {
+		t.Fatalf("unexpected metadata: %v", meta)
+	}
+}
+
 func TestStackName(t *testing.T) {
 	if got := stackName("app"); got != "app-stack" {
 		t.Fatalf("unexpected stack name: %s", got)
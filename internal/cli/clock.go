@@ -0,0 +1,66 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newClockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clock",
+		Short: "Show the schedule time of the local run",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			baseURL, err := ensureLocalRunning(cmd.Context())
+			if err != nil {
+				return err
+			}
+			state, err := fetchLocalClock(cmd.Context(), baseURL)
+			if err != nil {
+				return err
+			}
+			kind := "wall clock"
+			if state.Fake {
+				kind = "fake clock"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s (%s)\n", state.Now.Format(time.RFC3339), kind)
+			return nil
+		},
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "advance <duration>",
+		Short: "Fast-forward the local run's schedules, firing every tick in between in order",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			by, err := time.ParseDuration(args[0])
+			if err != nil || by <= 0 {
+				return fmt.Errorf("invalid duration %q: use a positive duration such as 1h or 24h", args[0])
+			}
+			baseURL, err := ensureLocalRunning(cmd.Context())
+			if err != nil {
+				return err
+			}
+			state, err := advanceLocalClock(cmd.Context(), baseURL, by)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			for _, run := range state.Runs {
+				if run.Error != "" {
+					fmt.Fprintf(out, "%s  %s  failed: %s\n", run.At.Format(time.RFC3339), run.Schedule, run.Error)
+					continue
+				}
+				fmt.Fprintf(out, "%s  %s\n", run.At.Format(time.RFC3339), run.Schedule)
+			}
+			fmt.Fprintf(out, "fired %d schedule runs; schedule time is now %s\n", len(state.Runs), state.Now.Format(time.RFC3339))
+			return nil
+		},
+	})
+	return cmd
+}
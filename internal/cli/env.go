@@ -4,7 +4,15 @@
 
 package cli
 
-import "github.com/transire/transire/internal/config"
+import (
+	"sort"
+	"strings"
+
+	"github.com/transire/transire/internal/config"
+)
+
+// localEnv is the transire.yaml environment that applies to "transire run".
+const localEnv = "local"
 
 type envSettings struct {
 	profile string
@@ -29,3 +37,23 @@ func resolveEnv(m config.Manifest, envFlag string, profileFlag string, regionFla
 	}
 	return envSettings{profile: profile, region: region}
 }
+
+// localScheduleEnv returns the TRANSIRE_SCHEDULE_<NAME>_* variables through which the
+// local dispatcher applies the schedule overrides of the local env, sorted.
+func localScheduleEnv(m config.Manifest) []string {
+	var vars []string
+	for name, o := range m.Environments[localEnv].Schedules {
+		key := "TRANSIRE_SCHEDULE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		if o.Disabled() {
+			vars = append(vars, key+"_ENABLED=false")
+		}
+		if o.Rate != "" {
+			vars = append(vars, key+"_RATE="+o.Rate)
+		}
+		if o.Cron != "" {
+			vars = append(vars, key+"_CRON="+o.Cron)
+		}
+	}
+	sort.Strings(vars)
+	return vars
+}
@@ -5,6 +5,7 @@
 package cli
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/transire/transire/internal/config"
@@ -41,3 +42,24 @@ func TestResolveEnvDefaults(t *testing.T) {
 		t.Fatalf("defaults not applied: %+v", res)
 	}
 }
+
+func TestLocalScheduleEnv(t *testing.T) {
+	off := false
+	m := config.Manifest{
+		Environments: map[string]config.Environment{
+			"local": {Schedules: map[string]config.ScheduleOverride{
+				"nightly-report": {Enabled: &off},
+				"reconcile":      {Rate: "1m"},
+				"digest":         {Cron: "*/5 * * * *"},
+			}},
+			"prod": {Schedules: map[string]config.ScheduleOverride{
+				"reconcile": {Rate: "1h"},
+			}},
+		},
+	}
+	got := strings.Join(localScheduleEnv(m), " ")
+	want := "TRANSIRE_SCHEDULE_DIGEST_CRON=*/5 * * * * TRANSIRE_SCHEDULE_NIGHTLY_REPORT_ENABLED=false TRANSIRE_SCHEDULE_RECONCILE_RATE=1m"
+	if got != want {
+		t.Fatalf("unexpected env %q", got)
+	}
+}
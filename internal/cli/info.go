@@ -15,6 +15,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/transire/transire"
 	"github.com/transire/transire/internal/config"
 	"github.com/transire/transire/internal/discover"
 )
@@ -48,18 +49,27 @@ func newInfoCmd() *cobra.Command {
 			} else {
 				var names []string
 				for _, q := range layout.Queues {
-					names = append(names, q.Name)
+					names = append(names, describeQueue(q))
 				}
 				sort.Strings(names)
 				fmt.Fprintf(cmd.OutOrStdout(), "Queues (%d): %s\n", len(names), strings.Join(names, ", "))
 			}
 
+			if len(layout.Topics) > 0 {
+				var rows []string
+				for _, t := range layout.Topics {
+					rows = append(rows, fmt.Sprintf("%s -> %s", t.Name, strings.Join(t.Subscriptions, ", ")))
+				}
+				sort.Strings(rows)
+				fmt.Fprintf(cmd.OutOrStdout(), "Topics (%d): %s\n", len(rows), strings.Join(rows, "; "))
+			}
+
 			if len(layout.Schedules) == 0 {
 				fmt.Fprintln(cmd.OutOrStdout(), "Schedules: none discovered")
 			} else {
 				var rows []string
 				for _, s := range layout.Schedules {
-					rows = append(rows, fmt.Sprintf("%s every %s", s.Name, humanDuration(s.Every)))
+					rows = append(rows, describeSchedule(s))
 				}
 				sort.Strings(rows)
 				fmt.Fprintf(cmd.OutOrStdout(), "Schedules (%d): %s\n", len(rows), strings.Join(rows, "; "))
@@ -95,6 +105,80 @@ func newInfoCmd() *cobra.Command {
 	return cmd
 }
 
+func describeQueue(q discover.Queue) string {
+	var details []string
+	if q.Topic != "" {
+		details = append(details, fmt.Sprintf("subscribes to %s", q.Topic))
+	}
+	if q.Batch {
+		details = append(details, "batch handler")
+	}
+	if q.ContentBasedDeduplication {
+		details = append(details, "fifo, content-based dedup")
+	} else if q.FIFO {
+		details = append(details, "fifo")
+	}
+	if q.MaxReceiveCount > 0 {
+		details = append(details, fmt.Sprintf("dead-letter %s after %d receives", transire.DeadLetterQueueName(q.Name), q.MaxReceiveCount))
+	}
+	if q.BatchSize > 0 {
+		details = append(details, fmt.Sprintf("batch %d", q.BatchSize))
+	}
+	if q.BatchWindow > 0 {
+		details = append(details, fmt.Sprintf("window %s", q.BatchWindow))
+	}
+	if q.MaxConcurrency > 0 {
+		details = append(details, fmt.Sprintf("concurrency %d", q.MaxConcurrency))
+	}
+	if q.VisibilityTimeout > 0 {
+		details = append(details, fmt.Sprintf("visibility %s", q.VisibilityTimeout))
+	}
+	if q.MaxRetries > 0 {
+		details = append(details, fmt.Sprintf("retries %d", q.MaxRetries))
+	}
+	if len(details) == 0 {
+		return q.Name
+	}
+	return fmt.Sprintf("%s (%s)", q.Name, strings.Join(details, ", "))
+}
+
+func describeSchedule(s discover.Schedule) string {
+	desc := fmt.Sprintf("%s every %s", s.Name, humanDuration(s.Every))
+	if s.Cron != "" {
+		tz := s.Timezone
+		if tz == "" {
+			tz = "UTC"
+		}
+		desc = fmt.Sprintf("%s cron %q (%s)", s.Name, s.Cron, tz)
+	}
+	var details []string
+	if transire.Concurrency(s.Concurrency).Exclusive() {
+		details = append(details, "concurrency "+s.Concurrency)
+	}
+	if s.RunOnStart {
+		details = append(details, "run on start")
+	}
+	if s.Jitter > 0 {
+		details = append(details, "jitter "+humanDuration(s.Jitter))
+	}
+	if len(s.Metadata) > 0 {
+		keys := make([]string, 0, len(s.Metadata))
+		for k := range s.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = k + "=" + s.Metadata[k]
+		}
+		details = append(details, "metadata "+strings.Join(pairs, " "))
+	}
+	if len(details) > 0 {
+		desc += " [" + strings.Join(details, ", ") + "]"
+	}
+	return desc
+}
+
 func humanDuration(d time.Duration) string {
 	if d <= 0 {
 		return "0s"
@@ -3,11 +3,16 @@ package cli
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/transire/transire"
 )
 
 func resolveLocalURL(explicit string) string {
@@ -55,8 +60,18 @@ func ensureLocalRunning(ctx context.Context) (string, error) {
 	return base, nil
 }
 
-func sendLocalQueue(ctx context.Context, baseURL, queue string, payload []byte) error {
+func sendLocalQueue(ctx context.Context, baseURL, queue string, payload []byte, opts transire.SendOptions) error {
 	url := fmt.Sprintf("%s/_transire/queues/%s", resolveLocalURL(baseURL), queue)
+	query := neturl.Values{}
+	if opts.GroupID != "" {
+		query.Set("group", opts.GroupID)
+	}
+	if opts.DeduplicationID != "" {
+		query.Set("dedup", opts.DeduplicationID)
+	}
+	if len(query) > 0 {
+		url += "?" + query.Encode()
+	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
 	if err != nil {
 		return err
@@ -73,9 +88,17 @@ func sendLocalQueue(ctx context.Context, baseURL, queue string, payload []byte)
 	return nil
 }
 
-func triggerLocalSchedule(ctx context.Context, baseURL, schedule string) error {
+func triggerLocalSchedule(ctx context.Context, baseURL, schedule string, data map[string]string) error {
 	url := fmt.Sprintf("%s/_transire/schedules/%s", resolveLocalURL(baseURL), schedule)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	var body io.Reader
+	if len(data) > 0 {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
 	if err != nil {
 		return err
 	}
@@ -90,3 +113,44 @@ func triggerLocalSchedule(ctx context.Context, baseURL, schedule string) error {
 	}
 	return nil
 }
+
+// localClockState is the local dispatcher's schedule time, as served by /_transire/clock.
+type localClockState struct {
+	Now  time.Time `json:"now"`
+	Fake bool      `json:"fake"`
+	Runs []struct {
+		Schedule string    `json:"schedule"`
+		At       time.Time `json:"at"`
+		Error    string    `json:"error"`
+	} `json:"runs"`
+}
+
+func fetchLocalClock(ctx context.Context, baseURL string) (localClockState, error) {
+	return doLocalClock(ctx, http.MethodGet, fmt.Sprintf("%s/_transire/clock", resolveLocalURL(baseURL)))
+}
+
+func advanceLocalClock(ctx context.Context, baseURL string, by time.Duration) (localClockState, error) {
+	url := fmt.Sprintf("%s/_transire/clock/advance?by=%s", resolveLocalURL(baseURL), neturl.QueryEscape(by.String()))
+	return doLocalClock(ctx, http.MethodPost, url)
+}
+
+func doLocalClock(ctx context.Context, method, url string) (localClockState, error) {
+	var state localClockState
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return state, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return state, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(res.Body)
+		return state, fmt.Errorf("local clock request failed: %s", strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(res.Body).Decode(&state); err != nil {
+		return state, fmt.Errorf("decode local clock response: %w", err)
+	}
+	return state, nil
+}
@@ -2,11 +2,13 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -80,3 +82,71 @@ func TestTriggerCommandLocal(t *testing.T) {
 		t.Fatalf("trigger cmd failed: %v", err)
 	}
 }
+
+func TestTriggerCommandLocalSendsData(t *testing.T) {
+	var data map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_transire/health":
+			w.WriteHeader(http.StatusOK)
+		case "/_transire/schedules/heartbeat":
+			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+				t.Fatalf("decode data: %v", err)
+			}
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	appDir := filepath.Join(wd, "..", "..", "examples", "all-handlers-cli")
+	exitOnError(os.Chdir(appDir))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	t.Setenv("TRANSIRE_HTTP_ADDR", server.URL)
+
+	cmd := newTriggerCmd()
+	cmd.SetArgs([]string{"heartbeat", "--env", "local", "--data", "reason=backfill"})
+	cmd.SetContext(context.Background())
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("trigger cmd failed: %v", err)
+	}
+	if data["reason"] != "backfill" {
+		t.Fatalf("unexpected data %v", data)
+	}
+}
+
+func TestClockAdvanceCommandLocal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_transire/health":
+			w.WriteHeader(http.StatusOK)
+		case "/_transire/clock/advance":
+			if r.Method != http.MethodPost || r.URL.Query().Get("by") != "24h0m0s" {
+				t.Fatalf("unexpected request: %s %s", r.Method, r.URL)
+			}
+			_, _ = w.Write([]byte(`{"now":"2025-01-02T00:00:00Z","runs":[{"schedule":"heartbeat","at":"2025-01-01T12:00:00Z"}]}`))
+		default:
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("TRANSIRE_HTTP_ADDR", server.URL)
+
+	var out strings.Builder
+	cmd := newClockCmd()
+	cmd.SetArgs([]string{"advance", "24h"})
+	cmd.SetOut(&out)
+	cmd.SetContext(context.Background())
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("clock advance failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "2025-01-01T12:00:00Z  heartbeat") || !strings.Contains(out.String(), "fired 1 schedule runs") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
@@ -31,6 +31,7 @@ func newRootCmd() *cobra.Command {
 	cmd.AddCommand(newInfoCmd())
 	cmd.AddCommand(newSendCmd())
 	cmd.AddCommand(newTriggerCmd())
+	cmd.AddCommand(newClockCmd())
 	cmd.AddCommand(&cobra.Command{
 		Use:   "version",
 		Short: "Print the version",
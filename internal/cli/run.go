@@ -9,34 +9,64 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
+
+	"github.com/transire/transire/internal/config"
 )
 
 func newRunCmd() *cobra.Command {
 	var port string
 	var watch bool
+	var fakeClock string
 	cmd := &cobra.Command{
 		Use:   "run",
 		Short: "Run the current project locally",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Stop the app through its context so it can drain instead of dying with the CLI.
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			// The app inherits the CLI's environment on every (re)start.
+			if fakeClock != "" {
+				if err := os.Setenv("TRANSIRE_FAKE_CLOCK", fakeClock); err != nil {
+					return err
+				}
+			}
+			m, err := config.LoadManifest(filepath.Join(".", "transire.yaml"))
+			if err != nil {
+				return err
+			}
+			for _, kv := range localScheduleEnv(m) {
+				key, value, _ := strings.Cut(kv, "=")
+				if err := os.Setenv(key, value); err != nil {
+					return err
+				}
+			}
 			if watch {
-				return runWithWatch(cmd.Context(), port)
+				return runWithWatch(ctx, port)
 			}
-			return runOnce(cmd.Context(), port)
+			return runOnce(ctx, port)
 		},
 	}
 	cmd.Flags().StringVar(&port, "port", "", "port to serve locally (overrides PORT/TRANSIRE_PORT)")
 	cmd.Flags().BoolVar(&watch, "watch", true, "restart automatically when source files change")
+	cmd.Flags().StringVar(&fakeClock, "fake-clock", "", `run schedules on a fake clock starting at an RFC 3339 time or "now"; move it with "transire clock advance"`)
 	return cmd
 }
 
+// stopGracePeriod is how long a stopped app may shut down before it is killed. It exceeds
+// the local dispatcher's default drain timeout, which bounds its whole shutdown.
+const stopGracePeriod = 35 * time.Second
+
 func runOnce(ctx context.Context, port string) error {
 	runCmd := exec.CommandContext(ctx, "go", "run", "./cmd/app")
+	configureGracefulStop(runCmd)
 	if port != "" {
 		runCmd.Env = append(os.Environ(),
 			fmt.Sprintf("PORT=%s", port),
@@ -49,7 +79,10 @@ func runOnce(ctx context.Context, port string) error {
 	runCmd.Stdout = os.Stdout
 	runCmd.Stderr = os.Stderr
 	runCmd.Stdin = os.Stdin
-	return runCmd.Run()
+	if err := runCmd.Run(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
 }
 
 func runWithWatch(ctx context.Context, port string) error {
@@ -95,6 +128,7 @@ func runWithWatch(ctx context.Context, port string) error {
 	for {
 		runCtx, cancel := context.WithCancel(ctx)
 		cmd := exec.CommandContext(runCtx, "go", "run", "./cmd/app")
+		configureGracefulStop(cmd)
 		if port != "" {
 			cmd.Env = append(os.Environ(),
 				fmt.Sprintf("PORT=%s", port),
@@ -122,7 +156,7 @@ func runWithWatch(ctx context.Context, port string) error {
 		case <-ctx.Done():
 			cancel()
 			<-waitDone
-			return ctx.Err()
+			return nil
 		case err := <-waitDone:
 			cancel()
 			return err
@@ -15,6 +15,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/transire/transire"
 	"github.com/transire/transire/internal/config"
 	"github.com/transire/transire/internal/discover"
 )
@@ -25,6 +26,7 @@ func newSendCmd() *cobra.Command {
 	var profile string
 	var region string
 	var env string
+	var opts transire.SendOptions
 	cmd := &cobra.Command{
 		Use:   "send <queue> <message>",
 		Short: "Send an ad-hoc message to a discovered queue handler",
@@ -68,7 +70,7 @@ func newSendCmd() *cobra.Command {
 				if err != nil {
 					return err
 				}
-				return sendLocalQueue(cmd.Context(), baseURL, queue, payload)
+				return sendLocalQueue(cmd.Context(), baseURL, queue, payload, opts)
 			}
 
 			ctx := cmd.Context()
@@ -82,7 +84,7 @@ func newSendCmd() *cobra.Command {
 				return err
 			}
 			sqsClient := sqs.NewFromConfig(cfg)
-			return sendAWSQueue(ctx, sqsClient, outputs, queue, payload)
+			return sendAWSQueue(ctx, sqsClient, outputs, queue, payload, opts)
 		},
 	}
 	cmd.Flags().StringVar(&manifestPath, "manifest", "", "path to transire.yaml (defaults to ./transire.yaml)")
@@ -90,6 +92,8 @@ func newSendCmd() *cobra.Command {
 	cmd.Flags().StringVar(&profile, "profile", "transire-sandbox", "AWS profile to use")
 	cmd.Flags().StringVar(&region, "region", "", "AWS region (overrides AWS SDK defaults when set)")
 	cmd.Flags().StringVar(&env, "env", "", "environment key from transire.yaml envs section")
+	cmd.Flags().StringVar(&opts.GroupID, "group", "", "message group ID (required for FIFO queues)")
+	cmd.Flags().StringVar(&opts.DeduplicationID, "dedup-id", "", "deduplication ID for FIFO queues without content-based deduplication")
 	return cmd
 }
 
@@ -23,6 +23,7 @@ func newTriggerCmd() *cobra.Command {
 	var profile string
 	var region string
 	var env string
+	var data map[string]string
 	cmd := &cobra.Command{
 		Use:   "trigger <schedule>",
 		Short: "Trigger an ad-hoc schedule handler",
@@ -56,7 +57,7 @@ func newTriggerCmd() *cobra.Command {
 				if err != nil {
 					return err
 				}
-				return triggerLocalSchedule(cmd.Context(), baseURL, sched)
+				return triggerLocalSchedule(cmd.Context(), baseURL, sched, data)
 			}
 
 			ctx := cmd.Context()
@@ -70,13 +71,14 @@ func newTriggerCmd() *cobra.Command {
 				return err
 			}
 			lc := lambda.NewFromConfig(cfg)
-			return triggerScheduleLambda(ctx, lc, outputs, sched)
+			return triggerScheduleLambda(ctx, lc, outputs, sched, data)
 		},
 	}
 	cmd.Flags().StringVar(&manifestPath, "manifest", "", "path to transire.yaml (defaults to ./transire.yaml)")
 	cmd.Flags().StringVar(&profile, "profile", "transire-sandbox", "AWS profile to use")
 	cmd.Flags().StringVar(&region, "region", "", "AWS region (overrides AWS SDK defaults when set)")
 	cmd.Flags().StringVar(&env, "env", "", "environment key from transire.yaml envs section")
+	cmd.Flags().StringToStringVar(&data, "data", nil, "key=value data for the run, overlaying the schedule's metadata (repeatable)")
 	return cmd
 }
 
@@ -22,6 +22,39 @@ type Manifest struct {
 
 type Environment struct {
 	Profile string `yaml:"profile"`
+	// Schedules overrides registered schedules, by name, in this environment.
+	Schedules map[string]ScheduleOverride `yaml:"schedules"`
+}
+
+// ScheduleOverride changes a schedule in one environment. Rate and Cron replace the
+// schedule's interval or cron expression; at most one may be set.
+type ScheduleOverride struct {
+	Enabled *bool  `yaml:"enabled"`
+	Rate    string `yaml:"rate"`
+	Cron    string `yaml:"cron"`
+}
+
+// Disabled reports whether the override turns the schedule off.
+func (o ScheduleOverride) Disabled() bool {
+	return o.Enabled != nil && !*o.Enabled
+}
+
+// Validate rejects overrides that set both a rate and a cron expression or an invalid rate.
+func (o ScheduleOverride) Validate() error {
+	if o.Rate != "" && o.Cron != "" {
+		return fmt.Errorf("set at most one of rate and cron")
+	}
+	if o.Rate == "" {
+		return nil
+	}
+	d, err := ParseDuration(o.Rate)
+	if err != nil {
+		return err
+	}
+	if d <= 0 {
+		return fmt.Errorf("rate %q must be positive", o.Rate)
+	}
+	return nil
 }
 
 // LoadManifest reads transire.yaml from the given path. If missing, it returns defaults.
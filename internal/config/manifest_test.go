@@ -47,3 +47,49 @@ func TestParseDuration(t *testing.T) {
 		t.Fatalf("expected error for bad duration")
 	}
 }
+
+func TestLoadManifestParsesScheduleOverrides(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "transire.yaml")
+	manifest := `app:
+  name: demo
+envs:
+  staging:
+    schedules:
+      reconcile:
+        enabled: false
+  prod:
+    schedules:
+      reconcile:
+        rate: 15m
+      report:
+        cron: "0 6 * * *"
+`
+	if err := os.WriteFile(path, []byte(manifest), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	m, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o := m.Environments["staging"].Schedules["reconcile"]; !o.Disabled() {
+		t.Fatalf("expected reconcile disabled in staging, got %+v", o)
+	}
+	prod := m.Environments["prod"].Schedules
+	if prod["reconcile"].Disabled() || prod["reconcile"].Rate != "15m" || prod["report"].Cron != "0 6 * * *" {
+		t.Fatalf("unexpected prod overrides %+v", prod)
+	}
+}
+
+func TestScheduleOverrideValidate(t *testing.T) {
+	for _, o := range []ScheduleOverride{{}, {Rate: "1h"}, {Cron: "0 * * * *"}} {
+		if err := o.Validate(); err != nil {
+			t.Fatalf("unexpected error for %+v: %v", o, err)
+		}
+	}
+	for _, o := range []ScheduleOverride{{Rate: "1h", Cron: "0 * * * *"}, {Rate: "soon"}, {Rate: "-1m"}} {
+		if err := o.Validate(); err == nil {
+			t.Fatalf("expected error for %+v", o)
+		}
+	}
+}
@@ -9,24 +9,63 @@ import (
 	"go/ast"
 	"go/constant"
 	"go/token"
+	"sort"
 	"time"
 
+	"github.com/transire/transire"
 	"golang.org/x/tools/go/packages"
 )
 
-// Layout describes the queues and schedules found in user code.
+// transirePath is the import path of the transire package.
+const transirePath = "github.com/transire/transire"
+
+// Layout describes the queues, topics, and schedules found in user code.
 type Layout struct {
 	Queues    []Queue
+	Topics    []Topic
 	Schedules []Schedule
+	// Timers is set when handlers schedule one-off deliveries with ScheduleAt or
+	// ScheduleAfter.
+	Timers bool
 }
 
 type Queue struct {
 	Name string
+	// Topic and Subscription are set for queues backing a topic subscription.
+	Topic        string
+	Subscription string
+	// Batch is set when the queue has a batch handler.
+	Batch bool
+	// MaxReceiveCount enables a dead-letter queue when positive.
+	MaxReceiveCount int
+	// FIFO queues get a ".fifo" name and ordered, deduplicated delivery.
+	FIFO                      bool
+	ContentBasedDeduplication bool
+	// Consumer settings; zero values leave the platform defaults.
+	BatchSize         int
+	BatchWindow       time.Duration
+	MaxConcurrency    int
+	VisibilityTimeout time.Duration
+	MaxRetries        int
+}
+
+// Topic is a pub/sub topic and the sorted names of its subscriptions.
+type Topic struct {
+	Name          string
+	Subscriptions []string
 }
 
 type Schedule struct {
-	Name  string
-	Every time.Duration
+	Name     string
+	Every    time.Duration
+	Cron     string
+	Timezone string
+	// Concurrency is the schedule's transire.Concurrency policy; empty allows overlap.
+	Concurrency string
+	RunOnStart  bool
+	Jitter      time.Duration
+	// Metadata holds the literal pairs of the schedule's WithMetadata options.
+	Metadata map[string]string
 }
 
 // Scan walks user code to discover registered queues and schedules.
@@ -41,8 +80,9 @@ func Scan(dir string) (Layout, error) {
 		return Layout{}, err
 	}
 
-	queues := map[string]struct{}{}
+	queues := map[string]Queue{}
 	schedules := map[string]Schedule{}
+	timers := false
 
 	for _, pkg := range pkgs {
 		for _, file := range pkg.Syntax {
@@ -52,21 +92,36 @@ func Scan(dir string) (Layout, error) {
 					return true
 				}
 
-				sel, ok := call.Fun.(*ast.SelectorExpr)
-				if !ok {
-					return true
-				}
-
-				switch sel.Sel.Name {
-				case "RegisterQueueHandler":
-					if len(call.Args) < 1 {
+				switch calleeName(call.Fun) {
+				case "RegisterQueueHandler", "RegisterBatchQueueHandler":
+					if len(call.Args) < 2 {
 						return true
 					}
 					if name := stringValue(pkg, call.Args[0]); name != "" {
-						queues[name] = struct{}{}
+						q := Queue{Name: name, Batch: calleeName(call.Fun) == "RegisterBatchQueueHandler"}
+						queues[name] = queueOptions(pkg, q, call.Args[2:])
+					}
+				case "RegisterJSONQueueHandler":
+					// Package-level generic: the app is the first argument.
+					if len(call.Args) < 3 {
+						return true
+					}
+					if name := stringValue(pkg, call.Args[1]); name != "" {
+						queues[name] = queueOptions(pkg, Queue{Name: name}, call.Args[3:])
 					}
+				case "RegisterTopicSubscriber":
+					if len(call.Args) < 3 {
+						return true
+					}
+					topic := stringValue(pkg, call.Args[0])
+					sub := stringValue(pkg, call.Args[1])
+					if topic == "" || sub == "" {
+						return true
+					}
+					name := transire.SubscriptionQueueName(topic, sub)
+					queues[name] = queueOptions(pkg, Queue{Name: name, Topic: topic, Subscription: sub}, call.Args[3:])
 				case "RegisterScheduleHandler":
-					if len(call.Args) < 2 {
+					if len(call.Args) < 3 {
 						return true
 					}
 					name := stringValue(pkg, call.Args[0])
@@ -74,7 +129,22 @@ func Scan(dir string) (Layout, error) {
 						return true
 					}
 					dur := durationValue(pkg, call.Args[1])
-					schedules[name] = Schedule{Name: name, Every: dur}
+					schedules[name] = scheduleOptions(pkg, Schedule{Name: name, Every: dur}, call.Args[3:])
+				case "RegisterCronHandler":
+					if len(call.Args) < 4 {
+						return true
+					}
+					name := stringValue(pkg, call.Args[0])
+					expr := stringValue(pkg, call.Args[1])
+					if name == "" || expr == "" {
+						return true
+					}
+					sched := Schedule{Name: name, Cron: expr, Timezone: stringValue(pkg, call.Args[2])}
+					schedules[name] = scheduleOptions(pkg, sched, call.Args[4:])
+				case "ScheduleAt", "ScheduleAfter":
+					if isTransireMethod(pkg, call.Fun) {
+						timers = true
+					}
 				}
 
 				return true
@@ -82,9 +152,17 @@ func Scan(dir string) (Layout, error) {
 		}
 	}
 
-	var layout Layout
-	for name := range queues {
-		layout.Queues = append(layout.Queues, Queue{Name: name})
+	layout := Layout{Timers: timers}
+	topics := map[string][]string{}
+	for _, q := range queues {
+		layout.Queues = append(layout.Queues, q)
+		if q.Topic != "" {
+			topics[q.Topic] = append(topics[q.Topic], q.Subscription)
+		}
+	}
+	for name, subs := range topics {
+		sort.Strings(subs)
+		layout.Topics = append(layout.Topics, Topic{Name: name, Subscriptions: subs})
 	}
 	for _, sched := range schedules {
 		layout.Schedules = append(layout.Schedules, sched)
@@ -92,6 +170,112 @@ func Scan(dir string) (Layout, error) {
 	return layout, nil
 }
 
+// queueOptions applies literal queue option calls (e.g. transire.WithDeadLetterQueue(5)) to q.
+func queueOptions(pkg *packages.Package, q Queue, opts []ast.Expr) Queue {
+	for _, opt := range opts {
+		call, ok := opt.(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+		switch calleeName(call.Fun) {
+		case "WithDeadLetterQueue":
+			if len(call.Args) == 1 {
+				q.MaxReceiveCount = int(intValue(pkg, call.Args[0]))
+			}
+		case "WithFIFO":
+			q.FIFO = true
+		case "WithContentBasedDeduplication":
+			q.FIFO = true
+			q.ContentBasedDeduplication = true
+		case "WithBatchSize":
+			if len(call.Args) == 1 {
+				q.BatchSize = int(intValue(pkg, call.Args[0]))
+			}
+		case "WithBatchWindow":
+			if len(call.Args) == 1 {
+				q.BatchWindow = durationValue(pkg, call.Args[0])
+			}
+		case "WithMaxConcurrency":
+			if len(call.Args) == 1 {
+				q.MaxConcurrency = int(intValue(pkg, call.Args[0]))
+			}
+		case "WithVisibilityTimeout":
+			if len(call.Args) == 1 {
+				q.VisibilityTimeout = durationValue(pkg, call.Args[0])
+			}
+		case "WithMaxRetries":
+			if len(call.Args) == 1 {
+				q.MaxRetries = int(intValue(pkg, call.Args[0]))
+			}
+		}
+	}
+	return q
+}
+
+// scheduleOptions applies literal schedule option calls
+// (e.g. transire.WithConcurrency(transire.ConcurrencySkip)) to s.
+func scheduleOptions(pkg *packages.Package, s Schedule, opts []ast.Expr) Schedule {
+	for _, opt := range opts {
+		call, ok := opt.(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+		switch calleeName(call.Fun) {
+		case "WithConcurrency":
+			if len(call.Args) == 1 {
+				s.Concurrency = stringValue(pkg, call.Args[0])
+			}
+		case "WithRunOnStart":
+			s.RunOnStart = true
+		case "WithJitter":
+			if len(call.Args) == 1 {
+				s.Jitter = durationValue(pkg, call.Args[0])
+			}
+		case "WithMetadata":
+			if len(call.Args) != 2 {
+				continue
+			}
+			if key := stringValue(pkg, call.Args[0]); key != "" {
+				if s.Metadata == nil {
+					s.Metadata = map[string]string{}
+				}
+				s.Metadata[key] = stringValue(pkg, call.Args[1])
+			}
+		}
+	}
+	return s
+}
+
+// calleeName returns the selected function name of a call, unwrapping explicit
+// type arguments such as transire.RegisterJSONQueueHandler[Order].
+func calleeName(fun ast.Expr) string {
+	switch f := fun.(type) {
+	case *ast.IndexExpr:
+		return calleeName(f.X)
+	case *ast.IndexListExpr:
+		return calleeName(f.X)
+	case *ast.SelectorExpr:
+		return f.Sel.Name
+	default:
+		return ""
+	}
+}
+
+// isTransireMethod reports whether fun selects a method declared by the transire
+// package, such as transire.Context.ScheduleAt, rather than one of the same name on
+// another type.
+func isTransireMethod(pkg *packages.Package, fun ast.Expr) bool {
+	sel, ok := fun.(*ast.SelectorExpr)
+	if !ok || pkg.TypesInfo == nil {
+		return false
+	}
+	selection, ok := pkg.TypesInfo.Selections[sel]
+	if !ok || selection.Obj().Pkg() == nil {
+		return false
+	}
+	return selection.Obj().Pkg().Path() == transirePath
+}
+
 func parseBasicString(raw string) (string, error) {
 	if len(raw) < 2 {
 		return "", fmt.Errorf("invalid string literal: %s", raw)
@@ -110,7 +294,8 @@ func stringValue(pkg *packages.Package, expr ast.Expr) string {
 			return ""
 		}
 		return val
-	case *ast.Ident:
+	case *ast.Ident, *ast.SelectorExpr:
+		// Named constants, including those of other packages such as transire.ConcurrencySkip.
 		if pkg.TypesInfo == nil {
 			return ""
 		}
@@ -128,6 +313,10 @@ func stringValue(pkg *packages.Package, expr ast.Expr) string {
 }
 
 func durationValue(pkg *packages.Package, expr ast.Expr) time.Duration {
+	return time.Duration(intValue(pkg, expr))
+}
+
+func intValue(pkg *packages.Package, expr ast.Expr) int64 {
 	if pkg.TypesInfo == nil {
 		return 0
 	}
@@ -138,7 +327,7 @@ func durationValue(pkg *packages.Package, expr ast.Expr) time.Duration {
 	c := constant.ToInt(tv.Value)
 	if c.Kind() == constant.Int {
 		if v, ok := constant.Int64Val(c); ok {
-			return time.Duration(v)
+			return v
 		}
 	}
 	return 0
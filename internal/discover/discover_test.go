@@ -60,6 +60,182 @@ func Register(app *transire.App) {
 	}
 }
 
+func TestScanFindsJSONQueueHandlers(t *testing.T) {
+	dir := writeModule(t, `package handlers
+import "github.com/transire/transire"
+type Order struct{ ID string }
+func Register(app *transire.App) {
+	transire.RegisterJSONQueueHandler(app, "inferred", func(ctx transire.Context, msg transire.Message, o Order) error { return nil })
+	transire.RegisterJSONQueueHandler[Order](app, "explicit", func(ctx transire.Context, msg transire.Message, o Order) error { return nil })
+}`)
+
+	layout, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+	found := map[string]bool{}
+	for _, q := range layout.Queues {
+		found[q.Name] = true
+	}
+	if len(layout.Queues) != 2 || !found["inferred"] || !found["explicit"] {
+		t.Fatalf("unexpected queues: %+v", layout.Queues)
+	}
+}
+
+func TestScanFindsCronSchedules(t *testing.T) {
+	dir := writeModule(t, `package handlers
+import (
+	"time"
+	"github.com/transire/transire"
+)
+const weekdays = "0 2 * * MON-FRI"
+func Register(app *transire.App) {
+	app.RegisterCronHandler("nightly", weekdays, "Europe/London", func(ctx transire.Context, at time.Time) error { return nil })
+}`)
+
+	layout, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+	if len(layout.Schedules) != 1 {
+		t.Fatalf("unexpected schedules: %+v", layout.Schedules)
+	}
+	got := layout.Schedules[0]
+	if got.Name != "nightly" || got.Cron != "0 2 * * MON-FRI" || got.Timezone != "Europe/London" || got.Every != 0 {
+		t.Fatalf("unexpected cron schedule: %+v", got)
+	}
+}
+
+func TestScanFindsDeadLetterOption(t *testing.T) {
+	dir := writeModule(t, `package handlers
+import "github.com/transire/transire"
+type Order struct{ ID string }
+const receives = 3
+func Register(app *transire.App) {
+	app.RegisterQueueHandler("plain", func(ctx transire.Context, msg transire.Message) error { return nil })
+	app.RegisterQueueHandler("orders", func(ctx transire.Context, msg transire.Message) error { return nil }, transire.WithDeadLetterQueue(5))
+	transire.RegisterJSONQueueHandler(app, "typed", func(ctx transire.Context, msg transire.Message, o Order) error { return nil }, transire.WithDeadLetterQueue(receives))
+}`)
+
+	layout, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+	got := map[string]int{}
+	for _, q := range layout.Queues {
+		got[q.Name] = q.MaxReceiveCount
+	}
+	if len(got) != 3 || got["plain"] != 0 || got["orders"] != 5 || got["typed"] != 3 {
+		t.Fatalf("unexpected queues: %+v", layout.Queues)
+	}
+}
+
+func TestScanFindsConsumerOptions(t *testing.T) {
+	dir := writeModule(t, `package handlers
+import (
+	"time"
+	"github.com/transire/transire"
+)
+func Register(app *transire.App) {
+	app.RegisterQueueHandler("bulk", func(ctx transire.Context, msg transire.Message) error { return nil },
+		transire.WithBatchSize(50),
+		transire.WithBatchWindow(5*time.Second),
+		transire.WithMaxConcurrency(4),
+		transire.WithVisibilityTimeout(2*time.Minute),
+		transire.WithMaxRetries(2),
+	)
+}`)
+
+	layout, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+	if len(layout.Queues) != 1 {
+		t.Fatalf("unexpected queues: %+v", layout.Queues)
+	}
+	want := Queue{Name: "bulk", BatchSize: 50, BatchWindow: 5 * time.Second, MaxConcurrency: 4, VisibilityTimeout: 2 * time.Minute, MaxRetries: 2}
+	if got := layout.Queues[0]; got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestScanFindsBatchQueueHandlers(t *testing.T) {
+	dir := writeModule(t, `package handlers
+import (
+	"time"
+	"github.com/transire/transire"
+)
+func Register(app *transire.App) {
+	app.RegisterBatchQueueHandler("bulk", func(ctx transire.Context, msgs []transire.Message) transire.BatchResult {
+		return transire.BatchResult{}
+	}, transire.WithBatchSize(100), transire.WithBatchWindow(time.Second))
+}`)
+
+	layout, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+	want := Queue{Name: "bulk", Batch: true, BatchSize: 100, BatchWindow: time.Second}
+	if len(layout.Queues) != 1 || layout.Queues[0] != want {
+		t.Fatalf("unexpected queues: %+v", layout.Queues)
+	}
+}
+
+func TestScanFindsFIFOOptions(t *testing.T) {
+	dir := writeModule(t, `package handlers
+import "github.com/transire/transire"
+func Register(app *transire.App) {
+	app.RegisterQueueHandler("billing", func(ctx transire.Context, msg transire.Message) error { return nil }, transire.WithContentBasedDeduplication())
+	app.RegisterQueueHandler("ledger", func(ctx transire.Context, msg transire.Message) error { return nil }, transire.WithFIFO())
+}`)
+
+	layout, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+	got := map[string]Queue{}
+	for _, q := range layout.Queues {
+		got[q.Name] = q
+	}
+	if q := got["billing"]; !q.FIFO || !q.ContentBasedDeduplication {
+		t.Fatalf("unexpected billing queue: %+v", q)
+	}
+	if q := got["ledger"]; !q.FIFO || q.ContentBasedDeduplication {
+		t.Fatalf("unexpected ledger queue: %+v", q)
+	}
+}
+
+func TestScanFindsTopicSubscribers(t *testing.T) {
+	dir := writeModule(t, `package handlers
+import "github.com/transire/transire"
+const orderCreated = "order-created"
+func Register(app *transire.App) {
+	handler := func(ctx transire.Context, msg transire.Message) error { return nil }
+	app.RegisterTopicSubscriber(orderCreated, "email", handler)
+	app.RegisterTopicSubscriber(orderCreated, "billing", handler, transire.WithDeadLetterQueue(3))
+}`)
+
+	layout, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+	if len(layout.Topics) != 1 || layout.Topics[0].Name != "order-created" || len(layout.Topics[0].Subscriptions) != 2 ||
+		layout.Topics[0].Subscriptions[0] != "billing" || layout.Topics[0].Subscriptions[1] != "email" {
+		t.Fatalf("unexpected topics: %+v", layout.Topics)
+	}
+	queues := map[string]Queue{}
+	for _, q := range layout.Queues {
+		queues[q.Name] = q
+	}
+	billing := queues["order-created-billing"]
+	if billing.Topic != "order-created" || billing.Subscription != "billing" || billing.MaxReceiveCount != 3 {
+		t.Fatalf("unexpected subscription queue: %+v", billing)
+	}
+	if _, ok := queues["order-created-email"]; !ok || len(queues) != 2 {
+		t.Fatalf("unexpected queues: %+v", layout.Queues)
+	}
+}
+
 func TestScanIgnoresNonLiterals(t *testing.T) {
 	dir := writeModule(t, `package handlers
 import "github.com/transire/transire"
@@ -98,3 +274,74 @@ func writeModule(t *testing.T, src string) string {
 	}
 	return dir
 }
+
+func TestScanFindsScheduleOptions(t *testing.T) {
+	dir := writeModule(t, `package handlers
+import (
+	"time"
+	"github.com/transire/transire"
+)
+func Register(app *transire.App) {
+	app.RegisterScheduleHandler("tick", time.Minute, func(ctx transire.Context, at time.Time) error { return nil })
+	app.RegisterScheduleHandler("reconcile", time.Minute, func(ctx transire.Context, at time.Time) error { return nil }, transire.WithConcurrency(transire.ConcurrencySkip))
+	app.RegisterCronHandler("report", "0 * * * *", "", func(ctx transire.Context, at time.Time) error { return nil }, transire.WithConcurrency("queue"), transire.WithRunOnStart(), transire.WithJitter(2*time.Minute), transire.WithMetadata("team", "billing"), transire.WithMetadata(reportKey, "monthly"))
+}
+const reportKey = "kind"`)
+
+	layout, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+	got := map[string]string{}
+	for _, s := range layout.Schedules {
+		got[s.Name] = s.Concurrency
+	}
+	if len(got) != 3 || got["tick"] != "" || got["reconcile"] != "skip" || got["report"] != "queue" {
+		t.Fatalf("unexpected schedules: %+v", layout.Schedules)
+	}
+	for _, s := range layout.Schedules {
+		if want := s.Name == "report"; s.RunOnStart != want || (s.Jitter == 2*time.Minute) != want {
+			t.Fatalf("unexpected start and jitter options: %+v", s)
+		}
+		if s.Name == "report" && (len(s.Metadata) != 2 || s.Metadata["team"] != "billing" || s.Metadata["kind"] != "monthly") {
+			t.Fatalf("unexpected metadata: %+v", s.Metadata)
+		}
+	}
+}
+
+func TestScanFindsTimers(t *testing.T) {
+	dir := writeModule(t, `package handlers
+import "github.com/transire/transire"
+type calendar struct{}
+func (calendar) ScheduleAt(day string) {}
+func Register(app *transire.App) {
+	calendar{}.ScheduleAt("monday")
+	app.RegisterQueueHandler("reminders", func(ctx transire.Context, msg transire.Message) error { return nil })
+}`)
+	layout, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+	if layout.Timers {
+		t.Fatalf("expected methods of other types ignored")
+	}
+
+	dir = writeModule(t, `package handlers
+import (
+	"time"
+	"github.com/transire/transire"
+)
+func Register(app *transire.App) {
+	app.RegisterQueueHandler("signup", func(ctx transire.Context, msg transire.Message) error {
+		_, err := ctx.ScheduleAfter("reminders", 24*time.Hour, msg.Body)
+		return err
+	})
+	app.RegisterQueueHandler("reminders", func(ctx transire.Context, msg transire.Message) error { return nil })
+}`)
+	if layout, err = Scan(dir); err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+	if !layout.Timers {
+		t.Fatalf("expected ScheduleAfter found")
+	}
+}
@@ -126,19 +126,18 @@ func queueTemplate() string {
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/transire/transire"
 )
 
 func RegisterQueues(app *transire.App) {
 	app.RegisterQueueHandler(WorkQueue, func(ctx transire.Context, msg transire.Message) error {
-		log.Printf("work queue received: %s", string(msg.Body))
+		ctx.Log().Info("work queue received", "body", string(msg.Body))
 		return ctx.Queues.Send(ctx, AuditQueue, []byte(fmt.Sprintf("audit: %s", msg.Body)))
 	})
 
 	app.RegisterQueueHandler(AuditQueue, func(ctx transire.Context, msg transire.Message) error {
-		log.Printf("audit log: %s", string(msg.Body))
+		ctx.Log().Info("audit log", "body", string(msg.Body))
 		return nil
 	})
 }
@@ -150,7 +149,6 @@ func scheduleTemplate() string {
 
 import (
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/transire/transire"
@@ -158,7 +156,7 @@ import (
 
 func RegisterSchedules(app *transire.App) {
 	app.RegisterScheduleHandler("heartbeat", time.Minute, func(ctx transire.Context, at time.Time) error {
-		log.Printf("heartbeat at %s", at.UTC())
+		ctx.Log().Info("heartbeat", "at", at.UTC())
 		return ctx.Queues.Send(ctx, WorkQueue, []byte(fmt.Sprintf("heartbeat at %s", at.UTC().Format(time.RFC3339))))
 	})
 }
@@ -190,6 +188,7 @@ require (
 var gitignoreTemplate = strings.TrimSpace(`
 bin/
 dist/
+.transire/
 .DS_Store
 `) + "\n"
 
@@ -0,0 +1,51 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONQueueHandler processes a queue message whose body has been decoded into T.
+type JSONQueueHandler[T any] func(ctx Context, msg Message, payload T) error
+
+// DecodeError reports a queue message body that could not be decoded.
+// Redelivering the same body cannot succeed, so IsPermanent reports it as permanent.
+type DecodeError struct {
+	Queue     string
+	MessageID string
+	Err       error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("transire: decode message %s on queue %s: %v", e.MessageID, e.Queue, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// RegisterJSONQueueHandler binds a handler to a named queue, decoding each message body as JSON into T.
+// Bodies that fail to decode are reported as *DecodeError without invoking the handler.
+func RegisterJSONQueueHandler[T any](app *App, queue string, handler JSONQueueHandler[T], opts ...QueueOption) {
+	app.RegisterQueueHandler(queue, func(ctx Context, msg Message) error {
+		var payload T
+		if err := json.Unmarshal(msg.Body, &payload); err != nil {
+			return &DecodeError{Queue: msg.Queue, MessageID: msg.ID, Err: err}
+		}
+		return handler(ctx, msg, payload)
+	}, opts...)
+}
+
+// SendJSON encodes payload as JSON and sends it to the named queue.
+func SendJSON[T any](ctx context.Context, sender QueueSender, queue string, payload T) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("transire: encode message for queue %s: %w", queue, err)
+	}
+	return sender.Send(ctx, queue, body)
+}
@@ -0,0 +1,89 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type order struct {
+	ID    string `json:"id"`
+	Total int    `json:"total"`
+}
+
+type captureSender struct {
+	queue   string
+	payload []byte
+}
+
+func (c *captureSender) Send(ctx context.Context, queue string, payload []byte) error {
+	c.queue = queue
+	c.payload = payload
+	return nil
+}
+
+func TestRegisterJSONQueueHandlerDecodes(t *testing.T) {
+	app := New()
+	var got order
+	RegisterJSONQueueHandler(app, "orders", func(ctx Context, msg Message, payload order) error {
+		got = payload
+		return nil
+	})
+
+	handler := app.QueueHandlers()["orders"]
+	if handler == nil {
+		t.Fatalf("json queue handler not registered")
+	}
+	err := handler(Context{Context: context.Background()}, Message{Queue: "orders", Body: []byte(`{"id":"o-1","total":42}`)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ID != "o-1" || got.Total != 42 {
+		t.Fatalf("unexpected payload: %+v", got)
+	}
+}
+
+func TestRegisterJSONQueueHandlerDecodeError(t *testing.T) {
+	app := New()
+	called := false
+	RegisterJSONQueueHandler(app, "orders", func(ctx Context, msg Message, payload order) error {
+		called = true
+		return nil
+	})
+
+	err := app.QueueHandlers()["orders"](Context{Context: context.Background()}, Message{ID: "m-1", Queue: "orders", Body: []byte("not json")})
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected DecodeError, got %v", err)
+	}
+	if decodeErr.Queue != "orders" || decodeErr.MessageID != "m-1" {
+		t.Fatalf("unexpected decode error fields: %+v", decodeErr)
+	}
+	if called {
+		t.Fatalf("handler should not run for undecodable body")
+	}
+}
+
+func TestSendJSONEncodes(t *testing.T) {
+	sender := &captureSender{}
+	if err := SendJSON(context.Background(), sender, "orders", order{ID: "o-2", Total: 7}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sender.queue != "orders" || string(sender.payload) != `{"id":"o-2","total":7}` {
+		t.Fatalf("unexpected send: %s %s", sender.queue, sender.payload)
+	}
+}
+
+func TestSendJSONEncodeError(t *testing.T) {
+	sender := &captureSender{}
+	if err := SendJSON(context.Background(), sender, "orders", make(chan int)); err == nil {
+		t.Fatalf("expected encode error")
+	}
+	if sender.queue != "" {
+		t.Fatalf("sender should not be called on encode failure")
+	}
+}
@@ -0,0 +1,50 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrShuttingDown is returned by queue senders that no longer accept messages because
+// the dispatcher is shutting down.
+var ErrShuttingDown = errors.New("transire: dispatcher is shutting down")
+
+// LifecycleHook opens or closes app-wide resources such as database pools.
+type LifecycleHook func(ctx context.Context) error
+
+// OnStart registers a hook that dispatchers run before serving any events.
+func (a *App) OnStart(hook LifecycleHook) {
+	a.startHooks = append(a.startHooks, hook)
+}
+
+// OnShutdown registers a hook that dispatchers run after in-flight work has drained.
+func (a *App) OnShutdown(hook LifecycleHook) {
+	a.shutdownHooks = append(a.shutdownHooks, hook)
+}
+
+// Start runs the OnStart hooks in registration order, stopping at the first error.
+func (a *App) Start(ctx context.Context) error {
+	for _, hook := range a.startHooks {
+		if err := hook(ctx); err != nil {
+			return fmt.Errorf("transire: start hook: %w", err)
+		}
+	}
+	return nil
+}
+
+// Shutdown runs every OnShutdown hook in reverse registration order, so resources close
+// in the opposite order they were opened, and returns their joined errors.
+func (a *App) Shutdown(ctx context.Context) error {
+	var errs []error
+	for i := len(a.shutdownHooks) - 1; i >= 0; i-- {
+		if err := a.shutdownHooks[i](ctx); err != nil {
+			errs = append(errs, fmt.Errorf("transire: shutdown hook: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
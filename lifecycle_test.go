@@ -0,0 +1,52 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLifecycleHooksOrder(t *testing.T) {
+	app := New()
+	var calls []string
+	hook := func(name string, err error) LifecycleHook {
+		return func(ctx context.Context) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+	app.OnStart(hook("start db", nil))
+	app.OnStart(hook("start cache", nil))
+	app.OnShutdown(hook("close db", errors.New("db busy")))
+	app.OnShutdown(hook("close cache", nil))
+
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	err := app.Shutdown(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "db busy") {
+		t.Fatalf("expected shutdown error, got %v", err)
+	}
+	if got := strings.Join(calls, ","); got != "start db,start cache,close cache,close db" {
+		t.Fatalf("unexpected hook order: %s", got)
+	}
+}
+
+func TestStartStopsAtFirstError(t *testing.T) {
+	app := New()
+	ran := false
+	app.OnStart(func(ctx context.Context) error { return errors.New("no db") })
+	app.OnStart(func(ctx context.Context) error { ran = true; return nil })
+
+	if err := app.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "no db") {
+		t.Fatalf("expected start error, got %v", err)
+	}
+	if ran {
+		t.Fatalf("expected later hooks skipped")
+	}
+}
@@ -0,0 +1,123 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// Handler kinds recorded on handler loggers under the "kind" key.
+const (
+	KindHTTP     = "http"
+	KindQueue    = "queue"
+	KindSchedule = "schedule"
+)
+
+type loggerKey struct{}
+
+// ContextWithLogger returns a copy of ctx carrying logger. Handler contexts built from
+// it log through logger instead of the app logger, which lets dispatchers attach
+// invocation fields such as a Lambda request ID.
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFromContext returns the logger attached with ContextWithLogger, or nil.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	logger, _ := ctx.Value(loggerKey{}).(*slog.Logger)
+	return logger
+}
+
+// Log returns the context's logger, or slog.Default when Logger is unset, as in
+// contexts built by hand in tests. Handlers should log through it rather than Logger.
+func (c Context) Log() *slog.Logger {
+	if c.Logger == nil {
+		return slog.Default()
+	}
+	return c.Logger
+}
+
+// SetLogger configures the base logger for handler contexts.
+func (a *App) SetLogger(logger *slog.Logger) {
+	a.logger = logger
+}
+
+// Logger returns the configured base logger, or nil when dispatchers should pick a default.
+func (a *App) Logger() *slog.Logger {
+	return a.logger
+}
+
+// loggerFor resolves the logger for ctx: one attached to ctx, else the app logger, else
+// slog.Default. The correlation ID of ctx, if any, is added as a field.
+func (a *App) loggerFor(ctx context.Context) *slog.Logger {
+	logger := baseLogger(ctx, a.logger)
+	if id := CorrelationID(ctx); id != "" {
+		logger = logger.With("correlation_id", id)
+	}
+	return logger
+}
+
+func baseLogger(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if ctx != nil {
+		if logger := LoggerFromContext(ctx); logger != nil {
+			return logger
+		}
+	}
+	if fallback != nil {
+		return fallback
+	}
+	return slog.Default()
+}
+
+// QueueContext builds the handler context for a queue message, with the queue name and
+// message ID on its logger. The correlation ID is restored from the message attributes,
+// falling back to the one on ctx or a new ID.
+func (a *App) QueueContext(ctx context.Context, msg Message) Context {
+	id := msg.Attributes[CorrelationIDAttribute]
+	if id == "" {
+		id = CorrelationID(ctx)
+	}
+	if id == "" {
+		id = NewCorrelationID()
+	}
+	c := a.NewContext(ContextWithCorrelationID(ctx, id))
+	c.Logger = c.Logger.With("kind", KindQueue, "queue", msg.Queue, "message_id", msg.ID)
+	return c
+}
+
+// BatchContext builds the handler context for a batch of queue messages. Messages of a
+// batch may belong to different chains; each one's correlation ID is in its
+// CorrelationIDAttribute.
+func (a *App) BatchContext(ctx context.Context, queue string, size int) Context {
+	c := a.NewContext(ctx)
+	c.Logger = c.Logger.With("kind", KindQueue, "queue", queue, "batch_size", size)
+	return c
+}
+
+// ScheduleContext builds the handler context for a schedule run, with the schedule name on
+// its logger. Each run starts a new correlation chain unless ctx already carries one.
+func (a *App) ScheduleContext(ctx context.Context, name string) Context {
+	if CorrelationID(ctx) == "" {
+		ctx = ContextWithCorrelationID(ctx, NewCorrelationID())
+	}
+	c := a.NewContext(ctx)
+	c.Logger = c.Logger.With("kind", KindSchedule, "schedule", name)
+	return c
+}
+
+// httpContext builds the handler context for an HTTP request, logging its request ID when
+// it has one (see withRequestID). r's context must already carry the request's
+// correlation ID.
+func (a *App) httpContext(r *http.Request) Context {
+	c := a.NewContext(r.Context())
+	args := []any{"kind", KindHTTP, "method", r.Method, "path", r.URL.Path}
+	if id := requestID(r); id != "" {
+		args = append(args, "request_id", id)
+	}
+	c.Logger = c.Logger.With(args...)
+	return c
+}
@@ -0,0 +1,114 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func decodeLogLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("decode log line %q: %v", buf.String(), err)
+	}
+	return line
+}
+
+func TestQueueContextLoggerFields(t *testing.T) {
+	var buf bytes.Buffer
+	app := New()
+	app.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	ctx := app.QueueContext(context.Background(), Message{ID: "m1", Queue: "work"})
+	ctx.Logger.Info("hello")
+
+	line := decodeLogLine(t, &buf)
+	if line["kind"] != KindQueue || line["queue"] != "work" || line["message_id"] != "m1" {
+		t.Fatalf("unexpected log fields: %v", line)
+	}
+}
+
+func TestContextLoggerTakesPrecedence(t *testing.T) {
+	var appBuf, ctxBuf bytes.Buffer
+	app := New()
+	app.SetLogger(slog.New(slog.NewJSONHandler(&appBuf, nil)))
+
+	base := ContextWithLogger(context.Background(), slog.New(slog.NewJSONHandler(&ctxBuf, nil)).With("lambda_request_id", "req-1"))
+	app.ScheduleContext(base, "nightly").Logger.Info("tick")
+
+	if appBuf.Len() != 0 {
+		t.Fatalf("expected app logger unused, got %q", appBuf.String())
+	}
+	line := decodeLogLine(t, &ctxBuf)
+	if line["lambda_request_id"] != "req-1" || line["kind"] != KindSchedule || line["schedule"] != "nightly" {
+		t.Fatalf("unexpected log fields: %v", line)
+	}
+}
+
+func TestContextMiddlewareLogsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	app := New()
+	app.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	handler := app.ContextMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := RequestContext(r)
+		if !ok {
+			t.Fatalf("context not found")
+		}
+		ctx.Logger.Info("handled")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := decodeLogLine(t, &buf)
+	if line["kind"] != KindHTTP || line["method"] != http.MethodGet || line["path"] != "/orders" || line["request_id"] != "abc" {
+		t.Fatalf("unexpected log fields: %v", line)
+	}
+}
+
+func TestContextMiddlewareAssignsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	app := New()
+	app.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	var seen string
+	app.Router().Use(middleware.RequestID)
+	app.Router().Get("/orders", func(w http.ResponseWriter, r *http.Request) {
+		seen = middleware.GetReqID(r.Context())
+		ctx, _ := RequestContext(r)
+		ctx.Logger.Info("handled")
+	})
+	root := chi.NewRouter()
+	root.Use(app.ContextMiddleware())
+	root.Mount("/", app.Router())
+
+	root.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	line := decodeLogLine(t, &buf)
+	if seen == "" || line["request_id"] != seen {
+		t.Fatalf("expected the logged request ID %v to be the handler's %q", line["request_id"], seen)
+	}
+}
+
+func TestLogFallsBackToDefault(t *testing.T) {
+	if (Context{}).Log() != slog.Default() {
+		t.Fatalf("expected slog.Default for a hand-built context")
+	}
+	logger := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+	if (Context{Logger: logger}).Log() != logger {
+		t.Fatalf("expected the context's logger")
+	}
+}
@@ -0,0 +1,77 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import "time"
+
+// Metrics records handler activity. Handlers returned by App lookups report each
+// invocation to the app's Metrics, and queue senders report each message sent.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// HandlerStarted marks a handler invocation as in flight.
+	HandlerStarted(kind, name string)
+	// HandlerFinished records a completed invocation, its duration, and its error, if any.
+	HandlerFinished(kind, name string, duration time.Duration, err error)
+	// MessageSent counts a message sent to a queue.
+	MessageSent(queue string)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) HandlerStarted(kind, name string)                                     {}
+func (nopMetrics) HandlerFinished(kind, name string, duration time.Duration, err error) {}
+func (nopMetrics) MessageSent(queue string)                                             {}
+
+// SetMetrics configures where handler and send metrics are recorded.
+func (a *App) SetMetrics(metrics Metrics) {
+	a.metrics = metrics
+}
+
+// Metrics returns the configured metrics recorder, or nil when dispatchers should pick a default.
+func (a *App) Metrics() Metrics {
+	return a.metrics
+}
+
+// metricsOrNop returns the configured metrics recorder, or one that discards everything.
+func (a *App) metricsOrNop() Metrics {
+	if a.metrics != nil {
+		return a.metrics
+	}
+	return nopMetrics{}
+}
+
+// measure reports one invocation of fn to the app's metrics.
+func (a *App) measure(kind, name string, fn func() error) error {
+	m := a.metricsOrNop()
+	m.HandlerStarted(kind, name)
+	start := time.Now()
+	err := fn()
+	m.HandlerFinished(kind, name, time.Since(start), err)
+	return err
+}
+
+func (a *App) measuredQueue(queue string, handler QueueHandler) QueueHandler {
+	return func(ctx Context, msg Message) error {
+		return a.measure(KindQueue, queue, func() error { return handler(ctx, msg) })
+	}
+}
+
+// measuredBatch records one invocation per batch, failed when any message failed.
+func (a *App) measuredBatch(queue string, handler BatchQueueHandler) BatchQueueHandler {
+	return func(ctx Context, msgs []Message) BatchResult {
+		var result BatchResult
+		_ = a.measure(KindQueue, queue, func() error {
+			result = handler(ctx, msgs)
+			return batchError(result, len(msgs))
+		})
+		return result
+	}
+}
+
+func (a *App) measuredSchedule(name string, handler ScheduleHandler) ScheduleHandler {
+	return func(ctx Context, at time.Time) error {
+		return a.measure(KindSchedule, name, func() error { return handler(ctx, at) })
+	}
+}
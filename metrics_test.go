@@ -0,0 +1,91 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordedCall struct {
+	kind, name string
+	err        error
+}
+
+type recordingMetrics struct {
+	mu       sync.Mutex
+	started  []string
+	finished []recordedCall
+	sent     []string
+}
+
+func (m *recordingMetrics) HandlerStarted(kind, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.started = append(m.started, kind+"/"+name)
+}
+
+func (m *recordingMetrics) HandlerFinished(kind, name string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.finished = append(m.finished, recordedCall{kind: kind, name: name, err: err})
+}
+
+func (m *recordingMetrics) MessageSent(queue string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, queue)
+}
+
+func TestHandlersReportMetrics(t *testing.T) {
+	metrics := &recordingMetrics{}
+	app := New()
+	app.SetMetrics(metrics)
+	app.RegisterQueueHandler("work", func(ctx Context, msg Message) error {
+		return errors.New("boom")
+	})
+	app.RegisterScheduleHandler("nightly", time.Hour, func(ctx Context, at time.Time) error {
+		return nil
+	})
+	app.RegisterBatchQueueHandler("events", func(ctx Context, msgs []Message) BatchResult {
+		return FailAll(msgs[:1], errors.New("bad"))
+	})
+
+	queue, _ := app.QueueHandler("work")
+	_ = queue(app.NewContext(context.Background()), Message{ID: "m1"})
+	schedule, _ := app.ScheduleHandler("nightly")
+	_ = schedule(app.NewContext(context.Background()), time.Now())
+	batch, _ := app.BatchQueueHandler("events")
+	batch(app.NewContext(context.Background()), []Message{{ID: "a"}, {ID: "b"}})
+
+	if len(metrics.started) != 3 || len(metrics.finished) != 3 {
+		t.Fatalf("expected 3 recorded invocations, got %v / %+v", metrics.started, metrics.finished)
+	}
+	work, nightly, events := metrics.finished[0], metrics.finished[1], metrics.finished[2]
+	if work.kind != KindQueue || work.name != "work" || work.err == nil {
+		t.Fatalf("unexpected queue metrics: %+v", work)
+	}
+	if nightly.kind != KindSchedule || nightly.name != "nightly" || nightly.err != nil {
+		t.Fatalf("unexpected schedule metrics: %+v", nightly)
+	}
+	if events.name != "events" || events.err == nil {
+		t.Fatalf("expected failed batch to count as an error: %+v", events)
+	}
+}
+
+func TestMetricsUnsetByDefault(t *testing.T) {
+	app := New()
+	if app.Metrics() != nil {
+		t.Fatalf("expected no metrics configured")
+	}
+	app.RegisterQueueHandler("work", func(ctx Context, msg Message) error { return nil })
+	handler, _ := app.QueueHandler("work")
+	if err := handler(app.NewContext(context.Background()), Message{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
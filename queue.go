@@ -0,0 +1,153 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"fmt"
+	"time"
+)
+
+// DeadLetterSuffix is appended to a queue name to name its dead-letter queue.
+const DeadLetterSuffix = "-dlq"
+
+// FIFOSuffix ends the physical name of every FIFO queue, as SQS requires.
+const FIFOSuffix = ".fifo"
+
+// DeduplicationWindow is how long a FIFO queue remembers deduplication IDs.
+const DeduplicationWindow = 5 * time.Minute
+
+// QueueConfig holds per-queue settings declared with QueueOption values.
+// Zero values fall back to the runtime defaults.
+type QueueConfig struct {
+	// MaxReceiveCount is how many times a message is delivered before it moves to the
+	// queue's dead-letter queue. Zero means no dead-letter queue.
+	MaxReceiveCount int
+	// BatchSize is the maximum number of messages handed to the consumer per poll.
+	BatchSize int
+	// BatchWindow is how long to wait to fill a batch before invoking the consumer.
+	BatchWindow time.Duration
+	// MaxConcurrency caps how many messages from the queue are processed at once.
+	MaxConcurrency int
+	// VisibilityTimeout is how long a received message stays hidden from other consumers.
+	VisibilityTimeout time.Duration
+	// MaxRetries is how many times a failing handler is re-invoked within a single
+	// receive before the message counts as failed.
+	MaxRetries int
+	// RetryPolicy sets the delay before a failed message is redelivered.
+	RetryPolicy RetryPolicy
+	// FIFO queues deliver messages in order within each message group and drop
+	// duplicates sent within DeduplicationWindow.
+	FIFO bool
+	// ContentBasedDeduplication derives the deduplication ID from the message body.
+	ContentBasedDeduplication bool
+}
+
+// ValidateSend reports send options the queue cannot accept. FIFO queues require a
+// group ID, a deduplication ID unless content-based deduplication is enabled, and
+// do not support per-message delays.
+func (c QueueConfig) ValidateSend(queue string, opts SendOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if !c.FIFO {
+		return nil
+	}
+	if opts.GroupID == "" {
+		return fmt.Errorf("transire: queue %s is FIFO and requires a group ID", queue)
+	}
+	if opts.DeduplicationID == "" && !c.ContentBasedDeduplication {
+		return fmt.Errorf("transire: queue %s is FIFO and requires a deduplication ID", queue)
+	}
+	if opts.Delay > 0 {
+		return fmt.Errorf("transire: queue %s is FIFO and does not support per-message delays", queue)
+	}
+	return nil
+}
+
+// QueueOption configures a queue at registration time.
+type QueueOption func(*QueueConfig)
+
+// WithDeadLetterQueue provisions a dead-letter queue that receives messages after
+// maxReceiveCount failed deliveries.
+func WithDeadLetterQueue(maxReceiveCount int) QueueOption {
+	return func(c *QueueConfig) {
+		c.MaxReceiveCount = maxReceiveCount
+	}
+}
+
+// WithBatchSize sets the maximum number of messages fetched per poll.
+// On AWS, sizes above 10 require WithBatchWindow.
+func WithBatchSize(n int) QueueOption {
+	return func(c *QueueConfig) {
+		c.BatchSize = n
+	}
+}
+
+// WithBatchWindow sets how long to gather messages before invoking the consumer.
+func WithBatchWindow(d time.Duration) QueueOption {
+	return func(c *QueueConfig) {
+		c.BatchWindow = d
+	}
+}
+
+// WithMaxConcurrency caps concurrent processing of the queue's messages.
+// AWS accepts values between 2 and 1000.
+func WithMaxConcurrency(n int) QueueOption {
+	return func(c *QueueConfig) {
+		c.MaxConcurrency = n
+	}
+}
+
+// WithVisibilityTimeout overrides the queue visibility timeout.
+// On AWS it must not be shorter than the Lambda timeout, 30s unless infra/extend.ts sets it.
+func WithVisibilityTimeout(d time.Duration) QueueOption {
+	return func(c *QueueConfig) {
+		c.VisibilityTimeout = d
+	}
+}
+
+// WithMaxRetries re-invokes a failing handler up to n more times before the
+// message is reported as failed.
+func WithMaxRetries(n int) QueueOption {
+	return func(c *QueueConfig) {
+		c.MaxRetries = n
+	}
+}
+
+// WithFIFO declares a FIFO queue: messages sent with the same SendOptions.GroupID are
+// delivered one at a time, in order.
+func WithFIFO() QueueOption {
+	return func(c *QueueConfig) {
+		c.FIFO = true
+	}
+}
+
+// WithContentBasedDeduplication declares a FIFO queue that deduplicates messages by body,
+// so senders need not set SendOptions.DeduplicationID.
+func WithContentBasedDeduplication() QueueOption {
+	return func(c *QueueConfig) {
+		c.FIFO = true
+		c.ContentBasedDeduplication = true
+	}
+}
+
+// DeadLetterQueueName returns the name of the dead-letter queue for queue.
+func DeadLetterQueueName(queue string) string {
+	return queue + DeadLetterSuffix
+}
+
+// retrying re-invokes handler until it succeeds, fails permanently, or has been retried retries times.
+func retrying(handler QueueHandler, retries int) QueueHandler {
+	return func(ctx Context, msg Message) error {
+		err := handler(ctx, msg)
+		for i := 0; err != nil && i < retries && !IsPermanent(err); i++ {
+			if ctx.Context != nil && ctx.Err() != nil {
+				break
+			}
+			err = handler(ctx, msg)
+		}
+		return err
+	}
+}
@@ -0,0 +1,146 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// PanicError is the error a handler panic is converted to. The message is retried
+// or dead-lettered like any other failure.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("transire: handler panic: %v", e.Value)
+}
+
+// Unwrap exposes the panic value when it was an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// HandlerError describes a failed queue message or schedule run, as passed to OnError hooks.
+type HandlerError struct {
+	// Kind is KindQueue or KindSchedule.
+	Kind string
+	// Name is the queue or schedule name.
+	Name string
+	// Message is the failed message, or nil for schedule runs.
+	Message *Message
+	// Err is the handler error after retries; a *PanicError for panics.
+	Err error
+}
+
+// ErrorHook receives handler failures, for example to forward them to an error tracker.
+type ErrorHook func(ctx Context, failure HandlerError)
+
+// OnError registers a hook called whenever a queue or schedule handler fails, after
+// in-process retries. Hooks run in registration order; a panicking hook is logged and skipped.
+func (a *App) OnError(hook ErrorHook) {
+	a.errorHooks = append(a.errorHooks, hook)
+}
+
+func (a *App) reportError(ctx Context, failure HandlerError) {
+	for _, hook := range a.errorHooks {
+		func() {
+			defer func() {
+				if v := recover(); v != nil {
+					a.loggerFor(ctx.Context).Error("error hook panicked", "panic", fmt.Sprint(v))
+				}
+			}()
+			hook(ctx, failure)
+		}()
+	}
+}
+
+// recoverPanic converts a panic into a *PanicError, logging it with its stack trace.
+// It must be deferred directly.
+func recoverPanic(ctx Context, err *error) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	pe := &PanicError{Value: v, Stack: debug.Stack()}
+	ctx.Log().Error("handler panicked", "panic", fmt.Sprint(v), "stack", string(pe.Stack))
+	*err = pe
+}
+
+// recovering turns handler panics into *PanicError returns, so that each in-process
+// retry attempt recovers on its own.
+func recovering(handler QueueHandler) QueueHandler {
+	return func(ctx Context, msg Message) (err error) {
+		defer recoverPanic(ctx, &err)
+		return handler(ctx, msg)
+	}
+}
+
+// recoveringBatch fails the whole batch when the handler panics.
+func recoveringBatch(handler BatchQueueHandler) BatchQueueHandler {
+	return func(ctx Context, msgs []Message) (result BatchResult) {
+		var err error
+		func() {
+			defer recoverPanic(ctx, &err)
+			result = handler(ctx, msgs)
+		}()
+		if err != nil {
+			result = FailAll(msgs, err)
+		}
+		return result
+	}
+}
+
+// recoveredQueue turns handler panics into errors and reports failures to the OnError hooks.
+func (a *App) recoveredQueue(queue string, handler QueueHandler) QueueHandler {
+	return func(ctx Context, msg Message) (err error) {
+		defer func() {
+			if err != nil {
+				a.reportError(ctx, HandlerError{Kind: KindQueue, Name: queue, Message: &msg, Err: err})
+			}
+		}()
+		defer recoverPanic(ctx, &err)
+		return handler(ctx, msg)
+	}
+}
+
+// recoveredBatch fails the whole batch when the handler panics and reports each failed
+// message to the OnError hooks.
+func (a *App) recoveredBatch(queue string, handler BatchQueueHandler) BatchQueueHandler {
+	handler = recoveringBatch(handler)
+	return func(ctx Context, msgs []Message) (result BatchResult) {
+		defer func() {
+			byID := make(map[string]int, len(msgs))
+			for i, msg := range msgs {
+				byID[msg.ID] = i
+			}
+			for _, f := range result.Failures {
+				failure := HandlerError{Kind: KindQueue, Name: queue, Err: f.cause()}
+				if i, ok := byID[f.MessageID]; ok {
+					failure.Message = &msgs[i]
+				}
+				a.reportError(ctx, failure)
+			}
+		}()
+		return handler(ctx, msgs)
+	}
+}
+
+// recoveredSchedule turns handler panics into errors and reports failures to the OnError hooks.
+func (a *App) recoveredSchedule(name string, handler ScheduleHandler) ScheduleHandler {
+	return func(ctx Context, at time.Time) (err error) {
+		defer func() {
+			if err != nil {
+				a.reportError(ctx, HandlerError{Kind: KindSchedule, Name: name, Err: err})
+			}
+		}()
+		defer recoverPanic(ctx, &err)
+		return handler(ctx, at)
+	}
+}
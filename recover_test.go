@@ -0,0 +1,114 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueueHandlerPanicBecomesError(t *testing.T) {
+	var logs bytes.Buffer
+	app := New()
+	app.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	var failures []HandlerError
+	app.OnError(func(ctx Context, failure HandlerError) {
+		failures = append(failures, failure)
+	})
+	app.RegisterQueueHandler("work", func(ctx Context, msg Message) error {
+		panic("nil map")
+	})
+
+	handler, _ := app.QueueHandler("work")
+	msg := Message{ID: "m1", Queue: "work"}
+	err := handler(app.QueueContext(context.Background(), msg), msg)
+
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value != "nil map" || len(pe.Stack) == 0 {
+		t.Fatalf("expected *PanicError with stack, got %v", err)
+	}
+	if len(failures) != 1 || failures[0].Kind != KindQueue || failures[0].Name != "work" || failures[0].Message == nil || failures[0].Message.ID != "m1" || failures[0].Err != err {
+		t.Fatalf("unexpected hook calls: %+v", failures)
+	}
+	if !strings.Contains(logs.String(), "handler panicked") || !strings.Contains(logs.String(), "stack=") {
+		t.Fatalf("expected panic logged with stack, got %q", logs.String())
+	}
+}
+
+func TestBatchHandlerPanicFailsBatch(t *testing.T) {
+	app := New()
+	var reported []string
+	app.OnError(func(ctx Context, failure HandlerError) {
+		reported = append(reported, failure.Message.ID)
+	})
+	app.RegisterBatchQueueHandler("events", func(ctx Context, msgs []Message) BatchResult {
+		panic(errors.New("boom"))
+	})
+
+	handler, _ := app.BatchQueueHandler("events")
+	result := handler(app.NewContext(context.Background()), []Message{{ID: "a"}, {ID: "b"}})
+	if len(result.Failures) != 2 || !strings.Contains(result.Failures[0].Err.Error(), "boom") {
+		t.Fatalf("expected all messages failed, got %+v", result.Failures)
+	}
+	if strings.Join(reported, ",") != "a,b" {
+		t.Fatalf("expected each message reported, got %v", reported)
+	}
+}
+
+func TestScheduleErrorsReachHooks(t *testing.T) {
+	app := New()
+	var failure HandlerError
+	app.OnError(func(ctx Context, f HandlerError) { failure = f })
+	app.OnError(func(ctx Context, f HandlerError) { panic("hook bug") })
+	app.RegisterScheduleHandler("nightly", time.Hour, func(ctx Context, at time.Time) error {
+		return errors.New("db down")
+	})
+
+	handler, _ := app.ScheduleHandler("nightly")
+	if err := handler(app.ScheduleContext(context.Background(), "nightly"), time.Now()); err == nil {
+		t.Fatalf("expected schedule error")
+	}
+	if failure.Kind != KindSchedule || failure.Name != "nightly" || failure.Message != nil || failure.Err == nil {
+		t.Fatalf("unexpected failure: %+v", failure)
+	}
+}
+
+func TestPanicsAreRetriedInProcess(t *testing.T) {
+	app := New()
+	calls := 0
+	app.RegisterQueueHandler("work", func(ctx Context, msg Message) error {
+		calls++
+		if calls < 3 {
+			panic("flaky")
+		}
+		return nil
+	}, WithMaxRetries(2))
+
+	handler, _ := app.QueueHandler("work")
+	msg := Message{ID: "m1", Queue: "work"}
+	if err := handler(app.QueueContext(context.Background(), msg), msg); err != nil || calls != 3 {
+		t.Fatalf("expected the panicking handler retried to success, got %v after %d calls", err, calls)
+	}
+}
+
+func TestBatchFailureWithoutErrorIsReported(t *testing.T) {
+	app := New()
+	var failure HandlerError
+	app.OnError(func(ctx Context, f HandlerError) { failure = f })
+	app.RegisterBatchQueueHandler("events", func(ctx Context, msgs []Message) BatchResult {
+		return BatchResult{Failures: []MessageFailure{{MessageID: msgs[0].ID}}}
+	})
+
+	handler, _ := app.BatchQueueHandler("events")
+	handler(app.NewContext(context.Background()), []Message{{ID: "a"}})
+	if failure.Err == nil || failure.Message == nil || failure.Message.ID != "a" {
+		t.Fatalf("expected the failure reported with an error, got %+v", failure)
+	}
+}
@@ -0,0 +1,113 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Retry policy defaults used when a RetryPolicy field is left zero.
+const (
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = 5 * time.Minute
+	DefaultBackoffFactor  = 2.0
+)
+
+// MaxRetryDelay is the longest a message can be hidden before redelivery (the SQS visibility limit).
+const MaxRetryDelay = 12 * time.Hour
+
+// RetryPolicy controls how long a failed queue message waits before it is redelivered.
+// The delay after the nth failed receive is InitialBackoff * Factor^(n-1), capped at MaxBackoff.
+type RetryPolicy struct {
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Factor         float64
+}
+
+// Backoff returns the redelivery delay after the given (1-based) failed receive.
+func (p RetryPolicy) Backoff(receive int) time.Duration {
+	initial, maxBackoff, factor := p.InitialBackoff, p.MaxBackoff, p.Factor
+	if initial <= 0 {
+		initial = DefaultInitialBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+	if factor < 1 {
+		factor = DefaultBackoffFactor
+	}
+
+	delay := float64(initial)
+	for i := 1; i < receive && delay < float64(maxBackoff); i++ {
+		delay *= factor
+	}
+	if delay > float64(maxBackoff) {
+		return maxBackoff
+	}
+	return time.Duration(delay)
+}
+
+// Delay returns how long to wait before redelivering a message whose handler returned err
+// on the given receive. A RetryAfter error overrides the backoff.
+func (p RetryPolicy) Delay(err error, receive int) time.Duration {
+	var ra *retryAfterError
+	if errors.As(err, &ra) {
+		return min(max(ra.delay, 0), MaxRetryDelay)
+	}
+	return p.Backoff(receive)
+}
+
+// WithRetryPolicy sets the redelivery backoff for failed messages.
+func WithRetryPolicy(p RetryPolicy) QueueOption {
+	return func(c *QueueConfig) {
+		c.RetryPolicy = p
+	}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying. The message is moved straight to the
+// queue's dead-letter queue, or dropped when the queue has none.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err, or an error it wraps, was marked with Permanent
+// or is a *DecodeError.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	var d *DecodeError
+	return errors.As(err, &p) || errors.As(err, &d)
+}
+
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("%v (retry after %s)", e.err, e.delay)
+}
+
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// RetryAfter marks err as transient and asks for redelivery after d instead of the
+// policy backoff. Delays are capped at MaxRetryDelay.
+func RetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryAfterError{err: err, delay: d}
+}
@@ -0,0 +1,68 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, Factor: 3}
+	for receive, want := range map[int]time.Duration{1: time.Second, 2: 3 * time.Second, 3: 9 * time.Second, 4: 10 * time.Second, 50: 10 * time.Second} {
+		if got := p.Backoff(receive); got != want {
+			t.Errorf("receive %d: expected %s, got %s", receive, want, got)
+		}
+	}
+	if got := (RetryPolicy{}).Backoff(2); got != 2*DefaultInitialBackoff {
+		t.Errorf("expected default backoff, got %s", got)
+	}
+}
+
+func TestRetryPolicyDelayHonoursRetryAfter(t *testing.T) {
+	var p RetryPolicy
+	err := fmt.Errorf("wrapped: %w", RetryAfter(errors.New("throttled"), 42*time.Second))
+	if got := p.Delay(err, 1); got != 42*time.Second {
+		t.Fatalf("expected 42s, got %s", got)
+	}
+	if got := p.Delay(RetryAfter(errors.New("x"), 24*time.Hour), 1); got != MaxRetryDelay {
+		t.Fatalf("expected cap at %s, got %s", MaxRetryDelay, got)
+	}
+	if RetryAfter(nil, time.Second) != nil || Permanent(nil) != nil {
+		t.Fatalf("wrapping nil should return nil")
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	base := errors.New("bad")
+	if !IsPermanent(fmt.Errorf("ctx: %w", Permanent(base))) {
+		t.Fatalf("expected wrapped permanent error to be permanent")
+	}
+	if !errors.Is(Permanent(base), base) {
+		t.Fatalf("permanent error should unwrap to its cause")
+	}
+	if !IsPermanent(&DecodeError{Err: base}) {
+		t.Fatalf("decode errors should be permanent")
+	}
+	if IsPermanent(base) || IsPermanent(RetryAfter(base, time.Second)) {
+		t.Fatalf("plain and retry-after errors should not be permanent")
+	}
+}
+
+func TestRetryingStopsOnPermanent(t *testing.T) {
+	calls := 0
+	handler := retrying(func(ctx Context, msg Message) error {
+		calls++
+		return Permanent(errors.New("no"))
+	}, 5)
+	if err := handler(Context{}, Message{}); !IsPermanent(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+}
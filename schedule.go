@@ -0,0 +1,162 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Concurrency decides what happens when a schedule comes due while a previous run of it
+// is still in progress.
+type Concurrency string
+
+const (
+	// ConcurrencyAllow starts every run, overlapping any in progress. It is the default.
+	ConcurrencyAllow Concurrency = "allow"
+	// ConcurrencySkip drops a run that comes due while another is in progress.
+	ConcurrencySkip Concurrency = "skip"
+	// ConcurrencyQueue holds a run that comes due while another is in progress until
+	// that run returns.
+	ConcurrencyQueue Concurrency = "queue"
+)
+
+// Exclusive reports whether the policy keeps runs from overlapping.
+func (c Concurrency) Exclusive() bool {
+	return c == ConcurrencySkip || c == ConcurrencyQueue
+}
+
+// validate rejects unknown policies.
+func (c Concurrency) validate() error {
+	switch c {
+	case "", ConcurrencyAllow, ConcurrencySkip, ConcurrencyQueue:
+		return nil
+	default:
+		return fmt.Errorf("unknown concurrency policy %q", string(c))
+	}
+}
+
+// ScheduleOption configures a schedule at registration time.
+type ScheduleOption func(*Schedule)
+
+// WithConcurrency sets how the schedule's runs may overlap. On AWS, ConcurrencySkip and
+// ConcurrencyQueue hold a lease in a DynamoDB table that the build provisions.
+func WithConcurrency(policy Concurrency) ScheduleOption {
+	return func(s *Schedule) {
+		s.Concurrency = policy
+	}
+}
+
+// WithRunOnStart also runs the schedule once when the dispatcher starts, before its first
+// tick. On AWS the run follows each deploy.
+func WithRunOnStart() ScheduleOption {
+	return func(s *Schedule) {
+		s.RunOnStart = true
+	}
+}
+
+// WithJitter delays each run by a random duration of up to max, spreading schedules that
+// share a tick. Handlers still receive the tick time. On AWS the window is rounded up to
+// whole minutes.
+func WithJitter(max time.Duration) ScheduleOption {
+	return func(s *Schedule) {
+		s.Jitter = max
+	}
+}
+
+// WithMetadata attaches a static key/value pair to the schedule. Handlers read it from the
+// run's ScheduleInvocation; on AWS it is also sent in the EventBridge target input.
+func WithMetadata(key, value string) ScheduleOption {
+	return func(s *Schedule) {
+		if s.Metadata == nil {
+			s.Metadata = map[string]string{}
+		}
+		s.Metadata[key] = value
+	}
+}
+
+// ScheduleInvocation describes one run of a schedule.
+type ScheduleInvocation struct {
+	Schedule string
+	// At is the tick time passed to the handler.
+	At time.Time
+	// Metadata is the schedule's metadata overlaid with the data the run was triggered
+	// with, if any.
+	Metadata map[string]string
+}
+
+type scheduleDataKey struct{}
+
+type scheduleInvocationKey struct{}
+
+// ContextWithScheduleData returns a copy of ctx carrying data for the schedule run started
+// with it. Dispatchers use it for runs triggered with data, such as by
+// "transire trigger --data"; the data overlays the schedule's metadata.
+func ContextWithScheduleData(ctx context.Context, data map[string]string) context.Context {
+	return context.WithValue(ctx, scheduleDataKey{}, data)
+}
+
+// ScheduleInvocationFromContext returns the schedule run ctx belongs to. ok is false
+// outside schedule handlers.
+func ScheduleInvocationFromContext(ctx context.Context) (ScheduleInvocation, bool) {
+	if ctx == nil {
+		return ScheduleInvocation{}, false
+	}
+	inv, ok := ctx.Value(scheduleInvocationKey{}).(ScheduleInvocation)
+	return inv, ok
+}
+
+// invokedSchedule attaches each run's ScheduleInvocation to the handler context.
+func invokedSchedule(sched Schedule, next ScheduleHandler) ScheduleHandler {
+	return func(ctx Context, at time.Time) error {
+		data, _ := ctx.Value(scheduleDataKey{}).(map[string]string)
+		inv := ScheduleInvocation{Schedule: sched.Name, At: at}
+		if len(sched.Metadata)+len(data) > 0 {
+			inv.Metadata = make(map[string]string, len(sched.Metadata)+len(data))
+			for k, v := range sched.Metadata {
+				inv.Metadata[k] = v
+			}
+			for k, v := range data {
+				inv.Metadata[k] = v
+			}
+		}
+		ctx.Context = context.WithValue(ctx.Context, scheduleInvocationKey{}, inv)
+		return next(ctx, at)
+	}
+}
+
+// Reschedule returns a copy of s that runs every duration or, when cron is set, on the
+// cron expression in s's location. Environment overrides use it to change the rate of a
+// registered schedule.
+func (s Schedule) Reschedule(every time.Duration, cron string) (Schedule, error) {
+	s.Every, s.Cron, s.cron = every, cron, nil
+	if cron == "" {
+		return s, nil
+	}
+	spec, err := parseCron(cron)
+	if err != nil {
+		return s, fmt.Errorf("transire: schedule %s: %w", s.Name, err)
+	}
+	s.cron = spec
+	return s, nil
+}
+
+// applyScheduleOptions applies opts to s, panicking on invalid settings like
+// RegisterCronHandler does for invalid expressions.
+func applyScheduleOptions(s *Schedule, opts []ScheduleOption) {
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.Concurrency.validate(); err != nil {
+		panic(fmt.Errorf("transire: schedule %s: %w", s.Name, err))
+	}
+	if s.Jitter < 0 {
+		panic(fmt.Errorf("transire: schedule %s: negative jitter %s", s.Name, s.Jitter))
+	}
+	if _, ok := s.Metadata[""]; ok {
+		panic(fmt.Errorf("transire: schedule %s: empty metadata key", s.Name))
+	}
+}
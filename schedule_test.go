@@ -0,0 +1,93 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestScheduleOptionsApplyConcurrency(t *testing.T) {
+	app := New()
+	noop := func(ctx Context, at time.Time) error { return nil }
+	app.RegisterScheduleHandler("reconcile", time.Minute, noop, WithConcurrency(ConcurrencySkip))
+	app.RegisterCronHandler("report", "0 * * * *", "", noop, WithConcurrency(ConcurrencyQueue))
+	app.RegisterScheduleHandler("tick", time.Minute, noop)
+
+	if got := app.Schedules()["reconcile"].Concurrency; got != ConcurrencySkip || !got.Exclusive() {
+		t.Fatalf("unexpected policy %q", got)
+	}
+	if got := app.Schedules()["report"].Concurrency; got != ConcurrencyQueue {
+		t.Fatalf("unexpected policy %q", got)
+	}
+	if got := app.Schedules()["tick"].Concurrency; got.Exclusive() {
+		t.Fatalf("expected overlapping runs by default, got %q", got)
+	}
+}
+
+func TestUnknownConcurrencyPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected a panic for an unknown policy")
+		}
+	}()
+	New().RegisterScheduleHandler("tick", time.Minute, func(ctx Context, at time.Time) error { return nil }, WithConcurrency("serial"))
+}
+
+func TestScheduleOptionsApplyStartAndJitter(t *testing.T) {
+	app := New()
+	app.RegisterScheduleHandler("warm", time.Hour, func(ctx Context, at time.Time) error { return nil }, WithRunOnStart(), WithJitter(5*time.Minute))
+	sched := app.Schedules()["warm"]
+	if !sched.RunOnStart || sched.Jitter != 5*time.Minute {
+		t.Fatalf("unexpected schedule %+v", sched)
+	}
+}
+
+func TestReschedule(t *testing.T) {
+	app := New()
+	app.RegisterCronHandler("report", "0 9 * * *", "Europe/London", func(ctx Context, at time.Time) error { return nil })
+	base := app.Schedules()["report"]
+
+	hourly, err := base.Reschedule(0, "0 * * * *")
+	if err != nil {
+		t.Fatalf("reschedule: %v", err)
+	}
+	from := time.Date(2025, 6, 1, 10, 30, 0, 0, time.UTC)
+	if got := hourly.Next(from); !got.Equal(time.Date(2025, 6, 1, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected next run %v", got)
+	}
+	every, err := base.Reschedule(10*time.Minute, "")
+	if err != nil || !every.Next(from).Equal(from.Add(10*time.Minute)) {
+		t.Fatalf("unexpected rate reschedule: %v", err)
+	}
+	if _, err := base.Reschedule(0, "not cron"); err == nil {
+		t.Fatalf("expected an invalid cron rejected")
+	}
+	if base.Cron != "0 9 * * *" {
+		t.Fatalf("expected the registered schedule unchanged, got %q", base.Cron)
+	}
+}
+
+func TestScheduleInvocationOnHandlerContext(t *testing.T) {
+	app := New()
+	var got ScheduleInvocation
+	app.RegisterScheduleHandler("report", time.Hour, func(ctx Context, at time.Time) error {
+		got, _ = ScheduleInvocationFromContext(ctx)
+		return nil
+	}, WithMetadata("team", "billing"))
+	handler, _ := app.ScheduleHandler("report")
+
+	at := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	if err := handler(app.ScheduleContext(context.Background(), "report"), at); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got.Schedule != "report" || !got.At.Equal(at) || got.Metadata["team"] != "billing" {
+		t.Fatalf("unexpected invocation %+v", got)
+	}
+	if _, ok := ScheduleInvocationFromContext(context.Background()); ok {
+		t.Fatalf("expected no invocation outside schedule handlers")
+	}
+}
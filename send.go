@@ -0,0 +1,113 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxSendDelay is the longest delivery delay a queue message may request (the SQS limit).
+const MaxSendDelay = 15 * time.Minute
+
+// ErrSendOptionsUnsupported signals SendWithOptions was given options the sender cannot honour.
+var ErrSendOptionsUnsupported = errors.New("transire: queue sender does not support send options")
+
+// SendOptions carries optional delivery settings for a queue message.
+type SendOptions struct {
+	// Delay postpones delivery to the handler, up to MaxSendDelay.
+	Delay time.Duration
+	// Attributes are delivered to the handler as Message.Attributes.
+	Attributes map[string]string
+	// DeduplicationID and GroupID apply to FIFO queues.
+	DeduplicationID string
+	GroupID         string
+}
+
+// Validate reports options that no runtime can honour.
+func (o SendOptions) Validate() error {
+	if o.Delay < 0 || o.Delay > MaxSendDelay {
+		return fmt.Errorf("transire: send delay %s outside [0, %s]", o.Delay, MaxSendDelay)
+	}
+	return nil
+}
+
+func (o SendOptions) isZero() bool {
+	return o.Delay == 0 && len(o.Attributes) == 0 && o.DeduplicationID == "" && o.GroupID == ""
+}
+
+// OptionsSender is implemented by queue senders that honour SendOptions.
+type OptionsSender interface {
+	SendWithOptions(ctx context.Context, queue string, payload []byte, opts SendOptions) error
+}
+
+// SendWithOptions sends a message using opts when the sender supports them.
+// Senders without option support still accept zero options via their plain Send.
+func SendWithOptions(ctx context.Context, sender QueueSender, queue string, payload []byte, opts SendOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if s, ok := sender.(OptionsSender); ok {
+		return s.SendWithOptions(ctx, queue, payload, opts)
+	}
+	if !opts.isZero() {
+		return ErrSendOptionsUnsupported
+	}
+	return sender.Send(ctx, queue, payload)
+}
+
+// BatchEntry is a single message within a SendBatch call.
+type BatchEntry struct {
+	Body    []byte
+	Options SendOptions
+}
+
+// BatchFailure describes an entry that could not be sent.
+type BatchFailure struct {
+	// Index is the position of the entry in the slice passed to SendBatch.
+	Index int
+	Err   error
+}
+
+// BatchError reports the entries of a batch that failed; all other entries were sent.
+type BatchError struct {
+	Queue  string
+	Failed []BatchFailure
+}
+
+func (e *BatchError) Error() string {
+	if len(e.Failed) == 1 {
+		return fmt.Sprintf("transire: batch send to queue %s: entry %d failed: %v", e.Queue, e.Failed[0].Index, e.Failed[0].Err)
+	}
+	return fmt.Sprintf("transire: batch send to queue %s: %d entries failed", e.Queue, len(e.Failed))
+}
+
+// BatchSender is implemented by queue senders that can send many messages in one call.
+type BatchSender interface {
+	SendBatch(ctx context.Context, queue string, entries []BatchEntry) error
+}
+
+// SendBatch sends all entries to the named queue, using the sender's batch API when available.
+// Per-entry failures are reported as *BatchError.
+func SendBatch(ctx context.Context, sender QueueSender, queue string, entries []BatchEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if s, ok := sender.(BatchSender); ok {
+		return s.SendBatch(ctx, queue, entries)
+	}
+	batchErr := &BatchError{Queue: queue}
+	for i, entry := range entries {
+		if err := SendWithOptions(ctx, sender, queue, entry.Body, entry.Options); err != nil {
+			batchErr.Failed = append(batchErr.Failed, BatchFailure{Index: i, Err: err})
+		}
+	}
+	if len(batchErr.Failed) > 0 {
+		return batchErr
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type optionsSender struct {
+	captureSender
+	opts SendOptions
+}
+
+func (o *optionsSender) SendWithOptions(ctx context.Context, queue string, payload []byte, opts SendOptions) error {
+	o.opts = opts
+	return o.Send(ctx, queue, payload)
+}
+
+func TestSendWithOptionsUsesOptionsSender(t *testing.T) {
+	sender := &optionsSender{}
+	opts := SendOptions{Delay: time.Second, Attributes: map[string]string{"k": "v"}}
+	if err := SendWithOptions(context.Background(), sender, "q", []byte("x"), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sender.queue != "q" || sender.opts.Delay != time.Second || sender.opts.Attributes["k"] != "v" {
+		t.Fatalf("options not forwarded: %+v", sender.opts)
+	}
+}
+
+func TestSendWithOptionsFallsBackForZeroOptions(t *testing.T) {
+	sender := &captureSender{}
+	if err := SendWithOptions(context.Background(), sender, "q", []byte("x"), SendOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sender.queue != "q" {
+		t.Fatalf("plain sender not used")
+	}
+	err := SendWithOptions(context.Background(), sender, "q", []byte("x"), SendOptions{GroupID: "g"})
+	if !errors.Is(err, ErrSendOptionsUnsupported) {
+		t.Fatalf("expected ErrSendOptionsUnsupported, got %v", err)
+	}
+}
+
+func TestSendOptionsValidate(t *testing.T) {
+	if err := (SendOptions{Delay: MaxSendDelay}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := (SendOptions{Delay: MaxSendDelay + time.Second}).Validate(); err == nil {
+		t.Fatalf("expected error for delay above max")
+	}
+	if err := (SendOptions{Delay: -time.Second}).Validate(); err == nil {
+		t.Fatalf("expected error for negative delay")
+	}
+}
+
+type failingSender struct {
+	sent int
+}
+
+func (f *failingSender) Send(ctx context.Context, queue string, payload []byte) error {
+	if string(payload) == "bad" {
+		return errors.New("rejected")
+	}
+	f.sent++
+	return nil
+}
+
+func TestSendBatchFallsBackToSend(t *testing.T) {
+	sender := &failingSender{}
+	entries := []BatchEntry{{Body: []byte("a")}, {Body: []byte("bad")}, {Body: []byte("c")}}
+	err := SendBatch(context.Background(), sender, "q", entries)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected BatchError, got %v", err)
+	}
+	if len(batchErr.Failed) != 1 || batchErr.Failed[0].Index != 1 {
+		t.Fatalf("unexpected failures: %+v", batchErr.Failed)
+	}
+	if sender.sent != 2 {
+		t.Fatalf("expected remaining entries to be sent, got %d", sender.sent)
+	}
+}
+
+func TestQueueConfigValidateSend(t *testing.T) {
+	fifo := QueueConfig{FIFO: true}
+	if err := fifo.ValidateSend("billing", SendOptions{GroupID: "cust-1", DeduplicationID: "inv-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, opts := range map[string]SendOptions{
+		"missing group": {DeduplicationID: "inv-1"},
+		"missing dedup": {GroupID: "cust-1"},
+		"delay":         {GroupID: "cust-1", DeduplicationID: "inv-1", Delay: time.Second},
+	} {
+		if err := fifo.ValidateSend("billing", opts); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	content := QueueConfig{FIFO: true, ContentBasedDeduplication: true}
+	if err := content.ValidateSend("billing", SendOptions{GroupID: "cust-1"}); err != nil {
+		t.Fatalf("content-based deduplication should not need an ID: %v", err)
+	}
+	if err := (QueueConfig{}).ValidateSend("work", SendOptions{}); err != nil {
+		t.Fatalf("standard queues need no FIFO options: %v", err)
+	}
+}
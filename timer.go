@@ -0,0 +1,75 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNoTimers is returned by ScheduleAt, ScheduleAfter, and CancelScheduled when the
+// dispatcher provides no timers.
+var ErrNoTimers = errors.New("transire: no timers configured")
+
+// ErrTimerNotFound is returned when cancelling a timer that has fired, was cancelled, or
+// never existed.
+var ErrTimerNotFound = errors.New("transire: timer not found")
+
+// Timer is a queue message waiting for its delivery time.
+type Timer struct {
+	ID      string    `json:"id"`
+	Queue   string    `json:"queue"`
+	At      time.Time `json:"at"`
+	Payload []byte    `json:"payload"`
+}
+
+// Timers send queue messages at arbitrary future times, beyond the MaxSendDelay that
+// SendOptions.Delay allows. Timers target standard queues; FIFO queues are rejected.
+type Timers interface {
+	// ScheduleAt arranges for payload to be sent to queue at at and returns the timer's
+	// ID. A time in the past sends it as soon as possible.
+	ScheduleAt(ctx context.Context, queue string, at time.Time, payload []byte) (string, error)
+	// Cancel stops the timer with id, or returns ErrTimerNotFound.
+	Cancel(ctx context.Context, id string) error
+	// Now is the time the timers run on, which ScheduleAfter counts from.
+	Now() time.Time
+}
+
+// SetTimers configures the timers used inside handler contexts.
+func (a *App) SetTimers(timers Timers) {
+	a.timers = timers
+}
+
+// Timers returns the configured timers.
+func (a *App) Timers() Timers {
+	return a.timers
+}
+
+// ScheduleAt sends payload to queue at at and returns an ID for CancelScheduled.
+func (c Context) ScheduleAt(queue string, at time.Time, payload []byte) (string, error) {
+	if c.Timers == nil {
+		return "", ErrNoTimers
+	}
+	return c.Timers.ScheduleAt(c, queue, at, payload)
+}
+
+// ScheduleAfter sends payload to queue once d has elapsed and returns an ID for
+// CancelScheduled.
+func (c Context) ScheduleAfter(queue string, d time.Duration, payload []byte) (string, error) {
+	if c.Timers == nil {
+		return "", ErrNoTimers
+	}
+	return c.Timers.ScheduleAt(c, queue, c.Timers.Now().Add(d), payload)
+}
+
+// CancelScheduled stops a delivery arranged by ScheduleAt or ScheduleAfter. It returns
+// ErrTimerNotFound when the message was already sent.
+func (c Context) CancelScheduled(id string) error {
+	if c.Timers == nil {
+		return ErrNoTimers
+	}
+	return c.Timers.Cancel(c, id)
+}
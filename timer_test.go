@@ -0,0 +1,64 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type captureTimers struct {
+	now   time.Time
+	queue string
+	at    time.Time
+}
+
+func (c *captureTimers) ScheduleAt(ctx context.Context, queue string, at time.Time, payload []byte) (string, error) {
+	c.queue, c.at = queue, at
+	return "t-1", nil
+}
+
+func (c *captureTimers) Cancel(ctx context.Context, id string) error {
+	if id != "t-1" {
+		return ErrTimerNotFound
+	}
+	return nil
+}
+
+func (c *captureTimers) Now() time.Time {
+	return c.now
+}
+
+func TestScheduleAfterCountsFromTimersClock(t *testing.T) {
+	timers := &captureTimers{now: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+	app := New()
+	app.SetTimers(timers)
+	ctx := app.NewContext(context.Background())
+	id, err := ctx.ScheduleAfter("reminders", 48*time.Hour, []byte("x"))
+	if err != nil || id != "t-1" {
+		t.Fatalf("unexpected schedule %q: %v", id, err)
+	}
+	if timers.queue != "reminders" || !timers.at.Equal(timers.now.Add(48*time.Hour)) {
+		t.Fatalf("unexpected timer for %s at %v", timers.queue, timers.at)
+	}
+	if err := ctx.CancelScheduled("t-2"); !errors.Is(err, ErrTimerNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestTimersRequireDispatcher(t *testing.T) {
+	ctx := New().NewContext(context.Background())
+	if _, err := ctx.ScheduleAt("q", time.Now(), nil); !errors.Is(err, ErrNoTimers) {
+		t.Fatalf("expected ErrNoTimers, got %v", err)
+	}
+	if _, err := ctx.ScheduleAfter("q", time.Hour, nil); !errors.Is(err, ErrNoTimers) {
+		t.Fatalf("expected ErrNoTimers, got %v", err)
+	}
+	if err := ctx.CancelScheduled("t-1"); !errors.Is(err, ErrNoTimers) {
+		t.Fatalf("expected ErrNoTimers, got %v", err)
+	}
+}
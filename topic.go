@@ -0,0 +1,60 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// TopicPublisher broadcasts a message to every subscriber of a topic.
+type TopicPublisher interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+}
+
+// SubscriptionQueueName returns the queue that delivers topic messages to a subscription.
+func SubscriptionQueueName(topic, subscription string) string {
+	return topic + "-" + subscription
+}
+
+// RegisterTopicSubscriber subscribes a handler to a topic under a subscription name.
+// Each subscription is backed by its own queue, named by SubscriptionQueueName, so
+// queue options such as retries and dead-letter queues apply per subscriber.
+func (a *App) RegisterTopicSubscriber(topic, subscription string, handler QueueHandler, opts ...QueueOption) {
+	a.RegisterQueueHandler(SubscriptionQueueName(topic, subscription), handler, opts...)
+	for _, existing := range a.topics[topic] {
+		if existing == subscription {
+			return
+		}
+	}
+	a.topics[topic] = append(a.topics[topic], subscription)
+	sort.Strings(a.topics[topic])
+}
+
+// Topics exposes the subscription names registered for each topic.
+func (a *App) Topics() map[string][]string {
+	return a.topics
+}
+
+// SetTopicPublisher configures the topic publisher used inside handler contexts.
+func (a *App) SetTopicPublisher(publisher TopicPublisher) {
+	a.topicPublisher = publisher
+}
+
+// TopicPublisher returns the configured topic publisher.
+func (a *App) TopicPublisher() TopicPublisher {
+	return a.topicPublisher
+}
+
+// PublishJSON encodes payload as JSON and publishes it to the named topic.
+func PublishJSON[T any](ctx context.Context, publisher TopicPublisher, topic string, payload T) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("transire: encode message for topic %s: %w", topic, err)
+	}
+	return publisher.Publish(ctx, topic, body)
+}
@@ -0,0 +1,63 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type capturePublisher struct {
+	topic   string
+	payload []byte
+}
+
+func (p *capturePublisher) Publish(ctx context.Context, topic string, payload []byte) error {
+	p.topic, p.payload = topic, payload
+	return nil
+}
+
+func TestRegisterTopicSubscriber(t *testing.T) {
+	app := New()
+	handler := func(ctx Context, msg Message) error { return nil }
+	app.RegisterTopicSubscriber("order-created", "email", handler)
+	app.RegisterTopicSubscriber("order-created", "billing", handler, WithDeadLetterQueue(3))
+	app.RegisterTopicSubscriber("order-created", "billing", handler, WithDeadLetterQueue(3))
+
+	subs := app.Topics()["order-created"]
+	if len(subs) != 2 || subs[0] != "billing" || subs[1] != "email" {
+		t.Fatalf("unexpected subscriptions: %v", subs)
+	}
+	if _, ok := app.QueueHandler("order-created-billing"); !ok {
+		t.Fatalf("subscription should be backed by a queue")
+	}
+	if got := app.QueueConfig(SubscriptionQueueName("order-created", "billing")).MaxReceiveCount; got != 3 {
+		t.Fatalf("queue options should apply to the subscription queue, got %d", got)
+	}
+}
+
+func TestContextMiddlewareCarriesTopics(t *testing.T) {
+	app := New()
+	publisher := &capturePublisher{}
+	app.SetQueueSender(noopSender{})
+	app.SetTopicPublisher(publisher)
+
+	handler := app.ContextMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := RequestContext(r)
+		if !ok || ctx.Queues == nil || ctx.Topics == nil {
+			t.Fatalf("expected queues and topics in context, got %+v", ctx)
+		}
+		if err := PublishJSON(ctx, ctx.Topics, "order-created", map[string]string{"id": "42"}); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if publisher.topic != "order-created" || string(publisher.payload) != `{"id":"42"}` {
+		t.Fatalf("unexpected publish: %s %s", publisher.topic, publisher.payload)
+	}
+}
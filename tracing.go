@@ -0,0 +1,208 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation name of the spans Transire creates.
+const TracerName = "github.com/transire/transire"
+
+// tracePropagator carries span context through HTTP headers and message attributes
+// (the W3C traceparent and tracestate keys).
+var tracePropagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// SetTracerProvider configures the provider for the spans created around each HTTP
+// request, queue message, and schedule run. Without one the global otel provider is used.
+func (a *App) SetTracerProvider(provider trace.TracerProvider) {
+	a.tracerProvider = provider
+}
+
+// SetSpanExporter configures a tracer provider that batches spans to exporter.
+// Dispatchers flush it after each invocation where the runtime may freeze between them.
+func (a *App) SetSpanExporter(exporter sdktrace.SpanExporter) {
+	a.tracerProvider = sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+}
+
+// TracerProvider returns the configured tracer provider, or the global otel provider.
+func (a *App) TracerProvider() trace.TracerProvider {
+	if a.tracerProvider != nil {
+		return a.tracerProvider
+	}
+	return otel.GetTracerProvider()
+}
+
+// FlushTraces exports any buffered spans when the tracer provider supports it.
+func (a *App) FlushTraces(ctx context.Context) error {
+	if f, ok := a.tracerProvider.(interface{ ForceFlush(context.Context) error }); ok {
+		return f.ForceFlush(ctx)
+	}
+	return nil
+}
+
+// ShutdownTraces flushes and stops the tracer provider when it supports it.
+func (a *App) ShutdownTraces(ctx context.Context) error {
+	if s, ok := a.tracerProvider.(interface{ Shutdown(context.Context) error }); ok {
+		return s.Shutdown(ctx)
+	}
+	return nil
+}
+
+func (a *App) tracer() trace.Tracer {
+	return a.TracerProvider().Tracer(TracerName)
+}
+
+// TraceAttributes returns attrs with the span context of ctx added, so the receiving
+// handler's span joins the sender's trace. attrs is not modified.
+func TraceAttributes(ctx context.Context, attrs map[string]string) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return attrs
+	}
+	carrier := propagation.MapCarrier{}
+	for k, v := range attrs {
+		carrier[k] = v
+	}
+	tracePropagator.Inject(ctx, carrier)
+	return carrier
+}
+
+// OutgoingAttributes returns attrs with the correlation ID and span context of ctx added.
+// Queue senders and topic publishers call it on every outgoing message.
+func OutgoingAttributes(ctx context.Context, attrs map[string]string) map[string]string {
+	return TraceAttributes(ctx, CorrelationAttributes(ctx, attrs))
+}
+
+// contextOrBackground guards against handlers invoked with a zero Context.
+func contextOrBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// batchError summarizes the failures of a batch result, or returns nil when all succeeded.
+func batchError(result BatchResult, size int) error {
+	if n := len(result.Failures); n > 0 {
+		return fmt.Errorf("%d of %d messages failed", n, size)
+	}
+	return nil
+}
+
+// endSpan records err on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedQueue starts a consumer span for each message, parented on the span context
+// carried in the message attributes.
+func (a *App) tracedQueue(queue string, handler QueueHandler) QueueHandler {
+	return func(ctx Context, msg Message) error {
+		base := contextOrBackground(ctx.Context)
+		parent := tracePropagator.Extract(base, propagation.MapCarrier(msg.Attributes))
+		spanCtx, span := a.tracer().Start(parent, "queue "+queue,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", "transire"),
+				attribute.String("messaging.destination.name", queue),
+				attribute.String("messaging.message.id", msg.ID),
+				attribute.String("transire.correlation_id", CorrelationID(base)),
+			))
+		ctx.Context = spanCtx
+		err := handler(ctx, msg)
+		endSpan(span, err)
+		return err
+	}
+}
+
+// tracedBatch starts one consumer span per batch, linked to the span of each message.
+func (a *App) tracedBatch(queue string, handler BatchQueueHandler) BatchQueueHandler {
+	return func(ctx Context, msgs []Message) BatchResult {
+		links := make([]trace.Link, 0, len(msgs))
+		for _, msg := range msgs {
+			sc := trace.SpanContextFromContext(tracePropagator.Extract(context.Background(), propagation.MapCarrier(msg.Attributes)))
+			if sc.IsValid() {
+				links = append(links, trace.Link{SpanContext: sc})
+			}
+		}
+		spanCtx, span := a.tracer().Start(contextOrBackground(ctx.Context), "queue "+queue,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithLinks(links...),
+			trace.WithAttributes(
+				attribute.String("messaging.system", "transire"),
+				attribute.String("messaging.destination.name", queue),
+				attribute.Int("messaging.batch.message_count", len(msgs)),
+			))
+		ctx.Context = spanCtx
+		result := handler(ctx, msgs)
+		endSpan(span, batchError(result, len(msgs)))
+		return result
+	}
+}
+
+// tracedSchedule starts a span for each schedule run.
+func (a *App) tracedSchedule(name string, handler ScheduleHandler) ScheduleHandler {
+	return func(ctx Context, at time.Time) error {
+		base := contextOrBackground(ctx.Context)
+		spanCtx, span := a.tracer().Start(base, "schedule "+name,
+			trace.WithAttributes(
+				attribute.String("transire.schedule", name),
+				attribute.String("transire.correlation_id", CorrelationID(base)),
+			))
+		ctx.Context = spanCtx
+		err := handler(ctx, at)
+		endSpan(span, err)
+		return err
+	}
+}
+
+// traceHTTP starts a server span for the request, parented on incoming traceparent
+// headers, and names it after the matched chi route once the handler returns.
+func (a *App) traceHTTP(w http.ResponseWriter, r *http.Request, next func(http.ResponseWriter, *http.Request)) {
+	parent := tracePropagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := a.tracer().Start(parent, r.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", r.URL.Path),
+			attribute.String("transire.correlation_id", CorrelationID(r.Context())),
+		))
+	ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	r = r.WithContext(ctx)
+	next(ww, r)
+
+	status := ww.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if rc := chi.RouteContext(r.Context()); rc != nil {
+		if pattern := rc.RoutePattern(); pattern != "" {
+			span.SetName(r.Method + " " + pattern)
+			span.SetAttributes(attribute.String("http.route", pattern))
+		}
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	var err error
+	if status >= http.StatusInternalServerError {
+		err = fmt.Errorf("HTTP %d", status)
+	}
+	endSpan(span, err)
+}
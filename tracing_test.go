@@ -0,0 +1,110 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transire
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTracedApp(t *testing.T) (*App, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	app := New()
+	app.SetSpanExporter(exporter)
+	t.Cleanup(func() { _ = app.ShutdownTraces(context.Background()) })
+	return app, exporter
+}
+
+func flushedSpans(t *testing.T, app *App, exporter *tracetest.InMemoryExporter) tracetest.SpanStubs {
+	t.Helper()
+	if err := app.FlushTraces(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	return exporter.GetSpans()
+}
+
+func TestHTTPAndQueueSpansShareTrace(t *testing.T) {
+	app, exporter := newTracedApp(t)
+	var attrs map[string]string
+	app.Router().Post("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		attrs = OutgoingAttributes(r.Context(), nil)
+	})
+	app.RegisterQueueHandler("work", func(ctx Context, msg Message) error {
+		return errors.New("boom")
+	})
+
+	root := chi.NewRouter()
+	root.Use(app.ContextMiddleware())
+	root.Mount("/", app.Router())
+	root.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders/1", nil))
+	if attrs["traceparent"] == "" || attrs[CorrelationIDAttribute] == "" {
+		t.Fatalf("expected trace and correlation attributes, got %v", attrs)
+	}
+
+	queueHandler, _ := app.QueueHandler("work")
+	msg := Message{ID: "m1", Queue: "work", Attributes: attrs}
+	if err := queueHandler(app.QueueContext(context.Background(), msg), msg); err == nil {
+		t.Fatalf("expected handler error")
+	}
+
+	spans := flushedSpans(t, app, exporter)
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	server, consumer := spans[0], spans[1]
+	if server.Name != "POST /orders/{id}" || server.SpanKind != trace.SpanKindServer {
+		t.Fatalf("unexpected server span: %s %v", server.Name, server.SpanKind)
+	}
+	if consumer.Name != "queue work" || consumer.SpanKind != trace.SpanKindConsumer {
+		t.Fatalf("unexpected consumer span: %s %v", consumer.Name, consumer.SpanKind)
+	}
+	if consumer.Parent.SpanID() != server.SpanContext.SpanID() || consumer.SpanContext.TraceID() != server.SpanContext.TraceID() {
+		t.Fatalf("expected consumer span to continue the server span's trace")
+	}
+	if consumer.Status.Code != codes.Error {
+		t.Fatalf("expected error status, got %v", consumer.Status)
+	}
+}
+
+func TestScheduleAndBatchSpans(t *testing.T) {
+	app, exporter := newTracedApp(t)
+	app.RegisterScheduleHandler("nightly", time.Hour, func(ctx Context, at time.Time) error {
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			t.Errorf("expected an active span in the handler context")
+		}
+		return nil
+	})
+	app.RegisterBatchQueueHandler("events", func(ctx Context, msgs []Message) BatchResult {
+		return BatchResult{}
+	})
+
+	schedule, _ := app.ScheduleHandler("nightly")
+	if err := schedule(app.ScheduleContext(context.Background(), "nightly"), time.Now()); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	batch, _ := app.BatchQueueHandler("events")
+	batch(app.BatchContext(context.Background(), "events", 2), []Message{{ID: "a"}, {ID: "b"}})
+
+	spans := flushedSpans(t, app, exporter)
+	if len(spans) != 2 || spans[0].Name != "schedule nightly" || spans[1].Name != "queue events" {
+		t.Fatalf("unexpected spans: %+v", spans)
+	}
+}
+
+func TestTraceAttributesWithoutSpan(t *testing.T) {
+	if got := TraceAttributes(context.Background(), nil); got != nil {
+		t.Fatalf("expected no attributes without a span, got %v", got)
+	}
+}
@@ -0,0 +1,262 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transiretest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	transire "github.com/transire/transire"
+)
+
+// defaultBatchSize matches the SQS event source default for batch handlers.
+const defaultBatchSize = 10
+
+// pending is a sent message waiting for delivery.
+type pending struct {
+	msg transire.Message
+	// ctx carries the sender's values but not its cancellation.
+	ctx   context.Context
+	due   time.Time
+	group string
+	// receives counts completed deliveries of msg.
+	receives int
+}
+
+// queueSender records messages on the harness until Drain delivers them.
+type queueSender struct {
+	h *Harness
+}
+
+func (q *queueSender) Send(ctx context.Context, queue string, payload []byte) error {
+	return q.SendWithOptions(ctx, queue, payload, transire.SendOptions{})
+}
+
+// SendWithOptions enqueues the message to be delivered opts.Delay after the harness clock.
+func (q *queueSender) SendWithOptions(ctx context.Context, queue string, payload []byte, opts transire.SendOptions) error {
+	p, err := q.prepare(ctx, queue, payload, opts)
+	if err != nil {
+		return err
+	}
+	q.h.enqueue(p, opts.DeduplicationID, payload)
+	return nil
+}
+
+// SendBatch validates every entry before enqueuing any, like the local dispatcher.
+func (q *queueSender) SendBatch(ctx context.Context, queue string, entries []transire.BatchEntry) error {
+	ps := make([]*pending, len(entries))
+	batchErr := &transire.BatchError{Queue: queue}
+	for i, entry := range entries {
+		p, err := q.prepare(ctx, queue, entry.Body, entry.Options)
+		if err != nil {
+			batchErr.Failed = append(batchErr.Failed, transire.BatchFailure{Index: i, Err: err})
+			continue
+		}
+		ps[i] = p
+	}
+	if len(batchErr.Failed) > 0 {
+		return batchErr
+	}
+	for i, p := range ps {
+		q.h.enqueue(p, entries[i].Options.DeduplicationID, entries[i].Body)
+	}
+	return nil
+}
+
+func (q *queueSender) prepare(ctx context.Context, queue string, payload []byte, opts transire.SendOptions) (*pending, error) {
+	if _, ok := q.h.app.QueueHandler(queue); !ok {
+		return nil, fmt.Errorf("queue %q not registered", queue)
+	}
+	if err := q.h.app.QueueConfig(queue).ValidateSend(queue, opts); err != nil {
+		return nil, err
+	}
+	attrs := map[string]string{}
+	for k, v := range transire.OutgoingAttributes(ctx, opts.Attributes) {
+		attrs[k] = v
+	}
+	return &pending{
+		msg: transire.Message{
+			Queue:      queue,
+			Body:       append([]byte(nil), payload...),
+			Attributes: attrs,
+		},
+		ctx:   context.WithoutCancel(ctx),
+		due:   q.h.Now().Add(opts.Delay),
+		group: opts.GroupID,
+	}, nil
+}
+
+// topicPublisher fans messages out to each subscription queue of a topic.
+type topicPublisher struct {
+	h *Harness
+}
+
+func (p *topicPublisher) Publish(ctx context.Context, topic string, payload []byte) error {
+	subscriptions := p.h.app.Topics()[topic]
+	if len(subscriptions) == 0 {
+		return fmt.Errorf("topic %q has no subscribers", topic)
+	}
+	var errs []error
+	for _, sub := range subscriptions {
+		if err := p.h.app.QueueSender().Send(ctx, transire.SubscriptionQueueName(topic, sub), payload); err != nil {
+			errs = append(errs, fmt.Errorf("subscription %s: %w", sub, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// enqueue assigns the message an ID and records it, dropping FIFO duplicates sent
+// within the deduplication window of the harness clock.
+func (h *Harness) enqueue(p *pending, dedupID string, payload []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.app.QueueConfig(p.msg.Queue).FIFO {
+		if dedupID == "" {
+			sum := sha256.Sum256(payload)
+			dedupID = hex.EncodeToString(sum[:])
+		}
+		seen := h.dedup[p.msg.Queue]
+		if seen == nil {
+			seen = map[string]time.Time{}
+			h.dedup[p.msg.Queue] = seen
+		}
+		if at, ok := seen[dedupID]; ok && h.now.Sub(at) < transire.DeduplicationWindow {
+			return
+		}
+		seen[dedupID] = h.now
+	}
+	h.seq++
+	p.msg.ID = fmt.Sprintf("test-%d", h.seq)
+	h.sent = append(h.sent, p.msg)
+	h.pending = append(h.pending, p)
+}
+
+// Drain delivers every message that is due at the harness clock, including those sent
+// by the handlers it runs and by timers that are due, until none is left. Messages are
+// delivered one at a time in send order; batch handlers receive the due messages of
+// their queue together. Failed messages are retried after their queue's retry policy
+// delay on the harness clock, dead-lettered, or dropped as the local dispatcher would.
+func (h *Harness) Drain() {
+	h.t.Helper()
+	for i := 0; ; i++ {
+		if i == maxDeliveries {
+			h.t.Fatalf("transiretest: queues did not settle after %d deliveries", maxDeliveries)
+		}
+		h.sendDueTimers()
+		ps := h.take()
+		if len(ps) == 0 {
+			return
+		}
+		h.deliver(ps)
+	}
+}
+
+// take removes the next due message from the pending list, along with further due
+// messages of the same queue when it has a batch handler. Messages behind an undelivered
+// message of the same FIFO group are not due.
+func (h *Harness) take() []*pending {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var queue string
+	size := 1
+	var taken []*pending
+	blocked := map[string]bool{}
+	rest := h.pending[:0:0]
+	for _, p := range h.pending {
+		key := p.msg.Queue + "\x00" + p.group
+		fifo := h.app.QueueConfig(p.msg.Queue).FIFO
+		eligible := !p.due.After(h.now) && !(fifo && blocked[key]) && len(taken) < size &&
+			(queue == "" || queue == p.msg.Queue)
+		if fifo {
+			blocked[key] = true
+		}
+		if !eligible {
+			rest = append(rest, p)
+			continue
+		}
+		if queue == "" {
+			queue = p.msg.Queue
+			if _, ok := h.app.BatchQueueHandler(queue); ok {
+				size = h.app.QueueConfig(queue).BatchSize
+				if size <= 0 {
+					size = defaultBatchSize
+				}
+			}
+		}
+		taken = append(taken, p)
+	}
+	h.pending = rest
+	return taken
+}
+
+// deliver runs the queue's handler for ps and settles the failures.
+func (h *Harness) deliver(ps []*pending) {
+	queue := ps[0].msg.Queue
+	for _, p := range ps {
+		p.receives++
+	}
+	if batch, ok := h.app.BatchQueueHandler(queue); ok {
+		msgs := make([]transire.Message, len(ps))
+		for i, p := range ps {
+			msgs[i] = p.msg
+		}
+		result := batch(h.app.BatchContext(context.Background(), queue, len(ps)), msgs)
+		failed := make(map[string]error, len(result.Failures))
+		for _, f := range result.Failures {
+			if f.Err == nil {
+				f.Err = errors.New("message reported as failed")
+			}
+			failed[f.MessageID] = f.Err
+		}
+		var retry []*pending
+		for _, p := range ps {
+			if err, ok := failed[p.msg.ID]; ok && h.settle(p, err) {
+				retry = append(retry, p)
+			}
+		}
+		h.requeue(retry)
+		return
+	}
+	handler, _ := h.app.QueueHandler(queue)
+	p := ps[0]
+	if err := handler(h.app.QueueContext(p.ctx, p.msg), p.msg); err != nil && h.settle(p, err) {
+		h.requeue([]*pending{p})
+	}
+}
+
+// settle handles a failed receive following the local dispatcher's rules and reports
+// whether to retry the message, setting its due time after the retry policy delay.
+// Otherwise it is dead-lettered, or dropped when permanent and the queue has no
+// dead-letter queue.
+func (h *Harness) settle(p *pending, err error) bool {
+	cfg := h.app.QueueConfig(p.msg.Queue)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	permanent := transire.IsPermanent(err)
+	if permanent && cfg.MaxReceiveCount <= 0 {
+		return false
+	}
+	if permanent || (cfg.MaxReceiveCount > 0 && p.receives >= cfg.MaxReceiveCount) {
+		h.deadLetters[p.msg.Queue] = append(h.deadLetters[p.msg.Queue], p.msg)
+		return false
+	}
+	p.due = h.now.Add(cfg.RetryPolicy.Delay(err, p.receives))
+	return true
+}
+
+// requeue puts ps back at the front of the pending list, so each keeps its place ahead
+// of later messages in its FIFO group.
+func (h *Harness) requeue(ps []*pending) {
+	if len(ps) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pending = append(ps, h.pending...)
+}
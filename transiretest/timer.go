@@ -0,0 +1,107 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transiretest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	transire "github.com/transire/transire"
+)
+
+// timer is a ScheduleAt delivery held by the harness.
+type timer struct {
+	transire.Timer
+	// ctx carries the scheduler's values but not its cancellation.
+	ctx context.Context
+}
+
+// timers holds ScheduleAt deliveries on the harness until its clock reaches them.
+type timers struct {
+	h *Harness
+}
+
+func (t *timers) ScheduleAt(ctx context.Context, queue string, at time.Time, payload []byte) (string, error) {
+	h := t.h
+	if _, ok := h.app.QueueHandler(queue); !ok {
+		return "", fmt.Errorf("transiretest: queue %q not registered", queue)
+	}
+	if h.app.QueueConfig(queue).FIFO {
+		return "", fmt.Errorf("transire: timers cannot target FIFO queue %s", queue)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	id := fmt.Sprintf("timer-%d", h.seq)
+	h.timers[id] = &timer{
+		Timer: transire.Timer{ID: id, Queue: queue, At: at, Payload: payload},
+		ctx:   context.WithoutCancel(ctx),
+	}
+	return id, nil
+}
+
+func (t *timers) Cancel(ctx context.Context, id string) error {
+	h := t.h
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.timers[id]; !ok {
+		return transire.ErrTimerNotFound
+	}
+	delete(h.timers, id)
+	return nil
+}
+
+func (t *timers) Now() time.Time {
+	return t.h.Now()
+}
+
+// Timers returns the deliveries scheduled with ScheduleAt or ScheduleAfter that have not
+// been sent yet, in delivery order.
+func (h *Harness) Timers() []transire.Timer {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]transire.Timer, 0, len(h.timers))
+	for _, t := range h.sortedTimers() {
+		out = append(out, t.Timer)
+	}
+	return out
+}
+
+// sendDueTimers sends the timers due at the harness clock through the queue sender, in
+// delivery order.
+func (h *Harness) sendDueTimers() {
+	h.mu.Lock()
+	var due []*timer
+	for _, t := range h.sortedTimers() {
+		if t.At.After(h.now) {
+			break
+		}
+		due = append(due, t)
+		delete(h.timers, t.ID)
+	}
+	h.mu.Unlock()
+	for _, t := range due {
+		if err := h.app.QueueSender().Send(t.ctx, t.Queue, t.Payload); err != nil {
+			h.t.Fatalf("transiretest: send timer %s: %v", t.ID, err)
+		}
+	}
+}
+
+// sortedTimers returns the pending timers in delivery order. h.mu must be held.
+func (h *Harness) sortedTimers() []*timer {
+	out := make([]*timer, 0, len(h.timers))
+	for _, t := range h.timers {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].At.Equal(out[j].At) {
+			return out[i].At.Before(out[j].At)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
@@ -0,0 +1,333 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package transiretest runs a transire App in memory for tests. A Harness serves HTTP
+// requests through the app's router, holds sent queue messages until Drain delivers
+// them synchronously, fires schedules against a fake clock, and records every sent
+// message and handler failure for assertions.
+package transiretest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	transire "github.com/transire/transire"
+)
+
+// Epoch is the time a Harness clock starts at.
+var Epoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// maxDeliveries bounds a single Drain so that handlers feeding each other forever
+// fail the test instead of hanging it.
+const maxDeliveries = 10000
+
+// Harness runs an App in memory. It replaces the app's queue sender, topic publisher,
+// and timers, registers an OnError hook, and runs the OnStart hooks; the OnShutdown
+// hooks run when the test ends.
+type Harness struct {
+	t       testing.TB
+	app     *transire.App
+	handler http.Handler
+
+	mu          sync.Mutex
+	now         time.Time
+	nextRun     map[string]time.Time
+	pending     []*pending
+	timers      map[string]*timer
+	sent        []transire.Message
+	failures    []transire.HandlerError
+	deadLetters map[string][]transire.Message
+	// dedup records when each FIFO deduplication ID was last accepted, keyed by queue.
+	dedup map[string]map[string]time.Time
+	seq   int
+}
+
+// New builds a harness for app. Handlers must be registered before calling it.
+func New(t testing.TB, app *transire.App) *Harness {
+	t.Helper()
+	h := &Harness{
+		t:           t,
+		app:         app,
+		now:         Epoch,
+		nextRun:     map[string]time.Time{},
+		timers:      map[string]*timer{},
+		deadLetters: map[string][]transire.Message{},
+		dedup:       map[string]map[string]time.Time{},
+	}
+	app.SetQueueSender(&queueSender{h: h})
+	app.SetTopicPublisher(&topicPublisher{h: h})
+	app.SetTimers(&timers{h: h})
+	app.OnError(func(ctx transire.Context, failure transire.HandlerError) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.failures = append(h.failures, failure)
+	})
+	for name, sched := range app.Schedules() {
+		if next := sched.Next(h.now); !next.IsZero() {
+			h.nextRun[name] = next
+		}
+	}
+
+	root := chi.NewRouter()
+	root.Use(app.ContextMiddleware())
+	root.Mount("/", app.Router())
+	h.handler = root
+
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatalf("transiretest: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := app.Shutdown(context.Background()); err != nil {
+			t.Errorf("transiretest: %v", err)
+		}
+	})
+	// As under a dispatcher, run-on-start schedules run once the OnStart hooks have.
+	// Failures are recorded through the OnError hook.
+	for _, name := range h.scheduleNames() {
+		if app.Schedules()[name].RunOnStart {
+			_ = h.fire(context.Background(), name, h.now)
+		}
+	}
+	return h
+}
+
+// App returns the app under test.
+func (h *Harness) App() *transire.App {
+	return h.app
+}
+
+// Now returns the harness clock.
+func (h *Harness) Now() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.now
+}
+
+// Do serves req through the app's router. Messages the handler sends stay pending until
+// Drain or Advance.
+func (h *Harness) Do(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// Request builds a request for method and target and serves it with Do.
+func (h *Harness) Request(method, target string, body io.Reader) *httptest.ResponseRecorder {
+	return h.Do(httptest.NewRequest(method, target, body))
+}
+
+// Send enqueues payload on queue as if sent from outside the app, failing the test
+// when the queue rejects it.
+func (h *Harness) Send(queue string, payload []byte) {
+	h.t.Helper()
+	h.SendWithOptions(queue, payload, transire.SendOptions{})
+}
+
+// SendWithOptions enqueues payload on queue with opts, failing the test when the queue
+// rejects it.
+func (h *Harness) SendWithOptions(queue string, payload []byte, opts transire.SendOptions) {
+	h.t.Helper()
+	if err := transire.SendWithOptions(context.Background(), h.app.QueueSender(), queue, payload, opts); err != nil {
+		h.t.Fatalf("transiretest: send to %s: %v", queue, err)
+	}
+}
+
+// SendJSON encodes v as JSON and enqueues it on queue.
+func (h *Harness) SendJSON(queue string, v any) {
+	h.t.Helper()
+	body, err := json.Marshal(v)
+	if err != nil {
+		h.t.Fatalf("transiretest: encode message for %s: %v", queue, err)
+	}
+	h.Send(queue, body)
+}
+
+// Publish fans payload out to the subscription queues of topic.
+func (h *Harness) Publish(topic string, payload []byte) {
+	h.t.Helper()
+	if err := h.app.TopicPublisher().Publish(context.Background(), topic, payload); err != nil {
+		h.t.Fatalf("transiretest: publish to %s: %v", topic, err)
+	}
+}
+
+// Fire runs the named schedule once at the harness clock and returns its error.
+// Messages it sends stay pending until Drain or Advance.
+func (h *Harness) Fire(name string) error {
+	h.t.Helper()
+	return h.fire(context.Background(), name, h.Now())
+}
+
+// FireWithData runs the named schedule once like Fire, as "transire trigger --data" does:
+// data overlays the schedule's metadata in the run's ScheduleInvocation.
+func (h *Harness) FireWithData(name string, data map[string]string) error {
+	h.t.Helper()
+	return h.fire(transire.ContextWithScheduleData(context.Background(), data), name, h.Now())
+}
+
+func (h *Harness) fire(ctx context.Context, name string, at time.Time) error {
+	handler, ok := h.app.ScheduleHandler(name)
+	if !ok {
+		h.t.Fatalf("transiretest: schedule %q not registered", name)
+	}
+	return handler(h.app.ScheduleContext(ctx, name), at)
+}
+
+// Advance moves the clock forward by d. Schedules fire at each of their boundaries in
+// between, in time order and without jitter, and queue messages are drained as their
+// delays, retry backoffs, and ScheduleAt timers come due.
+func (h *Harness) Advance(d time.Duration) {
+	h.t.Helper()
+	h.mu.Lock()
+	target := h.now.Add(d)
+	h.mu.Unlock()
+	for {
+		h.Drain()
+		at, schedule, ok := h.nextEvent(target)
+		if !ok {
+			break
+		}
+		h.mu.Lock()
+		h.now = at
+		h.mu.Unlock()
+		if schedule != "" {
+			// Failures are recorded through the OnError hook.
+			_ = h.fire(context.Background(), schedule, at)
+		}
+	}
+	h.mu.Lock()
+	h.now = target
+	h.mu.Unlock()
+	h.Drain()
+}
+
+// nextEvent returns the earliest schedule boundary, pending message due time, or timer
+// after the clock and at or before target. For a schedule boundary it also advances
+// that schedule.
+func (h *Harness) nextEvent(target time.Time) (time.Time, string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var at time.Time
+	var schedule string
+	for _, name := range h.scheduleNames() {
+		next, ok := h.nextRun[name]
+		if !ok {
+			continue
+		}
+		if !next.After(target) && (at.IsZero() || next.Before(at)) {
+			at, schedule = next, name
+		}
+	}
+	// Messages already due were left pending by Drain because their FIFO group is held.
+	for _, p := range h.pending {
+		if p.due.After(h.now) && !p.due.After(target) && (at.IsZero() || p.due.Before(at)) {
+			at, schedule = p.due, ""
+		}
+	}
+	for _, t := range h.timers {
+		if t.At.After(h.now) && !t.At.After(target) && (at.IsZero() || t.At.Before(at)) {
+			at, schedule = t.At, ""
+		}
+	}
+	if at.IsZero() {
+		return time.Time{}, "", false
+	}
+	if schedule != "" {
+		sched := h.app.Schedules()[schedule]
+		if next := sched.Next(at); !next.IsZero() {
+			h.nextRun[schedule] = next
+		} else {
+			delete(h.nextRun, schedule)
+		}
+	}
+	return at, schedule, true
+}
+
+// scheduleNames returns the app's schedule names, sorted.
+func (h *Harness) scheduleNames() []string {
+	names := make([]string, 0, len(h.app.Schedules()))
+	for name := range h.app.Schedules() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Sent returns every message accepted for queue, in send order, whether or not it has
+// been delivered yet.
+func (h *Harness) Sent(queue string) []transire.Message {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var msgs []transire.Message
+	for _, msg := range h.sent {
+		if msg.Queue == queue {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+// Pending returns the number of messages not yet delivered, including delayed ones and
+// ones waiting for a retry.
+func (h *Harness) Pending() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.pending)
+}
+
+// Errors returns the handler failures reported to the OnError hooks, in order.
+func (h *Harness) Errors() []transire.HandlerError {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]transire.HandlerError(nil), h.failures...)
+}
+
+// DeadLetters returns the messages moved to the dead-letter queue of queue.
+func (h *Harness) DeadLetters(queue string) []transire.Message {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]transire.Message(nil), h.deadLetters[queue]...)
+}
+
+// AssertSent fails the test unless exactly n messages were sent to queue.
+func (h *Harness) AssertSent(queue string, n int) []transire.Message {
+	h.t.Helper()
+	msgs := h.Sent(queue)
+	if len(msgs) != n {
+		h.t.Fatalf("transiretest: expected %d messages sent to %s, got %d", n, queue, len(msgs))
+	}
+	return msgs
+}
+
+// AssertSentJSON fails the test unless exactly one message was sent to queue, then
+// decodes its body into v.
+func (h *Harness) AssertSentJSON(queue string, v any) {
+	h.t.Helper()
+	msgs := h.AssertSent(queue, 1)
+	if err := json.Unmarshal(msgs[0].Body, v); err != nil {
+		h.t.Fatalf("transiretest: decode message sent to %s: %v", queue, err)
+	}
+}
+
+// AssertNoErrors fails the test if any handler failure was reported.
+func (h *Harness) AssertNoErrors() {
+	h.t.Helper()
+	failures := h.Errors()
+	if len(failures) == 0 {
+		return
+	}
+	msg := fmt.Sprintf("transiretest: %d handler failures:", len(failures))
+	for _, f := range failures {
+		msg += fmt.Sprintf("\n  %s %s: %v", f.Kind, f.Name, f.Err)
+	}
+	h.t.Fatal(msg)
+}
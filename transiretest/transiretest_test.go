@@ -0,0 +1,262 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package transiretest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	transire "github.com/transire/transire"
+)
+
+func TestHTTPToQueueChain(t *testing.T) {
+	app := transire.New()
+	var started, stopped bool
+	app.OnStart(func(ctx context.Context) error { started = true; return nil })
+	app.OnShutdown(func(ctx context.Context) error { stopped = true; return nil })
+	app.Router().Post("/orders", func(w http.ResponseWriter, r *http.Request) {
+		ctx, _ := transire.RequestContext(r)
+		if err := ctx.Queues.Send(ctx, "orders", []byte("o-1")); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	var correlation string
+	app.RegisterQueueHandler("orders", func(ctx transire.Context, msg transire.Message) error {
+		correlation = transire.CorrelationID(ctx)
+		return ctx.Queues.Send(ctx, "emails", msg.Body)
+	})
+	app.RegisterQueueHandler("emails", func(ctx transire.Context, msg transire.Message) error {
+		if transire.CorrelationID(ctx) != correlation {
+			return errors.New("correlation ID lost")
+		}
+		return nil
+	})
+
+	t.Run("harness", func(t *testing.T) {
+		h := New(t, app)
+		if !started {
+			t.Fatalf("expected OnStart hooks run")
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/orders", nil)
+		req.Header.Set(transire.CorrelationIDHeader, "corr-1")
+		if rec := h.Do(req); rec.Code != http.StatusAccepted {
+			t.Fatalf("unexpected status %d", rec.Code)
+		}
+		if h.Pending() != 1 {
+			t.Fatalf("expected the message held until Drain, got %d pending", h.Pending())
+		}
+
+		h.Drain()
+		h.AssertNoErrors()
+		h.AssertSent("orders", 1)
+		if msgs := h.AssertSent("emails", 1); string(msgs[0].Body) != "o-1" {
+			t.Fatalf("unexpected email body %q", msgs[0].Body)
+		}
+		if correlation != "corr-1" || h.Pending() != 0 {
+			t.Fatalf("unexpected correlation %q with %d pending", correlation, h.Pending())
+		}
+	})
+	if !stopped {
+		t.Fatalf("expected OnShutdown hooks run at cleanup")
+	}
+}
+
+func TestAdvanceFiresSchedulesAndDelays(t *testing.T) {
+	app := transire.New()
+	var runs []time.Time
+	app.RegisterScheduleHandler("tick", 10*time.Minute, func(ctx transire.Context, at time.Time) error {
+		runs = append(runs, at)
+		return transire.SendWithOptions(ctx, ctx.Queues, "later", nil, transire.SendOptions{Delay: 5 * time.Minute})
+	})
+	var handled []time.Time
+	var h *Harness
+	app.RegisterQueueHandler("later", func(ctx transire.Context, msg transire.Message) error {
+		handled = append(handled, h.Now())
+		return nil
+	})
+	h = New(t, app)
+
+	h.Advance(25 * time.Minute)
+	if len(runs) != 2 || !runs[0].Equal(Epoch.Add(10*time.Minute)) || !runs[1].Equal(Epoch.Add(20*time.Minute)) {
+		t.Fatalf("unexpected schedule runs: %v", runs)
+	}
+	if len(handled) != 2 || !handled[1].Equal(Epoch.Add(25*time.Minute)) {
+		t.Fatalf("expected delayed messages delivered when due, got %v", handled)
+	}
+	if !h.Now().Equal(Epoch.Add(25 * time.Minute)) {
+		t.Fatalf("unexpected clock %v", h.Now())
+	}
+	if err := h.Fire("tick"); err != nil || h.Pending() != 1 {
+		t.Fatalf("expected Fire to run the schedule once, err=%v pending=%d", err, h.Pending())
+	}
+}
+
+func TestRetriesFollowPolicyAndDeadLetter(t *testing.T) {
+	app := transire.New()
+	attempts := 0
+	app.RegisterQueueHandler("work", func(ctx transire.Context, msg transire.Message) error {
+		attempts++
+		return errors.New("db down")
+	}, transire.WithDeadLetterQueue(3), transire.WithRetryPolicy(transire.RetryPolicy{InitialBackoff: time.Second}))
+
+	h := New(t, app)
+	h.Send("work", []byte("x"))
+	h.Drain()
+	if attempts != 1 || h.Pending() != 1 {
+		t.Fatalf("expected the retry held for its backoff, got %d attempts", attempts)
+	}
+	h.Advance(time.Minute)
+	if attempts != 3 || len(h.DeadLetters("work")) != 1 || len(h.Errors()) != 3 {
+		t.Fatalf("expected 3 attempts then a dead letter, got %d attempts, %d dead letters, %d errors",
+			attempts, len(h.DeadLetters("work")), len(h.Errors()))
+	}
+}
+
+func TestBatchAndFIFODelivery(t *testing.T) {
+	app := transire.New()
+	var sizes []int
+	app.RegisterBatchQueueHandler("bulk", func(ctx transire.Context, msgs []transire.Message) transire.BatchResult {
+		sizes = append(sizes, len(msgs))
+		return transire.BatchResult{}
+	}, transire.WithBatchSize(2))
+	var order []string
+	failed := false
+	app.RegisterQueueHandler("ledger", func(ctx transire.Context, msg transire.Message) error {
+		if string(msg.Body) == "a1" && !failed {
+			failed = true
+			return errors.New("retry")
+		}
+		order = append(order, string(msg.Body))
+		return nil
+	}, transire.WithContentBasedDeduplication(), transire.WithRetryPolicy(transire.RetryPolicy{InitialBackoff: time.Second}))
+
+	h := New(t, app)
+	for i := 0; i < 3; i++ {
+		h.Send("bulk", []byte{byte(i)})
+	}
+	for _, body := range []string{"a1", "b1", "a2", "a1"} {
+		h.SendWithOptions("ledger", []byte(body), transire.SendOptions{GroupID: body[:1]})
+	}
+	h.Drain()
+	if len(sizes) != 2 || sizes[0] != 2 || sizes[1] != 1 {
+		t.Fatalf("unexpected batch sizes %v", sizes)
+	}
+	if strings.Join(order, ",") != "b1" {
+		t.Fatalf("expected group a held behind its failed message, got %v", order)
+	}
+	h.Advance(time.Second)
+	if strings.Join(order, ",") != "b1,a1,a2" {
+		t.Fatalf("expected per-group order with the duplicate dropped, got %v", order)
+	}
+}
+
+func TestPublishFansOut(t *testing.T) {
+	app := transire.New()
+	for _, sub := range []string{"billing", "email"} {
+		app.RegisterTopicSubscriber("order-created", sub, func(ctx transire.Context, msg transire.Message) error {
+			return nil
+		})
+	}
+
+	h := New(t, app)
+	h.Publish("order-created", []byte("o-1"))
+	h.Drain()
+	h.AssertSent(transire.SubscriptionQueueName("order-created", "billing"), 1)
+	h.AssertSent(transire.SubscriptionQueueName("order-created", "email"), 1)
+}
+
+func TestRunOnStartSchedulesFireAtNew(t *testing.T) {
+	app := transire.New()
+	var runs []time.Time
+	app.RegisterScheduleHandler("warm", time.Hour, func(ctx transire.Context, at time.Time) error {
+		runs = append(runs, at)
+		return nil
+	}, transire.WithRunOnStart(), transire.WithJitter(time.Minute))
+
+	h := New(t, app)
+	if len(runs) != 1 || !runs[0].Equal(Epoch) {
+		t.Fatalf("expected one run at start, got %v", runs)
+	}
+	h.Advance(time.Hour)
+	if len(runs) != 2 || !runs[1].Equal(Epoch.Add(time.Hour)) {
+		t.Fatalf("expected the first tick at its nominal time, got %v", runs)
+	}
+}
+
+func TestFireWithDataOverlaysMetadata(t *testing.T) {
+	app := transire.New()
+	var got []map[string]string
+	app.RegisterScheduleHandler("report", time.Hour, func(ctx transire.Context, at time.Time) error {
+		inv, ok := transire.ScheduleInvocationFromContext(ctx)
+		if !ok || inv.Schedule != "report" || !inv.At.Equal(at) {
+			t.Fatalf("unexpected invocation %+v", inv)
+		}
+		got = append(got, inv.Metadata)
+		return nil
+	}, transire.WithMetadata("kind", "daily"), transire.WithMetadata("team", "billing"))
+
+	h := New(t, app)
+	if err := h.Fire("report"); err != nil {
+		t.Fatalf("fire: %v", err)
+	}
+	if err := h.FireWithData("report", map[string]string{"kind": "backfill"}); err != nil {
+		t.Fatalf("fire with data: %v", err)
+	}
+	if len(got) != 2 || got[0]["kind"] != "daily" || got[1]["kind"] != "backfill" || got[1]["team"] != "billing" {
+		t.Fatalf("unexpected metadata %v", got)
+	}
+	if app.Schedules()["report"].Metadata["kind"] != "daily" {
+		t.Fatalf("expected the schedule's metadata unchanged")
+	}
+}
+
+func TestTimersSendOnTheHarnessClock(t *testing.T) {
+	app := transire.New()
+	var ids []string
+	app.RegisterQueueHandler("signups", func(ctx transire.Context, msg transire.Message) error {
+		id, err := ctx.ScheduleAfter("reminders", 24*time.Hour, msg.Body)
+		ids = append(ids, id)
+		return err
+	})
+	app.RegisterQueueHandler("reminders", func(ctx transire.Context, msg transire.Message) error { return nil })
+	app.RegisterQueueHandler("ordered", func(ctx transire.Context, msg transire.Message) error { return nil }, transire.WithFIFO())
+
+	h := New(t, app)
+	start := h.Now()
+	h.Send("signups", []byte("u-1"))
+	h.Send("signups", []byte("u-2"))
+	h.Drain()
+	timers := h.Timers()
+	if len(timers) != 2 || !timers[0].At.Equal(start.Add(24*time.Hour)) || timers[0].Queue != "reminders" {
+		t.Fatalf("unexpected timers %+v", timers)
+	}
+	ctx := app.NewContext(context.Background())
+	if err := ctx.CancelScheduled(ids[1]); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if err := ctx.CancelScheduled(ids[1]); !errors.Is(err, transire.ErrTimerNotFound) {
+		t.Fatalf("expected a cancelled timer not found, got %v", err)
+	}
+	if _, err := ctx.ScheduleAfter("ordered", time.Hour, []byte("x")); err == nil {
+		t.Fatalf("expected a FIFO queue rejected")
+	}
+
+	h.Advance(23 * time.Hour)
+	h.AssertSent("reminders", 0)
+	h.Advance(time.Hour)
+	if msgs := h.AssertSent("reminders", 1); string(msgs[0].Body) != "u-1" {
+		t.Fatalf("unexpected reminder %q", msgs[0].Body)
+	}
+	if len(h.Timers()) != 0 {
+		t.Fatalf("expected no timers left, got %+v", h.Timers())
+	}
+	h.AssertNoErrors()
+}